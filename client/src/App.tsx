@@ -1,33 +1,57 @@
-import { Route } from "wouter";
+import { Redirect, Route, Switch } from "wouter";
 import { QueryClientProvider } from "@tanstack/react-query";
 import { ReactQueryDevtools } from "@tanstack/react-query-devtools";
 import { Toaster } from "@/components/ui/toaster";
 import { queryClient } from "@/lib/queryClient";
 import { Sidebar, SidebarInset, SidebarProvider } from "@/components/ui/sidebar";
 import Navigation from "@/components/Navigation";
+import { useAuth } from "@/hooks/use-auth";
+import LoginPage from "@/pages/login";
 import DashboardPage from "@/pages/dashboard";
 import InventoryPage from "@/pages/inventory/[product]";
 import InboundPage from "@/pages/inbound/[product]";
 import OutboundPage from "@/pages/outbound/[product]";
 import HistoryPage from "@/pages/history/[product]";
 
+// ログインが必要な画面（未ログインの場合はログイン画面へ移動する）
+function AuthenticatedApp() {
+  const { currentStaff, isLoading } = useAuth();
+
+  if (isLoading) {
+    return <div>読み込み中...</div>;
+  }
+
+  if (!currentStaff) {
+    return <Redirect to="/login" />;
+  }
+
+  return (
+    <SidebarProvider>
+      <Sidebar>
+        <Navigation />
+      </Sidebar>
+      <SidebarInset>
+        <Route path="/" component={DashboardPage} />
+        <Route path="/inventory/:product" component={InventoryPage} />
+        <Route path="/inbound/:product" component={InboundPage} />
+        <Route path="/outbound/:product" component={OutboundPage} />
+        <Route path="/history/:product" component={HistoryPage} />
+      </SidebarInset>
+    </SidebarProvider>
+  );
+}
+
 export function App() {
   return (
     <QueryClientProvider client={queryClient}>
-      <SidebarProvider>
-        <Sidebar>
-          <Navigation />
-        </Sidebar>
-        <SidebarInset>
-          <Route path="/" component={DashboardPage} />
-          <Route path="/inventory/:product" component={InventoryPage} />
-          <Route path="/inbound/:product" component={InboundPage} />
-          <Route path="/outbound/:product" component={OutboundPage} />
-          <Route path="/history/:product" component={HistoryPage} />
-        </SidebarInset>
-        <Toaster />
-        <ReactQueryDevtools initialIsOpen={false} />
-      </SidebarProvider>
+      <Switch>
+        <Route path="/login" component={LoginPage} />
+        <Route>
+          <AuthenticatedApp />
+        </Route>
+      </Switch>
+      <Toaster />
+      <ReactQueryDevtools initialIsOpen={false} />
     </QueryClientProvider>
   );
 }
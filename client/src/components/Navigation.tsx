@@ -1,23 +1,10 @@
 import { Link, useLocation } from "wouter";
-import { PRODUCT_CATEGORIES, SCREEN_COLORS } from "@/lib/constants";
+import { SCREEN_COLORS } from "@/lib/constants";
 import { cn } from "@/lib/utils";
-import { Package, Box, Heart, Shirt, Laptop, LayoutDashboard, History } from "lucide-react";
-
-const productIcons = {
-  [PRODUCT_CATEGORIES.DEVICE]: Package,
-  [PRODUCT_CATEGORIES.STATION]: Box,
-  [PRODUCT_CATEGORIES.HEART_RATE]: Heart,
-  [PRODUCT_CATEGORIES.VEST]: Shirt,
-  [PRODUCT_CATEGORIES.PC]: Laptop,
-} as const;
-
-const productLabels = {
-  [PRODUCT_CATEGORIES.DEVICE]: "デバイス",
-  [PRODUCT_CATEGORIES.STATION]: "ステーション",
-  [PRODUCT_CATEGORIES.HEART_RATE]: "心拍計",
-  [PRODUCT_CATEGORIES.VEST]: "ベスト",
-  [PRODUCT_CATEGORIES.PC]: "PC",
-} as const;
+import { useAuth } from "@/hooks/use-auth";
+import { useCategories, getCategoryIcon } from "@/hooks/use-categories";
+import { Button } from "@/components/ui/button";
+import { LayoutDashboard, History, LogOut } from "lucide-react";
 
 const menuItems = [
   { path: "inbound", label: "入庫", color: SCREEN_COLORS.inbound },
@@ -28,6 +15,13 @@ const menuItems = [
 
 export default function Navigation() {
   const [location] = useLocation();
+  const { currentStaff, logout } = useAuth();
+  const { categories } = useCategories();
+
+  // 担当カテゴリのみ表示する（担当カテゴリが未設定の場合はすべてのカテゴリ）
+  const visibleCategories = categories.filter(
+    (category) => !currentStaff?.categories || currentStaff.categories.includes(category.code)
+  );
 
   return (
     <nav className="min-h-screen w-64 bg-background border-r flex flex-col">
@@ -55,15 +49,15 @@ export default function Navigation() {
       </div>
 
       <div className="flex-1 p-4 space-y-4">
-        {Object.entries(PRODUCT_CATEGORIES).map(([key, value]) => {
-          const Icon = productIcons[value];
-          const label = productLabels[value];
+        {visibleCategories.map((category) => {
+          const Icon = getCategoryIcon(category.code);
+          const value = category.code;
 
           return (
-            <div key={key} className="space-y-1">
+            <div key={value} className="space-y-1">
               <div className="flex items-center gap-2 px-4 py-2 text-sm font-medium">
                 <Icon className="h-4 w-4" />
-                <span>{label}</span>
+                <span>{category.name}</span>
               </div>
               <div className="pl-6 space-y-1">
                 {menuItems.map((item) => (
@@ -85,6 +79,19 @@ export default function Navigation() {
           );
         })}
       </div>
+
+      <div className="p-4 border-t space-y-2">
+        <div className="px-4 text-sm text-muted-foreground">{currentStaff?.name}</div>
+        <Button
+          variant="ghost"
+          className="w-full justify-start gap-2"
+          onClick={() => logout.mutate()}
+          disabled={logout.isPending}
+        >
+          <LogOut className="h-4 w-4" />
+          ログアウト
+        </Button>
+      </div>
     </nav>
   );
 }
\ No newline at end of file
@@ -1,24 +1,8 @@
 import { ReactNode } from "react";
 import { useParams } from "wouter";
-import { SCREEN_COLORS, PRODUCT_CATEGORIES } from "@/lib/constants";
+import { SCREEN_COLORS } from "@/lib/constants";
 import { cn } from "@/lib/utils";
-import { Package, Box, Heart, Shirt, Laptop } from "lucide-react";
-
-const productIcons = {
-  [PRODUCT_CATEGORIES.DEVICE]: Package,
-  [PRODUCT_CATEGORIES.STATION]: Box,
-  [PRODUCT_CATEGORIES.HEART_RATE]: Heart,
-  [PRODUCT_CATEGORIES.VEST]: Shirt,
-  [PRODUCT_CATEGORIES.PC]: Laptop,
-} as const;
-
-const productLabels = {
-  [PRODUCT_CATEGORIES.DEVICE]: "デバイス",
-  [PRODUCT_CATEGORIES.STATION]: "ステーション",
-  [PRODUCT_CATEGORIES.HEART_RATE]: "心拍計",
-  [PRODUCT_CATEGORIES.VEST]: "ベスト",
-  [PRODUCT_CATEGORIES.PC]: "PC",
-} as const;
+import { useCategories, getCategoryIcon } from "@/hooks/use-categories";
 
 interface ProductLayoutProps {
   children: ReactNode;
@@ -32,8 +16,9 @@ export default function ProductLayout({
   title,
 }: ProductLayoutProps) {
   const { product } = useParams();
-  const Icon = product ? productIcons[product as keyof typeof productIcons] : null;
-  const label = product ? productLabels[product as keyof typeof productLabels] : null;
+  const { getCategoryLabel } = useCategories();
+  const Icon = product ? getCategoryIcon(product) : null;
+  const label = product ? getCategoryLabel(product) : null;
 
   return (
     <div className={cn(
@@ -2,7 +2,7 @@ import { useState, useEffect, useCallback, useMemo } from "react";
 import { useForm } from "react-hook-form";
 import { zodResolver } from "@hookform/resolvers/zod";
 import * as z from "zod";
-import { inventoryApi, pcModelNumbersApi } from "@/lib/api";
+import { inventoryApi, pcModelNumbersApi, type PCModelNumber } from "@/lib/api";
 import { CalendarIcon, Plus, Loader2, Trash2 } from "lucide-react";
 import { format } from "date-fns";
 import { Calendar } from "@/components/ui/calendar";
@@ -70,7 +70,7 @@ export function InboundForm({ category, onSubmit }: InboundFormProps) {
   const { staff } = useStaff();
   const { productTypes } = useProductTypes(category);
 
-  const { data: pcModelNumbers } = useQuery<{ id: number; modelNumber: string }[]>({
+  const { data: pcModelNumbers } = useQuery<PCModelNumber[]>({
     queryKey: ["pc-model-numbers"],
     enabled: category === PRODUCT_CATEGORIES.PC,
   });
@@ -726,7 +726,17 @@ export function InboundForm({ category, onSubmit }: InboundFormProps) {
                   <FormLabel>型番</FormLabel>
                   <div className="flex items-center gap-2">
                     <div className="flex-1">
-                      <Select onValueChange={field.onChange} value={field.value}>
+                      <Select
+                        onValueChange={(value) => {
+                          field.onChange(value);
+                          // 型番の既定の保証期間を初期値とする
+                          const model = pcModelNumbers?.find((m) => m.modelNumber === value);
+                          if (model?.defaultWarrantyPeriod != null) {
+                            form.setValue("warrantyPeriod", model.defaultWarrantyPeriod);
+                          }
+                        }}
+                        value={field.value}
+                      >
                         <FormControl>
                           <SelectTrigger className="w-[200px]">
                             <SelectValue placeholder="型番を選択" />
@@ -742,7 +752,9 @@ export function InboundForm({ category, onSubmit }: InboundFormProps) {
                                 value={model.modelNumber}
                                 className="flex-1"
                               >
-                                {model.modelNumber}
+                                {model.displayName
+                                  ? `${model.modelNumber}（${model.displayName}）`
+                                  : model.modelNumber}
                               </SelectItem>
                               <Button
                                 type="button"
@@ -866,7 +878,10 @@ export function InboundForm({ category, onSubmit }: InboundFormProps) {
               render={({ field }) => (
                 <FormItem>
                   <FormLabel>保証期間</FormLabel>
-                  <Select onValueChange={(v) => field.onChange(Number(v))}>
+                  <Select
+                    onValueChange={(v) => field.onChange(Number(v))}
+                    value={field.value?.toString()}
+                  >
                     <FormControl>
                       <SelectTrigger>
                         <SelectValue placeholder="保証期間を選択" />
@@ -0,0 +1,59 @@
+import axios from "axios";
+import { useMutation, useQuery, useQueryClient } from "@tanstack/react-query";
+import { useLocation } from "wouter";
+import { authApi, type LoginRequest, type Staff } from "@/lib/api";
+import { AUTH_QUERY_KEY } from "@/lib/queryClient";
+
+export const useAuth = () => {
+  const queryClient = useQueryClient();
+  const [, navigate] = useLocation();
+
+  // ログイン中の担当者（未ログインの場合は null）
+  const { data: currentStaff, isLoading } = useQuery<Staff | null>({
+    queryKey: AUTH_QUERY_KEY,
+    queryFn: async () => {
+      try {
+        const response = await authApi.me();
+        return response.data;
+      } catch (error) {
+        if (axios.isAxiosError(error) && error.response?.status === 401) {
+          return null;
+        }
+        throw error;
+      }
+    },
+  });
+
+  const login = useMutation({
+    mutationFn: async (data: LoginRequest) => {
+      const response = await authApi.login(data);
+      return response.data;
+    },
+    onSuccess: (data) => {
+      // 前の担当者のデータを残さない
+      queryClient.clear();
+      queryClient.setQueryData(AUTH_QUERY_KEY, data.staff);
+      navigate("/", { replace: true });
+    },
+  });
+
+  const logout = useMutation({
+    mutationFn: async () => {
+      const response = await authApi.logout();
+      return response.data;
+    },
+    // セッションの削除に失敗した場合もログイン画面へ戻す
+    onSettled: () => {
+      queryClient.clear();
+      queryClient.setQueryData(AUTH_QUERY_KEY, null);
+      navigate("/login", { replace: true });
+    },
+  });
+
+  return {
+    currentStaff: currentStaff ?? null,
+    isLoading,
+    login,
+    logout,
+  };
+};
@@ -0,0 +1,36 @@
+import { useQuery } from "@tanstack/react-query";
+import { Package, Box, Heart, Shirt, Laptop, Boxes, type LucideIcon } from "lucide-react";
+import { categoriesApi, type Category } from "@/lib/api";
+import { PRODUCT_CATEGORIES } from "@/lib/constants";
+
+// 初期カテゴリのアイコン（追加したカテゴリは既定のアイコンを使用する）
+const categoryIcons: Record<string, LucideIcon> = {
+  [PRODUCT_CATEGORIES.DEVICE]: Package,
+  [PRODUCT_CATEGORIES.STATION]: Box,
+  [PRODUCT_CATEGORIES.HEART_RATE]: Heart,
+  [PRODUCT_CATEGORIES.VEST]: Shirt,
+  [PRODUCT_CATEGORIES.PC]: Laptop,
+};
+
+export const getCategoryIcon = (code: string): LucideIcon => categoryIcons[code] ?? Boxes;
+
+// カテゴリ一覧（表示順）
+export const useCategories = () => {
+  const { data: categories, isLoading } = useQuery<Category[]>({
+    queryKey: ["/api/categories"],
+    queryFn: async () => {
+      const response = await categoriesApi.getAll();
+      return response.data;
+    },
+  });
+
+  // カテゴリの表示名（未登録のコードはそのまま表示する）
+  const getCategoryLabel = (code: string) =>
+    categories?.find((category) => category.code === code)?.name ?? code;
+
+  return {
+    categories: categories || [],
+    getCategoryLabel,
+    isLoading,
+  };
+};
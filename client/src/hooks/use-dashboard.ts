@@ -1,5 +1,5 @@
 import { useQuery } from "@tanstack/react-query";
-import { PRODUCT_CATEGORIES } from "@/lib/constants";
+import { useCategories } from "@/hooks/use-categories";
 
 interface DashboardStats {
   totalProducts: number;
@@ -14,18 +14,19 @@ interface DashboardStats {
 }
 
 export function useDashboard() {
-  const { data: stats, isLoading } = useQuery<DashboardStats>({
+  const { data: stats, isLoading: isStatsLoading } = useQuery<DashboardStats>({
     queryKey: ["/api/dashboard/stats"],
   });
+  const { categories, isLoading: isCategoriesLoading } = useCategories();
 
-  const stockLevels = Object.entries(PRODUCT_CATEGORIES).map(([key, value]) => ({
-    name: key,
-    value: stats?.byCategory[value] || 0,
+  const stockLevels = categories.map((category) => ({
+    name: category.name,
+    value: stats?.byCategory[category.code] || 0,
   }));
 
   return {
     stats,
     stockLevels,
-    isLoading,
+    isLoading: isStatsLoading || isCategoriesLoading,
   };
 }
@@ -1,5 +1,5 @@
 import { useQuery, useMutation, useQueryClient } from "@tanstack/react-query";
-import { inventoryApi } from "@/lib/api";
+import { fetchAllPages, inventoryApi } from "@/lib/api";
 import type { ProductCategory } from "@/lib/constants";
 
 export const useInventoryHistory = (category?: ProductCategory) => {
@@ -8,8 +8,8 @@ export const useInventoryHistory = (category?: ProductCategory) => {
   const { data: history, isLoading } = useQuery({
     queryKey: ["outbound-history", category],
     queryFn: async () => {
-      const response = await inventoryApi.getOutboundHistory(category || "");
-      return response.data;
+      // 一覧はページングされるため、すべてのページを取得する
+      return fetchAllPages((params) => inventoryApi.getOutboundHistory(category || "", params));
     },
     enabled: !!category,
   });
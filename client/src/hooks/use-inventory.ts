@@ -1,5 +1,5 @@
 import { useQuery, useMutation, useQueryClient } from "@tanstack/react-query";
-import { inventoryApi } from "@/lib/api";
+import { fetchAllPages, inventoryApi } from "@/lib/api";
 import type { ProductCategory } from "@/lib/constants";
 import type { InboundRequest, OutboundRequest } from "@/lib/api";
 
@@ -9,8 +9,8 @@ export const useInventory = (category?: ProductCategory) => {
   const { data: inventory, isLoading: isInventoryLoading } = useQuery({
     queryKey: ["inventory", category],
     queryFn: async () => {
-      const response = await inventoryApi.getByCategory(category || "");
-      return response.data;
+      // 一覧はページングされるため、すべてのページを取得する
+      return fetchAllPages((params) => inventoryApi.getByCategory(category || "", params));
     },
     enabled: !!category,
     staleTime: 0, // 常に最新データを取得
@@ -19,8 +19,8 @@ export const useInventory = (category?: ProductCategory) => {
   const { data: inboundHistory, isLoading: isInboundLoading } = useQuery({
     queryKey: ["inbound-history", category],
     queryFn: async () => {
-      const response = await inventoryApi.getInboundHistory(category || "");
-      return response.data;
+      // 一覧はページングされるため、すべてのページを取得する
+      return fetchAllPages((params) => inventoryApi.getInboundHistory(category || "", params));
     },
     enabled: !!category,
     staleTime: 0,
@@ -29,8 +29,8 @@ export const useInventory = (category?: ProductCategory) => {
   const { data: outboundHistory, isLoading: isOutboundLoading } = useQuery({
     queryKey: ["outbound-history", category],
     queryFn: async () => {
-      const response = await inventoryApi.getOutboundHistory(category || "");
-      return response.data;
+      // 一覧はページングされるため、すべてのページを取得する
+      return fetchAllPages((params) => inventoryApi.getOutboundHistory(category || "", params));
     },
     enabled: !!category,
     staleTime: 0,
@@ -44,12 +44,12 @@ export const useInventory = (category?: ProductCategory) => {
   });
 
   const inbound = useMutation({
-    mutationFn: async (data: InboundRequest & { category: string }) => {
+    mutationFn: async (data: InboundRequest & { category: string; idempotencyKey?: string }) => {
       const response = await inventoryApi.inbound(data.category, {
         products: data.products,
         staffId: data.staffId,
         inboundDate: data.inboundDate,
-      });
+      }, data.idempotencyKey);
       return response.data;
     },
     onSuccess: () => {
@@ -59,7 +59,7 @@ export const useInventory = (category?: ProductCategory) => {
   });
 
   const outbound = useMutation({
-    mutationFn: async (data: OutboundRequest & { category: string }) => {
+    mutationFn: async (data: OutboundRequest & { category: string; idempotencyKey?: string }) => {
       try {
         // outboundDateが既にISO文字列形式であることを確認
         if (!(typeof data.outboundDate === 'string')) {
@@ -69,6 +69,8 @@ export const useInventory = (category?: ProductCategory) => {
         const response = await inventoryApi.outbound(data.category, {
           productIdStart: data.productIdStart,
           productIdEnd: data.productIdEnd,
+          productIds: data.productIds,
+          allowGaps: data.allowGaps,
           staffId: data.staffId,
           outboundDate: data.outboundDate,
           customerNumber: data.customerNumber,
@@ -76,7 +78,7 @@ export const useInventory = (category?: ProductCategory) => {
           purchaserNumber: data.purchaserNumber,
           purchaserName: data.purchaserName,
           notes: data.notes,
-        });
+        }, data.idempotencyKey);
         return response.data;
       } catch (error) {
         // エラーを上位に伝播させる
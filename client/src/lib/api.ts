@@ -1,17 +1,119 @@
 import axios from 'axios';
 import { ProductCategory } from "./constants";
+import { redirectToLogin } from "./queryClient";
+
+export type StaffRole = 'admin' | 'operator' | 'viewer';
 
 export interface Staff {
     id: number;
     name: string;
+    role: StaffRole;
+    categories: string[] | null; // null の場合はすべてのカテゴリ
+    active: boolean;
+    createdAt: string;
+}
+
+export interface StaffPermissionsRequest {
+    role: StaffRole;
+    categories: string[] | null;
+}
+
+export interface LoginRequest {
+    loginId?: string;
+    password?: string;
+    staffId?: number;
+    pin?: string;
+}
+
+export interface LoginResponse {
+    staff: Staff;
+    token: string;
+    expiresAt: string;
+}
+
+export interface StaffCredentialsRequest {
+    loginId?: string;
+    password?: string;
+    pin?: string; // 空文字でPINログインを無効化
+}
+
+export interface Category {
+    code: string;
+    name: string;
+    nameEn: string;
+    sortOrder: number;
     createdAt: string;
+    updatedAt: string;
+}
+
+export interface CategoryRequest {
+    code?: string; // 登録時のみ（変更不可）
+    name: string;
+    nameEn?: string;
 }
 
 export interface ProductType {
     id: number;
     category: ProductCategory;
     name: string;
+    active: boolean;
+    sortOrder: number;
+    createdAt: string;
+}
+
+// 追加項目の値（キーは AttributeDefinition.key、日付は YYYY-MM-DD）
+export type AttributeValues = Record<string, string | number>;
+
+export type AttributeDataType = 'text' | 'number' | 'date' | 'enum';
+
+export interface AttributeDefinition {
+    id: number;
+    category: string;
+    productTypeId: number | null; // null の場合はカテゴリ内のすべての製品タイプ
+    key: string;
+    label: string;
+    dataType: AttributeDataType;
+    options: string[] | null; // enum の選択肢
+    required: boolean;
+    active: boolean;
+    sortOrder: number;
+    createdAt: string;
+    updatedAt: string;
+}
+
+export interface AttributeDefinitionRequest {
+    key?: string; // 登録時のみ（変更不可）
+    label: string;
+    dataType?: AttributeDataType; // 登録時のみ（変更不可）
+    options?: string[];
+    required?: boolean;
+    productTypeId?: number | null;
+}
+
+export interface PCModelNumber {
+    id: number;
+    modelNumber: string;
+    manufacturer: string;
+    displayName: string;
+    defaultWarrantyPeriod: number | null; // 入庫時に保証期間を省略した場合の既定値（月）
+    cpu: string;
+    memory: string;
+    storage: string;
+    os: string;
+    active: boolean;
     createdAt: string;
+    updatedAt: string;
+}
+
+export interface PCModelNumberRequest {
+    modelNumber: string;
+    manufacturer?: string;
+    displayName?: string;
+    defaultWarrantyPeriod?: number | null;
+    cpu?: string;
+    memory?: string;
+    storage?: string;
+    os?: string;
 }
 
 export interface PCDetails {
@@ -19,6 +121,7 @@ export interface PCDetails {
     serialNumber: string;
     purchaseDate: string;
     warrantyPeriod?: number;
+    warrantyEndDate?: string | null; // 購入日 + 保証期間（保証期間が未設定・0の場合は null）
 }
 
 export interface VestDetails {
@@ -38,6 +141,7 @@ export interface Product {
     type: ProductType;
     pcDetails?: PCDetails;
     vestDetails?: VestDetails;
+    attributes?: AttributeValues;
     staff?: {
         id: number;
         name: string;
@@ -54,19 +158,25 @@ export interface InboundProduct {
         purchaseDate: string;
         warrantyPeriod?: number;
     };
+    vestDetails?: VestDetails;
+    attributes?: AttributeValues;
 }
 
 export interface InboundRequest {
     products: InboundProduct[];
-    staffId: number;
+    staffId?: number; // サーバー側でログイン中の担当者が使われる
     inboundDate: string;
 }
 
 export interface OutboundRequest {
-    productIdStart: string;
-    productIdEnd: string;
-    staffId: number;
+    productIdStart?: string;
+    productIdEnd?: string;
+    productIds?: string[];
+    allowGaps?: boolean;
+    staffId?: number; // サーバー側でログイン中の担当者が使われる
     outboundDate: string;
+    customerId?: number;
+    purchaserId?: number;
     customerNumber?: string;
     customerName?: string;
     purchaserNumber?: string;
@@ -74,6 +184,217 @@ export interface OutboundRequest {
     notes?: string;
 }
 
+// 顧客・購入者マスタ
+export interface Partner {
+    id: number;
+    number: string | null;
+    name: string;
+    postalCode: string | null;
+    address: string | null;
+    phone: string | null;
+    email: string | null;
+    contactName: string | null;
+    notes: string | null;
+    createdAt: string;
+    updatedAt: string;
+}
+
+export type PartnerRequest = Omit<Partner, 'id' | 'number' | 'createdAt' | 'updatedAt'> & { number: string };
+
+export interface PartnerLookup {
+    id: number;
+    number: string;
+    name: string;
+}
+
+export interface OutboundGap {
+    productId: string;
+    reason: string;
+}
+
+export interface OutboundPreview {
+    canSubmit: boolean;
+    count: number;
+    products: Array<{
+        productId: string;
+        lotNumber: string;
+        type: { id: number; name: string };
+    }>;
+    types: Array<{ id: number; name: string; count: number }>;
+    lotNumbers: string[];
+    gaps: OutboundGap[] | null;
+    warnings: string[];
+}
+
+export interface VoidRequest {
+    staffId?: number; // サーバー側でログイン中の担当者が使われる
+    reason: string;
+}
+
+export interface ReturnRequest {
+    productIds?: string[];
+    outboundNumber?: string;
+    staffId?: number; // サーバー側でログイン中の担当者が使われる
+    returnDate: string;
+    reason: string;
+    notes?: string;
+}
+
+export interface InboundImportOptions {
+    inboundDate: string;
+    typeId?: number;
+    mapping?: Record<string, string>;
+    sheet?: string;
+    confirm?: boolean;
+}
+
+export interface InboundImportPreview {
+    rows: Array<{ row: number; valid: boolean; product: InboundProduct }>;
+    errors: Array<{ row: number; productId?: string; field: string; code: string; message: string }>;
+    totalCount: number;
+    errorCount: number;
+    canConfirm: boolean;
+}
+
+export interface ProductTimelineEvent {
+    type: 'inbound' | 'outbound' | 'outbound_void' | 'return' | 'inbound_void';
+    date: string;
+    staff: { id: number; name: string } | null;
+    inboundNumber?: string;
+    outboundNumber?: string;
+    returnNumber?: string;
+    customerNumber?: string;
+    customerName?: string;
+    purchaserNumber?: string;
+    purchaserName?: string;
+    notes?: string;
+    reason?: string;
+    voided?: boolean;
+}
+
+export interface ProductDetail {
+    product: Product;
+    timeline: ProductTimelineEvent[];
+}
+
+export interface AuditLogEntry {
+    id: number;
+    occurredAt: string;
+    actor: { id: number; name: string } | null;
+    action: 'create' | 'update' | 'delete';
+    entityType: string;
+    entityId: string;
+    before: unknown;
+    after: unknown;
+    requestId: string | null;
+    prevHash: string | null;
+    hash: string;
+}
+
+export interface AuditLogParams extends ListParams {
+    entityType?: string;
+    entityId?: string;
+    action?: string;
+    requestId?: string;
+}
+
+export interface AuditVerifyResult {
+    valid: boolean;
+    checkedCount: number;
+    lastId: number | null;
+    lastHash: string | null;
+    brokenId?: number;
+    reason?: string;
+}
+
+export type SearchResultType = 'product' | 'serial' | 'attribute' | 'lot' | 'inbound' | 'outbound' | 'customer' | 'purchaser';
+
+export interface SearchResult {
+    type: SearchResultType;
+    value: string;
+    match: 'exact' | 'prefix' | 'partial';
+    category: string | null;
+    details: Record<string, unknown>;
+}
+
+export type ExportFormat = 'csv' | 'xlsx';
+
+// 一覧取得の絞り込み・並び替え・ページング（sort は "-" を付けると降順）
+export interface ListParams {
+    limit?: number;
+    cursor?: string;
+    sort?: string;
+    status?: string;
+    typeId?: number;
+    lotNumber?: string;
+    staffId?: number;
+    dateFrom?: string;
+    dateTo?: string;
+    customer?: string;
+    modelNumber?: string;
+    vestType?: string;
+    vestSize?: string;
+    vestHasLogo?: boolean;
+}
+
+export interface ListResponse<T> {
+    items: T[];
+    totalCount: number;
+    nextCursor: string | null;
+    hasMore: boolean;
+}
+
+// 1回に取得する件数の上限（サーバーの maxPageSize と同じ）
+export const MAX_PAGE_SIZE = 500;
+
+// すべてのページを順に取得して結合する（ページングに対応していない画面用）
+export const fetchAllPages = async <T>(
+    fetchPage: (params: ListParams) => Promise<{ data: ListResponse<T> }>,
+    params?: ListParams,
+): Promise<T[]> => {
+    const items: T[] = [];
+    let cursor: string | undefined;
+    for (;;) {
+        const response = await fetchPage({ ...params, limit: MAX_PAGE_SIZE, cursor });
+        items.push(...response.data.items);
+        if (!response.data.hasMore || !response.data.nextCursor) {
+            return items;
+        }
+        cursor = response.data.nextCursor;
+    }
+};
+
+export type PCWarrantyStatus = 'expired' | 'expiring';
+
+export interface PCWarrantyReportParams extends ListParams {
+    days?: number; // 何日以内に保証が切れるPCを含めるか（既定30日）
+    warrantyStatus?: PCWarrantyStatus;
+}
+
+export interface PCWarrantyReportItem {
+    id: number;
+    productId: string;
+    status: 'in_stock' | 'out_of_stock';
+    type: { id: number; name: string };
+    modelNumber: string;
+    serialNumber: string;
+    purchaseDate: string;
+    warrantyPeriod: number;
+    warrantyEndDate: string;
+    daysRemaining: number;
+    warrantyStatus: PCWarrantyStatus;
+    lastOutbound?: {
+        outboundNumber: string;
+        outboundDate: string;
+        customerName: string;
+    };
+}
+
+export interface PCWarrantyReport extends ListResponse<PCWarrantyReportItem> {
+    asOf: string;
+    days: number;
+}
+
 export interface DashboardStats {
     totalProducts: number;
     byCategory: Record<string, number>;
@@ -92,8 +413,28 @@ export const api = axios.create({
     headers: {
         'Content-Type': 'application/json',
     },
+    withCredentials: true, // セッションCookieを送信
 });
 
+// 未ログイン・セッション切れ（401）の場合はログイン画面へ移動する（ログイン自体の失敗は画面で表示する）
+api.interceptors.response.use(
+    (response) => response,
+    (error) => {
+        if (axios.isAxiosError(error) && error.response?.status === 401 && error.config?.url !== '/auth/login') {
+            redirectToLogin();
+        }
+        return Promise.reject(error);
+    },
+);
+
+// 認証API
+export const authApi = {
+    login: (data: LoginRequest) => api.post<LoginResponse>('/auth/login', data),
+    logout: () => api.post<{ message: string }>('/auth/logout'),
+    me: () => api.get<Staff>('/auth/me'),
+    getPinLoginStaff: () => api.get<Array<{ id: number; name: string }>>('/auth/staff'),
+};
+
 // 在庫管理API
 export const inventoryApi = {
     checkProductId: (category: string, productId: string) =>
@@ -104,38 +445,169 @@ export const inventoryApi = {
                 category: string;
                 typeName: string;
                 status: string;
+                vestDetails?: VestDetails;
             };
         }>(`/inventory/${category}/check-product-id/${productId}`),
-    getByCategory: (category: string) =>
-        api.get<Product[]>(`/inventory/${category}`),
-    inbound: (category: string, data: InboundRequest) =>
-        api.post(`/inbound/${category}`, data),
-    outbound: (category: string, data: OutboundRequest) =>
-        api.post(`/outbound/${category}`, data),
-    getInboundHistory: (category: string, all?: boolean) =>
-        api.get(`/inbound/${category}/history${all ? '?all=true' : ''}`),
-    getOutboundHistory: (category: string, all?: boolean) =>
-        api.get(`/outbound/${category}/history${all ? '?all=true' : ''}`),
+    getByCategory: (category: string, params?: ListParams) =>
+        api.get<ListResponse<Product>>(`/inventory/${category}`, { params }),
+    inbound: (category: string, data: InboundRequest, idempotencyKey?: string) =>
+        api.post(`/inbound/${category}`, data, {
+            headers: idempotencyKey ? { 'Idempotency-Key': idempotencyKey } : undefined,
+        }),
+    voidInbound: (category: string, inboundNumber: string, data: VoidRequest) =>
+        api.post(`/inbound/${category}/void/${inboundNumber}`, data),
+    getInboundVoids: (category: string) =>
+        api.get(`/inbound/${category}/voids`),
+    importInbound: (category: string, file: File, options: InboundImportOptions) => {
+        const form = new FormData();
+        form.append('file', file);
+        form.append('inboundDate', options.inboundDate);
+        if (options.typeId) form.append('typeId', String(options.typeId));
+        if (options.mapping) form.append('mapping', JSON.stringify(options.mapping));
+        if (options.sheet) form.append('sheet', options.sheet);
+        form.append('confirm', String(!!options.confirm));
+        return api.post<InboundImportPreview>(`/inbound/${category}/import`, form);
+    },
+    outbound: (category: string, data: OutboundRequest, idempotencyKey?: string) =>
+        api.post(`/outbound/${category}`, data, {
+            headers: idempotencyKey ? { 'Idempotency-Key': idempotencyKey } : undefined,
+        }),
+    previewOutbound: (category: string, data: OutboundRequest) =>
+        api.post<OutboundPreview>(`/outbound/${category}/preview`, data),
+    voidOutbound: (category: string, outboundNumber: string, data: VoidRequest) =>
+        api.post(`/outbound/${category}/void/${outboundNumber}`, data),
+    getInboundHistory: (category: string, params?: ListParams) =>
+        api.get<ListResponse<any>>(`/inbound/${category}/history`, { params }),
+    getOutboundHistory: (category: string, params?: ListParams) =>
+        api.get<ListResponse<any>>(`/outbound/${category}/history`, { params }),
+    returnProducts: (category: string, data: ReturnRequest) =>
+        api.post(`/returns/${category}`, data),
+    getReturnHistory: (category: string) =>
+        api.get(`/returns/${category}/history`),
+    exportInventory: (category: string, format: ExportFormat, params?: ListParams) =>
+        api.get<Blob>(`/inventory/${category}`, { params: { ...params, format }, responseType: 'blob' }),
+    exportInboundHistory: (category: string, format: ExportFormat, params?: ListParams) =>
+        api.get<Blob>(`/inbound/${category}/history`, { params: { ...params, format }, responseType: 'blob' }),
+    exportOutboundHistory: (category: string, format: ExportFormat, params?: ListParams) =>
+        api.get<Blob>(`/outbound/${category}/history`, { params: { ...params, format }, responseType: 'blob' }),
 };
 
+// 製品詳細API
+export const productsApi = {
+    getDetail: (productId: string) =>
+        api.get<ProductDetail>(`/products/${encodeURIComponent(productId)}`),
+};
+
+// 横断検索API
+// 監査ログAPI
+export const auditLogApi = {
+    getAll: (params?: AuditLogParams) =>
+        api.get<ListResponse<AuditLogEntry>>('/audit-log', { params }),
+    // 前回の検証結果の lastId / lastHash を指定すると末尾の記録の削除も検出する
+    verify: (anchor?: { lastId: number; lastHash: string }) =>
+        api.get<AuditVerifyResult>('/audit-log/verify', { params: anchor }),
+};
+
+export const searchApi = {
+    search: (q: string, params?: { limit?: number; types?: SearchResultType[] }) =>
+        api.get<{ query: string; results: SearchResult[] }>('/search', {
+            params: { q, limit: params?.limit, types: params?.types?.join(',') },
+        }),
+};
+
+// 顧客・購入者マスタAPI
+const partnerApi = (path: string) => ({
+    getAll: (q?: string) => api.get<Partner[]>(path, { params: { q } }),
+    lookup: (q: string, limit?: number) =>
+        api.get<PartnerLookup[]>(`${path}/lookup`, { params: { q, limit } }),
+    get: (id: number) => api.get<Partner>(`${path}/${id}`),
+    create: (data: PartnerRequest) => api.post<Partner>(path, data),
+    update: (id: number, data: PartnerRequest) => api.put<Partner>(`${path}/${id}`, data),
+    delete: (id: number) => api.delete<{ message: string }>(`${path}/${id}`),
+});
+
+export const customersApi = partnerApi('/customers');
+export const purchasersApi = partnerApi('/purchasers');
+
 // スタッフAPI
 export const staffApi = {
-    getAll: () => api.get<Staff[]>('/staff'),
+    // 既定では有効な担当者のみ（includeInactive で無効化した担当者も含める）
+    getAll: (params?: { includeInactive?: boolean }) => api.get<Staff[]>('/staff', { params }),
     create: (data: { name: string }) => api.post<Staff>('/staff', data),
+    update: (id: number, data: { name: string }) =>
+        api.put<{ message: string }>(`/staff/${id}`, data),
+    deactivate: (id: number) => api.post<{ message: string }>(`/staff/${id}/deactivate`),
+    reactivate: (id: number) => api.post<{ message: string }>(`/staff/${id}/reactivate`),
     delete: (id: number) => api.delete(`/staff/${id}`),
+    updateCredentials: (id: number, data: StaffCredentialsRequest) =>
+        api.put<{ message: string }>(`/staff/${id}/credentials`, data),
+    updatePermissions: (id: number, data: StaffPermissionsRequest) =>
+        api.put<{ message: string }>(`/staff/${id}/permissions`, data),
+};
+
+// カテゴリAPI
+export const categoriesApi = {
+    getAll: () => api.get<Category[]>('/categories'),
+    create: (data: CategoryRequest) => api.post<Category>('/categories', data),
+    update: (code: string, data: CategoryRequest) => api.put<Category>(`/categories/${code}`, data),
+    // すべてのカテゴリのコードを表示順に指定
+    reorder: (codes: string[]) => api.put<{ message: string }>('/categories/order', { codes }),
+    delete: (code: string) => api.delete<{ message: string }>(`/categories/${code}`),
 };
 
 // 製品タイプAPI
 export const productTypesApi = {
-    getByCategory: (category: string) =>
-        api.get<ProductType[]>(`/product-types/${category}`),
+    // 既定では有効な製品タイプのみ（includeInactive で無効化した製品タイプも含める）
+    getByCategory: (category: string, params?: { includeInactive?: boolean }) =>
+        api.get<ProductType[]>(`/product-types/${category}`, { params }),
+    create: (category: string, data: { name: string }) =>
+        api.post<ProductType>(`/product-types/${category}`, data),
+    update: (category: string, id: number, data: { name: string }) =>
+        api.put<{ message: string }>(`/product-types/${category}/${id}`, data),
+    deactivate: (category: string, id: number) =>
+        api.post<{ message: string }>(`/product-types/${category}/${id}/deactivate`),
+    reactivate: (category: string, id: number) =>
+        api.post<{ message: string }>(`/product-types/${category}/${id}/reactivate`),
+    // カテゴリ内のすべての製品タイプのIDを表示順に指定
+    reorder: (category: string, ids: number[]) =>
+        api.put<{ message: string }>(`/product-types/${category}/order`, { ids }),
+    delete: (category: string, id: number) =>
+        api.delete<{ success: boolean }>(`/product-types/${category}/${id}`),
+};
+
+// 追加項目API
+export const attributeDefinitionsApi = {
+    // 既定では有効な項目のみ（includeInactive で無効化した項目も含める）
+    getByCategory: (category: string, params?: { includeInactive?: boolean }) =>
+        api.get<AttributeDefinition[]>(`/attribute-definitions/${category}`, { params }),
+    create: (category: string, data: AttributeDefinitionRequest) =>
+        api.post<AttributeDefinition>(`/attribute-definitions/${category}`, data),
+    update: (category: string, id: number, data: AttributeDefinitionRequest) =>
+        api.put<AttributeDefinition>(`/attribute-definitions/${category}/${id}`, data),
+    deactivate: (category: string, id: number) =>
+        api.post<AttributeDefinition>(`/attribute-definitions/${category}/${id}/deactivate`),
+    reactivate: (category: string, id: number) =>
+        api.post<AttributeDefinition>(`/attribute-definitions/${category}/${id}/reactivate`),
+    delete: (category: string, id: number) =>
+        api.delete<{ message: string }>(`/attribute-definitions/${category}/${id}`),
 };
 
 // PC型番API
 export const pcModelNumbersApi = {
-    getAll: () => api.get<{ id: number; modelNumber: string }[]>('/pc-model-numbers'),
-    create: (data: { modelNumber: string }) =>
-        api.post<{ id: number; modelNumber: string }>('/pc-model-numbers', data),
+    // includeInactive: true で無効化した型番も含める（既定は入庫の選択肢として有効な型番のみ）
+    getAll: (params?: { includeInactive?: boolean }) =>
+        api.get<PCModelNumber[]>('/pc-model-numbers', { params }),
+    create: (data: PCModelNumberRequest) =>
+        api.post<PCModelNumber>('/pc-model-numbers', data),
+    // modelNumber を変更すると登録済みのPCの型番も変更される
+    update: (modelNumber: string, data: PCModelNumberRequest) =>
+        api.put<{ pcModelNumber: PCModelNumber; affectedCount: number }>(
+            `/pc-model-numbers/${encodeURIComponent(modelNumber)}`, data
+        ),
+    deactivate: (modelNumber: string) =>
+        api.post<{ message: string }>(`/pc-model-numbers/${encodeURIComponent(modelNumber)}/deactivate`),
+    reactivate: (modelNumber: string) =>
+        api.post<{ message: string }>(`/pc-model-numbers/${encodeURIComponent(modelNumber)}/reactivate`),
     delete: (modelNumber: string) =>
         api.delete<{ message: string }>(`/pc-model-numbers/${modelNumber}`),
 };
@@ -145,6 +617,14 @@ export const dashboardApi = {
     getStats: () => api.get<DashboardStats>('/dashboard/stats'),
 };
 
+// レポートAPI
+export const reportsApi = {
+    getPcWarranty: (params?: PCWarrantyReportParams) =>
+        api.get<PCWarrantyReport>('/reports/pc-warranty', { params }),
+    exportPcWarranty: (format: ExportFormat, params?: PCWarrantyReportParams) =>
+        api.get<Blob>('/reports/pc-warranty', { params: { ...params, format }, responseType: 'blob' }),
+};
+
 // ロット番号API
 export const lotNumberApi = {
     getLatest: (category: string) =>
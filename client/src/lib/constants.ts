@@ -1,3 +1,5 @@
+// 専用の入力項目（PC詳細・ベスト詳細）を持つ初期カテゴリのコード
+// カテゴリの一覧・表示名は GET /categories から取得する（useCategories）
 export const PRODUCT_CATEGORIES = {
   DEVICE: "device",
   STATION: "station",
@@ -6,7 +8,8 @@ export const PRODUCT_CATEGORIES = {
   PC: "pc",
 } as const;
 
-export type ProductCategory = typeof PRODUCT_CATEGORIES[keyof typeof PRODUCT_CATEGORIES];
+// カテゴリのコード（追加したカテゴリを含む）
+export type ProductCategory = string;
 
 export const INITIAL_PRODUCT_TYPES = {
   [PRODUCT_CATEGORIES.DEVICE]: ["FIFA", "WR"],
@@ -1,4 +1,8 @@
 import { QueryClient } from "@tanstack/react-query";
+import { navigate } from "wouter/use-browser-location";
+
+// ログイン中の担当者のクエリキー（未ログインの場合は null）
+export const AUTH_QUERY_KEY = ["auth", "me"] as const;
 
 export const queryClient = new QueryClient({
   defaultOptions: {
@@ -9,6 +13,9 @@ export const queryClient = new QueryClient({
         });
 
         if (!res.ok) {
+          if (res.status === 401) {
+            redirectToLogin();
+          }
           if (res.status >= 500) {
             throw new Error(`${res.status}: ${res.statusText}`);
           }
@@ -28,3 +35,11 @@ export const queryClient = new QueryClient({
     }
   },
 });
+
+// 未ログイン・セッション切れ（401）の場合はログイン画面へ移動する
+export function redirectToLogin() {
+  queryClient.setQueryData(AUTH_QUERY_KEY, null);
+  if (window.location.pathname !== "/login") {
+    navigate("/login", { replace: true });
+  }
+}
@@ -0,0 +1,146 @@
+import { useState, type FormEvent } from "react";
+import { Redirect } from "wouter";
+import { useQuery } from "@tanstack/react-query";
+import { Card, CardContent, CardHeader, CardTitle } from "@/components/ui/card";
+import { Tabs, TabsContent, TabsList, TabsTrigger } from "@/components/ui/tabs";
+import {
+  Select,
+  SelectContent,
+  SelectItem,
+  SelectTrigger,
+  SelectValue,
+} from "@/components/ui/select";
+import { Input } from "@/components/ui/input";
+import { Label } from "@/components/ui/label";
+import { Button } from "@/components/ui/button";
+import { useAuth } from "@/hooks/use-auth";
+import { authApi } from "@/lib/api";
+
+export default function LoginPage() {
+  const { currentStaff, isLoading, login } = useAuth();
+  const [loginId, setLoginId] = useState("");
+  const [password, setPassword] = useState("");
+  const [staffId, setStaffId] = useState("");
+  const [pin, setPin] = useState("");
+
+  // PINでログインできる担当者
+  const { data: pinStaff = [] } = useQuery({
+    queryKey: ["auth", "staff"],
+    queryFn: async () => {
+      const response = await authApi.getPinLoginStaff();
+      return response.data;
+    },
+  });
+
+  if (isLoading) {
+    return <div>読み込み中...</div>;
+  }
+
+  if (currentStaff) {
+    return <Redirect to="/" />;
+  }
+
+  const handlePasswordLogin = (e: FormEvent) => {
+    e.preventDefault();
+    login.mutate({ loginId, password });
+  };
+
+  const handlePinLogin = (e: FormEvent) => {
+    e.preventDefault();
+    login.mutate({ staffId: Number(staffId), pin });
+  };
+
+  const error = login.error as any;
+  const errorMessage = error ? error.response?.data?.error || error.message : null;
+
+  return (
+    <div className="flex min-h-screen items-center justify-center bg-muted p-4">
+      <Card className="w-full max-w-sm">
+        <CardHeader>
+          <CardTitle>ログイン</CardTitle>
+        </CardHeader>
+        <CardContent className="space-y-4">
+          <Tabs defaultValue="password" onValueChange={() => login.reset()}>
+            <TabsList className="grid w-full grid-cols-2">
+              <TabsTrigger value="password">ログインID</TabsTrigger>
+              <TabsTrigger value="pin">PIN</TabsTrigger>
+            </TabsList>
+
+            <TabsContent value="password">
+              <form onSubmit={handlePasswordLogin} className="space-y-4">
+                <div className="space-y-2">
+                  <Label htmlFor="loginId">ログインID</Label>
+                  <Input
+                    id="loginId"
+                    autoComplete="username"
+                    value={loginId}
+                    onChange={(e) => setLoginId(e.target.value)}
+                  />
+                </div>
+                <div className="space-y-2">
+                  <Label htmlFor="password">パスワード</Label>
+                  <Input
+                    id="password"
+                    type="password"
+                    autoComplete="current-password"
+                    value={password}
+                    onChange={(e) => setPassword(e.target.value)}
+                  />
+                </div>
+                <Button
+                  type="submit"
+                  className="w-full"
+                  disabled={login.isPending || !loginId || !password}
+                >
+                  ログイン
+                </Button>
+              </form>
+            </TabsContent>
+
+            <TabsContent value="pin">
+              <form onSubmit={handlePinLogin} className="space-y-4">
+                <div className="space-y-2">
+                  <Label>担当者</Label>
+                  <Select value={staffId} onValueChange={setStaffId}>
+                    <SelectTrigger>
+                      <SelectValue placeholder="担当者を選択" />
+                    </SelectTrigger>
+                    <SelectContent>
+                      {pinStaff.map((staff) => (
+                        <SelectItem key={staff.id} value={String(staff.id)}>
+                          {staff.name}
+                        </SelectItem>
+                      ))}
+                    </SelectContent>
+                  </Select>
+                </div>
+                <div className="space-y-2">
+                  <Label htmlFor="pin">PIN</Label>
+                  <Input
+                    id="pin"
+                    type="password"
+                    inputMode="numeric"
+                    autoComplete="off"
+                    value={pin}
+                    onChange={(e) => setPin(e.target.value)}
+                  />
+                </div>
+                <Button
+                  type="submit"
+                  className="w-full"
+                  disabled={login.isPending || !staffId || !pin}
+                >
+                  ログイン
+                </Button>
+              </form>
+            </TabsContent>
+          </Tabs>
+
+          {errorMessage && (
+            <p className="text-sm text-destructive">{errorMessage}</p>
+          )}
+        </CardContent>
+      </Card>
+    </div>
+  );
+}
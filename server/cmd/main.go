@@ -4,6 +4,7 @@ import (
     "log"
     "os"
     "github.com/gin-gonic/gin"
+    "inventory-tracker/server/internal/auth"
     "inventory-tracker/server/internal/db"
     "inventory-tracker/server/internal/routes"
 )
@@ -21,6 +22,11 @@ func main() {
         log.Fatalf("データベース初期化エラー: %v", err)
     }
 
+    // 初期管理者の設定
+    if err := auth.BootstrapAdmin(); err != nil {
+        log.Fatalf("初期管理者の設定エラー: %v", err)
+    }
+
     // ルーターの設定
     router := routes.SetupRouter()
 
@@ -0,0 +1,176 @@
+package audit
+
+import (
+    "database/sql"
+    "encoding/json"
+    "fmt"
+    "github.com/gin-gonic/gin"
+    "inventory-tracker/server/internal/auth"
+    "inventory-tracker/server/internal/middleware"
+)
+
+// 操作の種類
+const (
+    ActionCreate = "create"
+    ActionUpdate = "update"
+    ActionDelete = "delete"
+)
+
+// 監査ログの記録内容
+// Before / After は JSON に変換できる値（json.RawMessage を含む）で、該当しない場合は nil
+type Entry struct {
+    Action     string
+    EntityType string
+    EntityID   string
+    Before     interface{}
+    After      interface{}
+}
+
+// 監査ログの記録
+// 呼び出し元のトランザクション内で記録するため、変更と同時にコミット・ロールバックされる
+// ハッシュチェーンが分岐しないよう、記録はアドバイザリロックで1件ずつ直列化する
+// （READ COMMITTED のトランザクションで呼び出すこと）
+func Record(tx *sql.Tx, c *gin.Context, e Entry) error {
+    before, err := marshalData(e.Before)
+    if err != nil {
+        return fmt.Errorf("監査ログの変更前データ変換エラー: %v", err)
+    }
+    after, err := marshalData(e.After)
+    if err != nil {
+        return fmt.Errorf("監査ログの変更後データ変換エラー: %v", err)
+    }
+
+    var actorID sql.NullInt64
+    var actorName, requestID sql.NullString
+    if staff, ok := auth.CurrentStaff(c); ok && staff != nil {
+        actorID = sql.NullInt64{Int64: int64(staff.ID), Valid: true}
+        actorName = sql.NullString{String: staff.Name, Valid: true}
+    }
+    if id := middleware.CurrentRequestID(c); id != "" {
+        requestID = sql.NullString{String: id, Valid: true}
+    }
+
+    if _, err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext('audit_log'))"); err != nil {
+        return fmt.Errorf("監査ログのロックエラー: %v", err)
+    }
+    _, err = tx.Exec(`
+        INSERT INTO audit_log (
+            occurred_at, actor_staff_id, actor_name, action, entity_type, entity_id,
+            before_data, after_data, request_id, prev_hash, hash
+        )
+        SELECT v.occurred_at, v.actor_staff_id, v.actor_name, v.action, v.entity_type, v.entity_id,
+            v.before_data, v.after_data, v.request_id, prev.hash,
+            audit_log_hash(
+                prev.hash, v.occurred_at, v.actor_staff_id, v.actor_name, v.action, v.entity_type, v.entity_id,
+                v.before_data, v.after_data, v.request_id
+            )
+        FROM (
+            SELECT clock_timestamp()::timestamp AS occurred_at,
+                $1::integer AS actor_staff_id,
+                $2::text AS actor_name,
+                $3::text AS action,
+                $4::text AS entity_type,
+                $5::text AS entity_id,
+                $6::jsonb AS before_data,
+                $7::jsonb AS after_data,
+                $8::text AS request_id
+        ) v
+        LEFT JOIN (SELECT hash FROM audit_log ORDER BY id DESC LIMIT 1) prev ON TRUE
+    `, actorID, actorName, e.Action, e.EntityType, e.EntityID, before, after, requestID)
+    if err != nil {
+        return fmt.Errorf("監査ログの記録エラー: %v", err)
+    }
+    return nil
+}
+
+// 監査ログ用の行データの取得
+// query は JSON（jsonb）の列を1つ返すクエリとし、該当行がない場合は nil を返す
+func Snapshot(tx *sql.Tx, query string, args ...interface{}) (json.RawMessage, error) {
+    var data []byte
+    err := tx.QueryRow(query, args...).Scan(&data)
+    if err == sql.ErrNoRows {
+        return nil, nil
+    }
+    if err != nil {
+        return nil, fmt.Errorf("監査ログ用データの取得エラー: %v", err)
+    }
+    return json.RawMessage(data), nil
+}
+
+// 監査ログの検証結果
+type VerifyResult struct {
+    Valid        bool    `json:"valid"`
+    CheckedCount int     `json:"checkedCount"`
+    LastID       *int64  `json:"lastId"`
+    LastHash     *string `json:"lastHash"`
+    BrokenID     *int64  `json:"brokenId,omitempty"`
+    Reason       string  `json:"reason,omitempty"`
+}
+
+// ハッシュチェーンの検証
+// 各記録のハッシュを再計算し、直前の記録のハッシュとのつながりを確認する
+// 末尾の記録の削除はチェーンだけでは検出できないため、LastHash を控えておき次回の検証時に照合する
+func Verify(tx *sql.Tx) (*VerifyResult, error) {
+    rows, err := tx.Query(`
+        SELECT id, hash, prev_hash,
+            audit_log_hash(
+                prev_hash, occurred_at, actor_staff_id, actor_name, action, entity_type, entity_id,
+                before_data, after_data, request_id
+            ) AS expected_hash,
+            LAG(hash) OVER (ORDER BY id) AS previous_hash
+        FROM audit_log
+        ORDER BY id
+    `)
+    if err != nil {
+        return nil, fmt.Errorf("監査ログの取得エラー: %v", err)
+    }
+    defer rows.Close()
+
+    result := &VerifyResult{Valid: true}
+    for rows.Next() {
+        var id int64
+        var hash, expectedHash string
+        var prevHash, previousHash sql.NullString
+        if err := rows.Scan(&id, &hash, &prevHash, &expectedHash, &previousHash); err != nil {
+            return nil, fmt.Errorf("監査ログの読み取りエラー: %v", err)
+        }
+
+        if hash != expectedHash {
+            result.fail(id, "記録の内容がハッシュと一致しません（記録が変更されています）")
+            break
+        }
+        if prevHash != previousHash {
+            result.fail(id, "直前の記録とのつながりが一致しません（記録が削除または挿入されています）")
+            break
+        }
+
+        result.CheckedCount++
+        result.LastID = &id
+        result.LastHash = &hash
+    }
+    if err := rows.Err(); err != nil {
+        return nil, fmt.Errorf("監査ログの読み取りエラー: %v", err)
+    }
+    return result, nil
+}
+
+func (r *VerifyResult) fail(id int64, reason string) {
+    r.Valid = false
+    r.BrokenID = &id
+    r.Reason = reason
+}
+
+// 記録データの JSON 変換（nil の場合は NULL）
+func marshalData(v interface{}) (interface{}, error) {
+    if v == nil {
+        return nil, nil
+    }
+    data, err := json.Marshal(v)
+    if err != nil {
+        return nil, err
+    }
+    if string(data) == "null" {
+        return nil, nil
+    }
+    return string(data), nil
+}
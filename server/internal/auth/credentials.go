@@ -0,0 +1,162 @@
+package auth
+
+import (
+    "database/sql"
+    "errors"
+    "log"
+    "os"
+    "regexp"
+    "time"
+    "unicode/utf8"
+    "github.com/lib/pq"
+    "golang.org/x/crypto/bcrypt"
+    "inventory-tracker/server/internal/db"
+    "inventory-tracker/server/internal/model"
+)
+
+const (
+    // パスワードの最小文字数
+    MinPasswordLength = 8
+    // 連続して失敗した場合にロックするまでの回数
+    maxFailedLogins = 5
+    // ロックする時間
+    lockDuration = 15 * time.Minute
+)
+
+// PINは4〜8桁の数字
+var pinPattern = regexp.MustCompile(`^[0-9]{4,8}$`)
+
+var (
+    ErrInvalidCredentials = errors.New("ログインIDまたはパスワードが正しくありません")
+    ErrLocked             = errors.New("ログインに続けて失敗したため、しばらくログインできません")
+)
+
+// パスワード・PINのハッシュ化
+func HashSecret(secret string) (string, error) {
+    hash, err := bcrypt.GenerateFromPassword([]byte(secret), bcrypt.DefaultCost)
+    if err != nil {
+        return "", err
+    }
+    return string(hash), nil
+}
+
+// パスワードの確認
+func ValidatePassword(password string) error {
+    if utf8.RuneCountInString(password) < MinPasswordLength {
+        return errors.New("パスワードは8文字以上で入力してください")
+    }
+    return nil
+}
+
+// PINの確認
+func ValidatePIN(pin string) error {
+    if !pinPattern.MatchString(pin) {
+        return errors.New("PINは4〜8桁の数字で入力してください")
+    }
+    return nil
+}
+
+// ログインIDとパスワードによる認証
+func AuthenticatePassword(loginID, password string) (*model.Staff, error) {
+    return authenticate("login_id = $1", loginID, "password_hash", password)
+}
+
+// 担当者IDとPINによる認証
+func AuthenticatePIN(staffID int, pin string) (*model.Staff, error) {
+    return authenticate("id = $1", staffID, "pin_hash", pin)
+}
+
+// 認証
+// 失敗が続いた場合は一定時間ロックし、ロック中は正しい入力でも認証しない
+// 無効化された担当者は存在しないアカウントと同様に扱う
+func authenticate(condition string, key interface{}, hashColumn string, secret string) (*model.Staff, error) {
+    var staff model.Staff
+    var hash sql.NullString
+    var lockedUntil sql.NullTime
+    err := db.DB.QueryRow(`
+        SELECT id, name, role, categories, active, created_at, `+hashColumn+`, locked_until
+        FROM staff
+        WHERE active AND `+condition,
+        key,
+    ).Scan(&staff.ID, &staff.Name, &staff.Role, pq.Array(&staff.Categories), &staff.Active, &staff.CreatedAt, &hash, &lockedUntil)
+    if err == sql.ErrNoRows {
+        // 存在しないアカウントでも応答時間が変わらないようにハッシュを比較する
+        bcrypt.CompareHashAndPassword(dummyHash, []byte(secret))
+        return nil, ErrInvalidCredentials
+    }
+    if err != nil {
+        return nil, err
+    }
+    if lockedUntil.Valid && lockedUntil.Time.After(time.Now()) {
+        return nil, ErrLocked
+    }
+
+    if !hash.Valid || bcrypt.CompareHashAndPassword([]byte(hash.String), []byte(secret)) != nil {
+        _, err := db.DB.Exec(`
+            UPDATE staff
+            SET failed_login_count = failed_login_count + 1,
+                locked_until = CASE WHEN failed_login_count + 1 >= $2 THEN $3::timestamp ELSE NULL END
+            WHERE id = $1
+        `, staff.ID, maxFailedLogins, time.Now().Add(lockDuration))
+        if err != nil {
+            log.Printf("ログイン失敗回数の更新エラー: %v", err)
+        }
+        return nil, ErrInvalidCredentials
+    }
+
+    if _, err := db.DB.Exec(
+        "UPDATE staff SET failed_login_count = 0, locked_until = NULL WHERE id = $1", staff.ID,
+    ); err != nil {
+        return nil, err
+    }
+    return &staff, nil
+}
+
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy-password"), bcrypt.DefaultCost)
+
+// 初期管理者の設定
+// 認証情報を持つ担当者が1人もいない場合のみ、最初の有効な担当者にログインID "admin" と
+// INITIAL_ADMIN_PASSWORD のパスワードを設定し、管理者にする（有効な担当者がいない場合は管理者を登録する）
+// INITIAL_ADMIN_PASSWORD が未設定の場合は誰もログインできないため、エラーとする
+func BootstrapAdmin() error {
+    var configured bool
+    err := db.DB.QueryRow(
+        "SELECT EXISTS(SELECT 1 FROM staff WHERE password_hash IS NOT NULL OR pin_hash IS NOT NULL)",
+    ).Scan(&configured)
+    if err != nil || configured {
+        return err
+    }
+
+    password := os.Getenv("INITIAL_ADMIN_PASSWORD")
+    if password == "" {
+        return errors.New("認証情報を持つ担当者がいません。INITIAL_ADMIN_PASSWORD を設定してください")
+    }
+    if err := ValidatePassword(password); err != nil {
+        return err
+    }
+
+    hash, err := HashSecret(password)
+    if err != nil {
+        return err
+    }
+    result, err := db.DB.Exec(`
+        UPDATE staff SET login_id = 'admin', password_hash = $1, role = 'admin'
+        WHERE id = (SELECT MIN(id) FROM staff WHERE active)
+    `, hash)
+    if err != nil {
+        return err
+    }
+    if n, _ := result.RowsAffected(); n > 0 {
+        log.Printf("初期管理者のログインIDを設定しました: admin")
+        return nil
+    }
+
+    if _, err := db.DB.Exec(`
+        INSERT INTO staff (name, login_id, password_hash, role)
+        VALUES ('管理者', 'admin', $1, 'admin')
+    `, hash); err != nil {
+        return err
+    }
+    log.Printf("初期管理者を登録しました: ログインID = admin")
+    return nil
+}
@@ -0,0 +1,72 @@
+package auth
+
+import (
+    "inventory-tracker/server/internal/model"
+)
+
+// 権限
+type Permission string
+
+const (
+    // 在庫・履歴・マスタの閲覧
+    PermView Permission = "view"
+    // 入庫・出庫・返品の登録
+    PermOperate Permission = "operate"
+    // 入庫・出庫の取消
+    PermVoid Permission = "void"
+    // 型番・顧客・購入者・伝票番号書式などのマスタ変更
+    PermManageMasters Permission = "manage_masters"
+    // 担当者の追加・削除、認証情報・権限の変更
+    PermManageStaff Permission = "manage_staff"
+    // 監査ログの閲覧・検証
+    PermAudit Permission = "audit"
+)
+
+// ロール
+const (
+    RoleAdmin    = "admin"
+    RoleOperator = "operator"
+    RoleViewer   = "viewer"
+)
+
+// ロールごとの権限
+var rolePermissions = map[string][]Permission{
+    RoleAdmin:    {PermView, PermOperate, PermVoid, PermManageMasters, PermManageStaff, PermAudit},
+    RoleOperator: {PermView, PermOperate},
+    RoleViewer:   {PermView},
+}
+
+// ロールの確認
+func IsValidRole(role string) bool {
+    _, ok := rolePermissions[role]
+    return ok
+}
+
+// 担当者が権限を持っているか
+func HasPermission(staff *model.Staff, perm Permission) bool {
+    if staff == nil {
+        return false
+    }
+    for _, p := range rolePermissions[staff.Role] {
+        if p == perm {
+            return true
+        }
+    }
+    return false
+}
+
+// 担当者がカテゴリを担当しているか（担当カテゴリが未設定の場合はすべてのカテゴリ）
+func CanAccessCategory(staff *model.Staff, category string) bool {
+    if staff == nil {
+        return false
+    }
+    if staff.Categories == nil {
+        return true
+    }
+    for _, c := range staff.Categories {
+        if c == category {
+            return true
+        }
+    }
+    return false
+}
@@ -0,0 +1,136 @@
+package auth
+
+import (
+    "crypto/rand"
+    "crypto/sha256"
+    "database/sql"
+    "encoding/base64"
+    "encoding/hex"
+    "strings"
+    "time"
+    "github.com/gin-gonic/gin"
+    "github.com/lib/pq"
+    "inventory-tracker/server/internal/db"
+    "inventory-tracker/server/internal/model"
+)
+
+const (
+    // セッションCookie名
+    SessionCookieName = "inventory_session"
+    // セッションの有効期間
+    SessionTTL = 12 * time.Hour
+
+    contextStaffKey = "sessionStaff"
+    contextTokenKey = "sessionToken"
+)
+
+// セッションの作成
+// 推測できないランダムなトークンを発行し、DBにはハッシュのみを保存する
+func NewSession(staffID int, userAgent string) (string, time.Time, error) {
+    buf := make([]byte, 32)
+    if _, err := rand.Read(buf); err != nil {
+        return "", time.Time{}, err
+    }
+    token := base64.RawURLEncoding.EncodeToString(buf)
+    expiresAt := time.Now().Add(SessionTTL)
+
+    _, err := db.DB.Exec(`
+        INSERT INTO staff_sessions (token_hash, staff_id, user_agent, expires_at)
+        VALUES ($1, $2, $3, $4)
+    `, hashToken(token), staffID, userAgent, expiresAt)
+    if err != nil {
+        return "", time.Time{}, err
+    }
+    return token, expiresAt, nil
+}
+
+// セッションの確認
+// 有効なセッションの場合は担当者を返す（無効・期限切れの場合は nil）
+func LookupSession(token string) (*model.Staff, error) {
+    if token == "" {
+        return nil, nil
+    }
+
+    var staff model.Staff
+    err := db.DB.QueryRow(`
+        UPDATE staff_sessions ss
+        SET last_seen_at = CURRENT_TIMESTAMP
+        FROM staff s
+        WHERE ss.token_hash = $1
+        AND ss.staff_id = s.id
+        AND ss.revoked_at IS NULL
+        AND ss.expires_at > CURRENT_TIMESTAMP
+        AND s.active
+        RETURNING s.id, s.name, s.role, s.categories, s.active, s.created_at
+    `, hashToken(token)).Scan(&staff.ID, &staff.Name, &staff.Role, pq.Array(&staff.Categories), &staff.Active, &staff.CreatedAt)
+    if err == sql.ErrNoRows {
+        return nil, nil
+    }
+    if err != nil {
+        return nil, err
+    }
+    return &staff, nil
+}
+
+// セッションの無効化
+func RevokeSession(token string) error {
+    _, err := db.DB.Exec(`
+        UPDATE staff_sessions SET revoked_at = CURRENT_TIMESTAMP
+        WHERE token_hash = $1 AND revoked_at IS NULL
+    `, hashToken(token))
+    return err
+}
+
+// 担当者のすべてのセッションを無効化（認証情報の変更時）
+func RevokeStaffSessions(staffID int) error {
+    _, err := db.DB.Exec(`
+        UPDATE staff_sessions SET revoked_at = CURRENT_TIMESTAMP
+        WHERE staff_id = $1 AND revoked_at IS NULL
+    `, staffID)
+    return err
+}
+
+// リクエストのセッショントークン（Cookie または Authorization: Bearer）
+func TokenFromRequest(c *gin.Context) string {
+    if header := c.GetHeader("Authorization"); strings.HasPrefix(header, "Bearer ") {
+        return strings.TrimSpace(strings.TrimPrefix(header, "Bearer "))
+    }
+    if cookie, err := c.Cookie(SessionCookieName); err == nil {
+        return cookie
+    }
+    return ""
+}
+
+// ログイン中の担当者をコンテキストに設定
+func SetSession(c *gin.Context, staff *model.Staff, token string) {
+    c.Set(contextStaffKey, staff)
+    c.Set(contextTokenKey, token)
+}
+
+// ログイン中の担当者
+func CurrentStaff(c *gin.Context) (*model.Staff, bool) {
+    v, ok := c.Get(contextStaffKey)
+    if !ok {
+        return nil, false
+    }
+    staff, ok := v.(*model.Staff)
+    return staff, ok
+}
+
+// ログイン中の担当者ID（未ログインの場合は 0）
+func CurrentStaffID(c *gin.Context) int {
+    if staff, ok := CurrentStaff(c); ok {
+        return staff.ID
+    }
+    return 0
+}
+
+// 現在のリクエストのセッショントークン
+func CurrentToken(c *gin.Context) string {
+    return c.GetString(contextTokenKey)
+}
+
+func hashToken(token string) string {
+    sum := sha256.Sum256([]byte(token))
+    return hex.EncodeToString(sum[:])
+}
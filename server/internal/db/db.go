@@ -7,7 +7,8 @@ import (
     "log"
     "os"
     "time"
-    _ "github.com/lib/pq"
+    "github.com/lib/pq"
+    "inventory-tracker/server/internal/model"
 )
 
 var DB *sql.DB
@@ -63,10 +64,30 @@ func InitDB() error {
 }
 
 // トランザクションを開始
+// 分離レベルは READ COMMITTED とする。更新処理はスナップショットの一貫性ではなく、次の排他に依存している
+//   - 伝票番号の採番カウンター（document_counters）は UPSERT により行ロックを取得する
+//   - 在庫状態の変更は status を条件とした UPDATE の更新件数で確認する
+//   - 重複登録は一意制約で検出する
+//   - 監査ログはアドバイザリロックの取得後に直前のハッシュを読み取る
+// REPEATABLE READ ではロック待ちの後に同じ行を更新するとシリアライズエラーとなり、
+// 監査ログの直前のハッシュもロック取得前のスナップショットから読まれて連鎖が分岐する。
+// 一貫した読み取りが必要な処理は BeginReadOnlyTx を使用する
 func BeginTx() (*sql.Tx, error) {
     // 分離レベルを指定してトランザクションを開始
+    tx, err := DB.BeginTx(context.Background(), &sql.TxOptions{
+        Isolation: sql.LevelReadCommitted,
+    })
+    if err != nil {
+        return nil, fmt.Errorf("トランザクション開始エラー: %v", err)
+    }
+    return tx, nil
+}
+
+// 読み取り専用トランザクションを開始
+func BeginReadOnlyTx() (*sql.Tx, error) {
     tx, err := DB.BeginTx(context.Background(), &sql.TxOptions{
         Isolation: sql.LevelRepeatableRead,
+        ReadOnly:  true,
     })
     if err != nil {
         return nil, fmt.Errorf("トランザクション開始エラー: %v", err)
@@ -74,49 +95,78 @@ func BeginTx() (*sql.Tx, error) {
     return tx, nil
 }
 
-// PCモデル番号の取得
-func GetPCModelNumbers() ([]string, error) {
-    rows, err := DB.Query("SELECT model_number FROM pc_model_numbers ORDER BY model_number")
+// PC型番の取得
+// includeInactive が false の場合は有効な型番のみ（入庫の選択肢）
+func GetPCModelNumbers(includeInactive bool) ([]model.PCModelNumber, error) {
+    rows, err := DB.Query(`
+        SELECT id, model_number, manufacturer, display_name, default_warranty_period,
+            cpu, memory, storage, os, active, created_at, updated_at
+        FROM pc_model_numbers
+        WHERE active OR $1
+        ORDER BY model_number
+    `, includeInactive)
     if err != nil {
         return nil, err
     }
     defer rows.Close()
 
-    var models []string
+    models := []model.PCModelNumber{}
     for rows.Next() {
-        var model string
-        if err := rows.Scan(&model); err != nil {
+        var m model.PCModelNumber
+        var warrantyPeriod sql.NullInt32
+        if err := rows.Scan(
+            &m.ID, &m.ModelNumber, &m.Manufacturer, &m.DisplayName, &warrantyPeriod,
+            &m.CPU, &m.Memory, &m.Storage, &m.OS, &m.Active, &m.CreatedAt, &m.UpdatedAt,
+        ); err != nil {
             return nil, err
         }
-        models = append(models, model)
+        if warrantyPeriod.Valid {
+            period := int(warrantyPeriod.Int32)
+            m.DefaultWarrantyPeriod = &period
+        }
+        models = append(models, m)
     }
-    return models, nil
+    return models, rows.Err()
 }
 
 // スタッフ一覧の取得
-func GetStaffList() ([]struct {
-    ID        int       `json:"id"`
-    Name      string    `json:"name"`
-    CreatedAt time.Time `json:"createdAt"`
+func GetStaffList(includeInactive bool) ([]struct {
+    ID         int       `json:"id"`
+    Name       string    `json:"name"`
+    Role       string    `json:"role"`
+    Categories []string  `json:"categories"`
+    Active     bool      `json:"active"`
+    CreatedAt  time.Time `json:"createdAt"`
 }, error) {
-    rows, err := DB.Query("SELECT id, name, created_at::timestamp FROM staff ORDER BY id")
+    rows, err := DB.Query(`
+        SELECT id, name, role, categories, active, created_at::timestamp
+        FROM staff
+        WHERE active OR $1
+        ORDER BY id
+    `, includeInactive)
     if err != nil {
         return nil, err
     }
     defer rows.Close()
 
     var staffList []struct {
-        ID        int       `json:"id"`
-        Name      string    `json:"name"`
-        CreatedAt time.Time `json:"createdAt"`
+        ID         int       `json:"id"`
+        Name       string    `json:"name"`
+        Role       string    `json:"role"`
+        Categories []string  `json:"categories"`
+        Active     bool      `json:"active"`
+        CreatedAt  time.Time `json:"createdAt"`
     }
     for rows.Next() {
         var staff struct {
-            ID        int       `json:"id"`
-            Name      string    `json:"name"`
-            CreatedAt time.Time `json:"createdAt"`
+            ID         int       `json:"id"`
+            Name       string    `json:"name"`
+            Role       string    `json:"role"`
+            Categories []string  `json:"categories"`
+            Active     bool      `json:"active"`
+            CreatedAt  time.Time `json:"createdAt"`
         }
-        if err := rows.Scan(&staff.ID, &staff.Name, &staff.CreatedAt); err != nil {
+        if err := rows.Scan(&staff.ID, &staff.Name, &staff.Role, pq.Array(&staff.Categories), &staff.Active, &staff.CreatedAt); err != nil {
             return nil, err
         }
         staffList = append(staffList, staff)
@@ -124,33 +174,72 @@ func GetStaffList() ([]struct {
     return staffList, nil
 }
 
-// 製品タイプの取得
-func GetProductTypes(category string) ([]struct {
-    ID       int    `json:"id"`
-    Name     string `json:"name"`
-    Category string `json:"category"`
+// カテゴリ一覧の取得（表示順）
+func GetCategories() ([]model.Category, error) {
+    rows, err := DB.Query(`
+        SELECT code, name, name_en, sort_order, created_at, updated_at
+        FROM categories
+        ORDER BY sort_order, code
+    `)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    categories := []model.Category{}
+    for rows.Next() {
+        var c model.Category
+        if err := rows.Scan(&c.Code, &c.Name, &c.NameEn, &c.SortOrder, &c.CreatedAt, &c.UpdatedAt); err != nil {
+            return nil, err
+        }
+        categories = append(categories, c)
+    }
+    return categories, rows.Err()
+}
+
+// カテゴリの存在チェック
+func CategoryExists(code string) (bool, error) {
+    var exists bool
+    err := DB.QueryRow("SELECT EXISTS(SELECT 1 FROM categories WHERE code = $1)", code).Scan(&exists)
+    return exists, err
+}
+
+// 製品タイプの取得（表示順）
+// includeInactive が false の場合は有効な製品タイプのみ（入庫の選択肢）
+func GetProductTypes(category string, includeInactive bool) ([]struct {
+    ID        int    `json:"id"`
+    Name      string `json:"name"`
+    Category  string `json:"category"`
+    Active    bool   `json:"active"`
+    SortOrder int    `json:"sortOrder"`
 }, error) {
-    rows, err := DB.Query(
-        "SELECT id, name, category FROM product_types WHERE category = $1 ORDER BY id",
-        category,
-    )
+    rows, err := DB.Query(`
+        SELECT id, name, category, active, sort_order
+        FROM product_types
+        WHERE category = $1 AND (active OR $2)
+        ORDER BY sort_order, id
+    `, category, includeInactive)
     if err != nil {
         return nil, err
     }
     defer rows.Close()
 
     var types []struct {
-        ID       int    `json:"id"`
-        Name     string `json:"name"`
-        Category string `json:"category"`
+        ID        int    `json:"id"`
+        Name      string `json:"name"`
+        Category  string `json:"category"`
+        Active    bool   `json:"active"`
+        SortOrder int    `json:"sortOrder"`
     }
     for rows.Next() {
         var t struct {
-            ID       int    `json:"id"`
-            Name     string `json:"name"`
-            Category string `json:"category"`
+            ID        int    `json:"id"`
+            Name      string `json:"name"`
+            Category  string `json:"category"`
+            Active    bool   `json:"active"`
+            SortOrder int    `json:"sortOrder"`
         }
-        if err := rows.Scan(&t.ID, &t.Name, &t.Category); err != nil {
+        if err := rows.Scan(&t.ID, &t.Name, &t.Category, &t.Active, &t.SortOrder); err != nil {
             return nil, err
         }
         types = append(types, t)
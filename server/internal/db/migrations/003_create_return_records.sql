@@ -0,0 +1,19 @@
+-- Create return records table
+CREATE TABLE IF NOT EXISTS return_records (
+    id SERIAL PRIMARY KEY,
+    product_id TEXT REFERENCES products(product_id),
+    outbound_record_id INTEGER NOT NULL REFERENCES outbound_records(id),
+    staff_id INTEGER REFERENCES staff(id),
+    return_number TEXT NOT NULL,
+    reason TEXT NOT NULL,
+    notes TEXT,
+    return_date TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create indexes
+-- 1件の出庫記録に対する返品は1回のみ
+CREATE UNIQUE INDEX IF NOT EXISTS idx_return_records_outbound_record_id ON return_records(outbound_record_id);
+CREATE INDEX IF NOT EXISTS idx_return_records_product_id ON return_records(product_id);
+CREATE INDEX IF NOT EXISTS idx_return_records_return_number ON return_records(return_number);
+CREATE INDEX IF NOT EXISTS idx_outbound_records_outbound_number ON outbound_records(outbound_number);
@@ -0,0 +1,8 @@
+-- Add void columns to outbound records
+-- 取消された出庫伝票は監査のため行を残し、取消日時・担当者・理由を記録する
+ALTER TABLE outbound_records ADD COLUMN IF NOT EXISTS voided_at TIMESTAMP;
+ALTER TABLE outbound_records ADD COLUMN IF NOT EXISTS voided_by INTEGER REFERENCES staff(id);
+ALTER TABLE outbound_records ADD COLUMN IF NOT EXISTS void_reason TEXT;
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_outbound_records_voided_at ON outbound_records(voided_at);
@@ -0,0 +1,16 @@
+-- Create inbound voids table
+-- 取消した入庫伝票の内容（削除した products / pc_details / vest_details / inbound_records）を監査用に保存する
+CREATE TABLE IF NOT EXISTS inbound_voids (
+    id SERIAL PRIMARY KEY,
+    inbound_number TEXT NOT NULL,
+    category TEXT NOT NULL,
+    staff_id INTEGER REFERENCES staff(id),
+    reason TEXT NOT NULL,
+    product_count INTEGER NOT NULL,
+    snapshot JSONB NOT NULL,
+    voided_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_inbound_voids_inbound_number ON inbound_voids(inbound_number);
+CREATE INDEX IF NOT EXISTS idx_inbound_records_inbound_number ON inbound_records(inbound_number);
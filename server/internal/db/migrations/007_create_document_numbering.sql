@@ -0,0 +1,67 @@
+-- Create document number formats table
+-- category が NULL の行は全カテゴリ共通の書式、カテゴリ指定の行はそのカテゴリ専用の書式
+-- 書式で使用できる項目: {date} {yyyy} {mm} {dd} {category} {seq} {seq:桁数}
+CREATE TABLE IF NOT EXISTS document_number_formats (
+    id SERIAL PRIMARY KEY,
+    doc_type TEXT NOT NULL,
+    category TEXT,
+    format TEXT NOT NULL,
+    per_category BOOLEAN NOT NULL DEFAULT false,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_document_number_formats_doc_type_category
+    ON document_number_formats(doc_type, COALESCE(category, ''));
+
+-- Create document counters table
+-- scope はカテゴリ別採番の場合のカテゴリ（共通採番は空文字）、period は書式の日付部分ごとの採番単位
+CREATE TABLE IF NOT EXISTS document_counters (
+    doc_type TEXT NOT NULL,
+    scope TEXT NOT NULL DEFAULT '',
+    period TEXT NOT NULL DEFAULT '',
+    last_value INTEGER NOT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (doc_type, scope, period)
+);
+
+DROP TRIGGER IF EXISTS update_document_number_formats_updated_at ON document_number_formats;
+CREATE TRIGGER update_document_number_formats_updated_at
+    BEFORE UPDATE ON document_number_formats
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+DROP TRIGGER IF EXISTS update_document_counters_updated_at ON document_counters;
+CREATE TRIGGER update_document_counters_updated_at
+    BEFORE UPDATE ON document_counters
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+-- Insert default formats (従来の YYYYMMDD-NNNN 形式)
+INSERT INTO document_number_formats (doc_type, category, format, per_category) VALUES
+    ('inbound', NULL, '{date}-{seq:4}', false),
+    ('outbound', NULL, '{date}-{seq:4}', false),
+    ('return', NULL, '{date}-{seq:4}', false)
+ON CONFLICT DO NOTHING;
+
+-- 既存の伝票番号から採番を引き継ぐ
+INSERT INTO document_counters (doc_type, scope, period, last_value)
+SELECT 'inbound', '', SUBSTRING(inbound_number FROM 1 FOR 8), MAX(CAST(SUBSTRING(inbound_number FROM 10) AS INTEGER))
+FROM inbound_records
+WHERE inbound_number ~ '^[0-9]{8}-[0-9]+$'
+GROUP BY SUBSTRING(inbound_number FROM 1 FOR 8)
+ON CONFLICT DO NOTHING;
+
+INSERT INTO document_counters (doc_type, scope, period, last_value)
+SELECT 'outbound', '', SUBSTRING(outbound_number FROM 1 FOR 8), MAX(CAST(SUBSTRING(outbound_number FROM 10) AS INTEGER))
+FROM outbound_records
+WHERE outbound_number ~ '^[0-9]{8}-[0-9]+$'
+GROUP BY SUBSTRING(outbound_number FROM 1 FOR 8)
+ON CONFLICT DO NOTHING;
+
+INSERT INTO document_counters (doc_type, scope, period, last_value)
+SELECT 'return', '', SUBSTRING(return_number FROM 1 FOR 8), MAX(CAST(SUBSTRING(return_number FROM 10) AS INTEGER))
+FROM return_records
+WHERE return_number ~ '^[0-9]{8}-[0-9]+$'
+GROUP BY SUBSTRING(return_number FROM 1 FOR 8)
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,9 @@
+-- Create indexes for list pagination
+-- 在庫・入出庫履歴のキーセットページング（並び替え値 + ID）用
+CREATE INDEX IF NOT EXISTS idx_products_created_at_id ON products(created_at, id);
+CREATE INDEX IF NOT EXISTS idx_products_lot_number ON products(lot_number);
+CREATE INDEX IF NOT EXISTS idx_inbound_records_inbound_date_id ON inbound_records(inbound_date, id);
+CREATE INDEX IF NOT EXISTS idx_inbound_records_staff_id ON inbound_records(staff_id);
+CREATE INDEX IF NOT EXISTS idx_outbound_records_outbound_date_id ON outbound_records(outbound_date, id);
+CREATE INDEX IF NOT EXISTS idx_outbound_records_staff_id ON outbound_records(staff_id);
+CREATE INDEX IF NOT EXISTS idx_pc_details_model_number ON pc_details(model_number);
@@ -0,0 +1,136 @@
+-- Create customer and purchaser master tables
+-- 出庫記録の顧客・購入者は ID で参照し、customer_number などの文字列は出庫時点の内容として残す
+
+-- 表記ゆれの正規化（前後の空白除去・連続する空白の統一、空文字は NULL）
+CREATE OR REPLACE FUNCTION normalize_master_text(value TEXT)
+RETURNS TEXT AS $$
+    SELECT NULLIF(regexp_replace(btrim(value, E' \t\r\n　'), E'[\\s　]+', ' ', 'g'), '');
+$$ LANGUAGE SQL IMMUTABLE;
+
+CREATE TABLE IF NOT EXISTS customers (
+    id SERIAL PRIMARY KEY,
+    customer_number TEXT UNIQUE,
+    name TEXT NOT NULL,
+    postal_code TEXT,
+    address TEXT,
+    phone TEXT,
+    email TEXT,
+    contact_name TEXT,
+    notes TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS purchasers (
+    id SERIAL PRIMARY KEY,
+    purchaser_number TEXT UNIQUE,
+    name TEXT NOT NULL,
+    postal_code TEXT,
+    address TEXT,
+    phone TEXT,
+    email TEXT,
+    contact_name TEXT,
+    notes TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+DROP TRIGGER IF EXISTS update_customers_updated_at ON customers;
+CREATE TRIGGER update_customers_updated_at
+    BEFORE UPDATE ON customers
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+DROP TRIGGER IF EXISTS update_purchasers_updated_at ON purchasers;
+CREATE TRIGGER update_purchasers_updated_at
+    BEFORE UPDATE ON purchasers
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+ALTER TABLE outbound_records ADD COLUMN IF NOT EXISTS customer_id INTEGER REFERENCES customers(id);
+ALTER TABLE outbound_records ADD COLUMN IF NOT EXISTS purchaser_id INTEGER REFERENCES purchasers(id);
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_customers_name ON customers(LOWER(name));
+CREATE INDEX IF NOT EXISTS idx_purchasers_name ON purchasers(LOWER(name));
+CREATE INDEX IF NOT EXISTS idx_outbound_records_customer_id ON outbound_records(customer_id);
+CREATE INDEX IF NOT EXISTS idx_outbound_records_purchaser_id ON outbound_records(purchaser_id);
+
+-- 既存の出庫記録の顧客を名寄せしてマスタを作成
+-- 番号がある場合は番号ごとに最も多い名称を採用し、番号がない場合は名称（大文字小文字を区別しない）ごとに1件作成する
+INSERT INTO customers (customer_number, name)
+SELECT
+    normalize_master_text(customer_number),
+    COALESCE(
+        mode() WITHIN GROUP (ORDER BY normalize_master_text(customer_name)),
+        normalize_master_text(customer_number)
+    )
+FROM outbound_records
+WHERE normalize_master_text(customer_number) IS NOT NULL
+GROUP BY normalize_master_text(customer_number)
+ON CONFLICT (customer_number) DO NOTHING;
+
+INSERT INTO customers (name)
+SELECT MIN(normalize_master_text(o.customer_name))
+FROM outbound_records o
+WHERE normalize_master_text(o.customer_number) IS NULL
+AND normalize_master_text(o.customer_name) IS NOT NULL
+AND NOT EXISTS (
+    SELECT 1 FROM customers c
+    WHERE LOWER(c.name) = LOWER(normalize_master_text(o.customer_name))
+)
+GROUP BY LOWER(normalize_master_text(o.customer_name));
+
+UPDATE outbound_records o
+SET customer_id = c.id
+FROM customers c
+WHERE o.customer_id IS NULL
+AND normalize_master_text(o.customer_number) = c.customer_number;
+
+UPDATE outbound_records o
+SET customer_id = (
+    SELECT MIN(c.id) FROM customers c
+    WHERE LOWER(c.name) = LOWER(normalize_master_text(o.customer_name))
+)
+WHERE o.customer_id IS NULL
+AND normalize_master_text(o.customer_number) IS NULL
+AND normalize_master_text(o.customer_name) IS NOT NULL;
+
+-- 購入者も同様に名寄せ
+INSERT INTO purchasers (purchaser_number, name)
+SELECT
+    normalize_master_text(purchaser_number),
+    COALESCE(
+        mode() WITHIN GROUP (ORDER BY normalize_master_text(purchaser_name)),
+        normalize_master_text(purchaser_number)
+    )
+FROM outbound_records
+WHERE normalize_master_text(purchaser_number) IS NOT NULL
+GROUP BY normalize_master_text(purchaser_number)
+ON CONFLICT (purchaser_number) DO NOTHING;
+
+INSERT INTO purchasers (name)
+SELECT MIN(normalize_master_text(o.purchaser_name))
+FROM outbound_records o
+WHERE normalize_master_text(o.purchaser_number) IS NULL
+AND normalize_master_text(o.purchaser_name) IS NOT NULL
+AND NOT EXISTS (
+    SELECT 1 FROM purchasers p
+    WHERE LOWER(p.name) = LOWER(normalize_master_text(o.purchaser_name))
+)
+GROUP BY LOWER(normalize_master_text(o.purchaser_name));
+
+UPDATE outbound_records o
+SET purchaser_id = p.id
+FROM purchasers p
+WHERE o.purchaser_id IS NULL
+AND normalize_master_text(o.purchaser_number) = p.purchaser_number;
+
+UPDATE outbound_records o
+SET purchaser_id = (
+    SELECT MIN(p.id) FROM purchasers p
+    WHERE LOWER(p.name) = LOWER(normalize_master_text(o.purchaser_name))
+)
+WHERE o.purchaser_id IS NULL
+AND normalize_master_text(o.purchaser_number) IS NULL
+AND normalize_master_text(o.purchaser_name) IS NOT NULL;
@@ -0,0 +1,23 @@
+-- Add staff credentials
+-- パスワードはログインIDと組み合わせて、PINは共用タブレットで担当者を選択してログインする際に使用する
+ALTER TABLE staff ADD COLUMN IF NOT EXISTS login_id TEXT UNIQUE;
+ALTER TABLE staff ADD COLUMN IF NOT EXISTS password_hash TEXT;
+ALTER TABLE staff ADD COLUMN IF NOT EXISTS pin_hash TEXT;
+ALTER TABLE staff ADD COLUMN IF NOT EXISTS failed_login_count INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE staff ADD COLUMN IF NOT EXISTS locked_until TIMESTAMP;
+
+-- Create staff sessions table
+-- トークンそのものは保存せず SHA-256 ハッシュのみを保存する
+CREATE TABLE IF NOT EXISTS staff_sessions (
+    token_hash TEXT PRIMARY KEY,
+    staff_id INTEGER NOT NULL REFERENCES staff(id),
+    user_agent TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    last_seen_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP
+);
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_staff_sessions_staff_id ON staff_sessions(staff_id);
+CREATE INDEX IF NOT EXISTS idx_staff_sessions_expires_at ON staff_sessions(expires_at);
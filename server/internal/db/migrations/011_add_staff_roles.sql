@@ -0,0 +1,13 @@
+-- Add staff roles
+-- admin: すべての操作、operator: 入出庫・返品などの日常業務、viewer: 閲覧のみ
+ALTER TABLE staff ADD COLUMN IF NOT EXISTS role TEXT NOT NULL DEFAULT 'operator';
+ALTER TABLE staff DROP CONSTRAINT IF EXISTS staff_role_check;
+ALTER TABLE staff ADD CONSTRAINT staff_role_check CHECK (role IN ('admin', 'operator', 'viewer'));
+
+-- 担当できるカテゴリ（NULLの場合はすべてのカテゴリ）
+ALTER TABLE staff ADD COLUMN IF NOT EXISTS categories TEXT[];
+
+-- 管理者が1人もいない場合は最初の担当者を管理者にする
+UPDATE staff SET role = 'admin'
+WHERE id = (SELECT MIN(id) FROM staff)
+AND NOT EXISTS (SELECT 1 FROM staff WHERE role = 'admin');
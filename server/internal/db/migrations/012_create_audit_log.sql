@@ -0,0 +1,71 @@
+-- Create audit log
+-- 各記録は直前の記録のハッシュを含めてハッシュ化し、改ざん・削除を検出できるようにする
+CREATE TABLE IF NOT EXISTS audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    occurred_at TIMESTAMP NOT NULL,
+    actor_staff_id INTEGER,
+    actor_name TEXT,
+    action TEXT NOT NULL CHECK (action IN ('create', 'update', 'delete')),
+    entity_type TEXT NOT NULL,
+    entity_id TEXT NOT NULL,
+    before_data JSONB,
+    after_data JSONB,
+    request_id TEXT,
+    prev_hash TEXT,
+    hash TEXT NOT NULL UNIQUE
+);
+
+-- 記録のハッシュ（SHA-256）
+-- 記録時と検証時で同じ計算を行うため、SQL関数として定義する
+CREATE OR REPLACE FUNCTION audit_log_hash(
+    prev_hash TEXT,
+    occurred_at TIMESTAMP,
+    actor_staff_id INTEGER,
+    actor_name TEXT,
+    action TEXT,
+    entity_type TEXT,
+    entity_id TEXT,
+    before_data JSONB,
+    after_data JSONB,
+    request_id TEXT
+)
+RETURNS TEXT AS $$
+    SELECT encode(sha256(convert_to(concat_ws(E'\n',
+        COALESCE(prev_hash, ''),
+        to_char(occurred_at, 'YYYY-MM-DD"T"HH24:MI:SS.US'),
+        COALESCE(actor_staff_id::text, ''),
+        COALESCE(actor_name, ''),
+        action,
+        entity_type,
+        entity_id,
+        COALESCE(before_data::text, ''),
+        COALESCE(after_data::text, ''),
+        COALESCE(request_id, '')
+    ), 'UTF8')), 'hex');
+$$ LANGUAGE SQL STABLE;
+
+-- 監査ログの変更・削除を禁止
+CREATE OR REPLACE FUNCTION reject_audit_log_change()
+RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION '監査ログは変更・削除できません';
+END;
+$$ language 'plpgsql';
+
+DROP TRIGGER IF EXISTS reject_audit_log_change ON audit_log;
+CREATE TRIGGER reject_audit_log_change
+    BEFORE UPDATE OR DELETE ON audit_log
+    FOR EACH ROW
+    EXECUTE FUNCTION reject_audit_log_change();
+
+DROP TRIGGER IF EXISTS reject_audit_log_truncate ON audit_log;
+CREATE TRIGGER reject_audit_log_truncate
+    BEFORE TRUNCATE ON audit_log
+    FOR EACH STATEMENT
+    EXECUTE FUNCTION reject_audit_log_change();
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_audit_log_entity ON audit_log(entity_type, entity_id);
+CREATE INDEX IF NOT EXISTS idx_audit_log_occurred_at ON audit_log(occurred_at);
+CREATE INDEX IF NOT EXISTS idx_audit_log_actor_staff_id ON audit_log(actor_staff_id);
+CREATE INDEX IF NOT EXISTS idx_audit_log_request_id ON audit_log(request_id);
@@ -0,0 +1,6 @@
+-- Add staff active flag
+-- 退職・異動した担当者は削除せず無効化し、履歴には名前を残す
+ALTER TABLE staff ADD COLUMN IF NOT EXISTS active BOOLEAN NOT NULL DEFAULT TRUE;
+ALTER TABLE staff ADD COLUMN IF NOT EXISTS deactivated_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_staff_active ON staff(active);
@@ -0,0 +1,23 @@
+-- Add product type management columns
+-- 無効化した製品タイプは入庫の選択肢に表示しない（登録済みの製品の表示には使用する）
+ALTER TABLE product_types ADD COLUMN IF NOT EXISTS active BOOLEAN NOT NULL DEFAULT TRUE;
+ALTER TABLE product_types ADD COLUMN IF NOT EXISTS sort_order INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE product_types ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP;
+
+-- 既存の製品タイプの表示順は登録順
+UPDATE product_types pt SET sort_order = o.sort_order
+FROM (
+    SELECT id, ROW_NUMBER() OVER (PARTITION BY category ORDER BY id) AS sort_order
+    FROM product_types
+) o
+WHERE pt.id = o.id AND pt.sort_order = 0;
+
+-- 製品タイプ名はカテゴリごとに一意（前後の空白・大文字小文字は区別しない）
+CREATE UNIQUE INDEX IF NOT EXISTS idx_product_types_category_name
+    ON product_types(category, lower(btrim(name)));
+
+DROP TRIGGER IF EXISTS update_product_types_updated_at ON product_types;
+CREATE TRIGGER update_product_types_updated_at
+    BEFORE UPDATE ON product_types
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
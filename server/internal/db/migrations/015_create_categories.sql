@@ -0,0 +1,50 @@
+-- Create categories table
+-- カテゴリは product_types.category などの文字列でのみ存在していたため、コード・表示名・表示順を管理する
+CREATE TABLE IF NOT EXISTS categories (
+    code TEXT PRIMARY KEY CHECK (code ~ '^[a-z][a-z0-9_]*$'),
+    name TEXT NOT NULL,
+    name_en TEXT NOT NULL DEFAULT '',
+    sort_order INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- 表示名は一意（前後の空白・大文字小文字は区別しない）
+CREATE UNIQUE INDEX IF NOT EXISTS idx_categories_name ON categories(lower(btrim(name)));
+
+DROP TRIGGER IF EXISTS update_categories_updated_at ON categories;
+CREATE TRIGGER update_categories_updated_at
+    BEFORE UPDATE ON categories
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+-- Insert initial categories
+INSERT INTO categories (code, name, name_en, sort_order) VALUES
+    ('device', 'デバイス', 'Device', 1),
+    ('station', 'ステーション', 'Station', 2),
+    ('heart_rate', '心拍計', 'Heart rate monitor', 3),
+    ('vest', 'ベスト', 'Vest', 4),
+    ('pc', 'PC', 'PC', 5)
+ON CONFLICT DO NOTHING;
+
+-- 初期データ以外のカテゴリが登録されている場合はコードを表示名として引き継ぐ
+INSERT INTO categories (code, name, sort_order)
+SELECT c.code, c.code, (SELECT COALESCE(MAX(sort_order), 0) FROM categories) + ROW_NUMBER() OVER (ORDER BY c.code)
+FROM (
+    SELECT category AS code FROM product_types
+    UNION SELECT category FROM inbound_voids
+    UNION SELECT category FROM document_number_formats WHERE category IS NOT NULL
+) c
+WHERE NOT EXISTS (SELECT 1 FROM categories WHERE code = c.code)
+ON CONFLICT DO NOTHING;
+
+-- カテゴリを参照する列
+ALTER TABLE product_types DROP CONSTRAINT IF EXISTS product_types_category_fkey;
+ALTER TABLE product_types ADD CONSTRAINT product_types_category_fkey
+    FOREIGN KEY (category) REFERENCES categories(code);
+ALTER TABLE inbound_voids DROP CONSTRAINT IF EXISTS inbound_voids_category_fkey;
+ALTER TABLE inbound_voids ADD CONSTRAINT inbound_voids_category_fkey
+    FOREIGN KEY (category) REFERENCES categories(code);
+ALTER TABLE document_number_formats DROP CONSTRAINT IF EXISTS document_number_formats_category_fkey;
+ALTER TABLE document_number_formats ADD CONSTRAINT document_number_formats_category_fkey
+    FOREIGN KEY (category) REFERENCES categories(code);
@@ -0,0 +1,34 @@
+-- Create custom attribute definitions
+-- カテゴリまたは製品タイプごとの追加項目（心拍計のセンサーID、ステーションのファームウェアバージョンなど）
+-- product_type_id が NULL の項目はカテゴリ内のすべての製品タイプに適用する
+-- 製品タイプ用の項目がある製品タイプは削除できない（項目の削除を監査ログに記録するため、先に項目を削除する）
+CREATE TABLE IF NOT EXISTS attribute_definitions (
+    id SERIAL PRIMARY KEY,
+    category TEXT NOT NULL REFERENCES categories(code),
+    product_type_id INTEGER REFERENCES product_types(id) ON DELETE RESTRICT,
+    key TEXT NOT NULL CHECK (key ~ '^[a-zA-Z][a-zA-Z0-9_]*$'),
+    label TEXT NOT NULL,
+    data_type TEXT NOT NULL CHECK (data_type IN ('text', 'number', 'date', 'enum')),
+    options TEXT[],
+    required BOOLEAN NOT NULL DEFAULT FALSE,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    sort_order INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CHECK (data_type <> 'enum' OR cardinality(options) > 0)
+);
+
+-- 項目キーはカテゴリごとに一意（製品の attributes のキー・エクスポートの列に使用する）
+CREATE UNIQUE INDEX IF NOT EXISTS idx_attribute_definitions_category_key
+    ON attribute_definitions(category, lower(key));
+CREATE INDEX IF NOT EXISTS idx_attribute_definitions_product_type_id ON attribute_definitions(product_type_id);
+
+DROP TRIGGER IF EXISTS update_attribute_definitions_updated_at ON attribute_definitions;
+CREATE TRIGGER update_attribute_definitions_updated_at
+    BEFORE UPDATE ON attribute_definitions
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+-- 製品ごとの追加項目の値（キーは attribute_definitions.key、日付は YYYY-MM-DD の文字列）
+ALTER TABLE products ADD COLUMN IF NOT EXISTS attributes JSONB NOT NULL DEFAULT '{}'::jsonb;
+CREATE INDEX IF NOT EXISTS idx_products_attributes ON products USING GIN (attributes);
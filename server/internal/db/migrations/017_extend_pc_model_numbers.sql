@@ -0,0 +1,24 @@
+-- Extend PC model catalog
+-- 型番ごとのメーカー・表示名・既定の保証期間（月）・仕様を管理する
+-- 無効化した型番は入庫の選択肢に表示しない（登録済みのPCの表示には使用する）
+ALTER TABLE pc_model_numbers ADD COLUMN IF NOT EXISTS manufacturer TEXT NOT NULL DEFAULT '';
+ALTER TABLE pc_model_numbers ADD COLUMN IF NOT EXISTS display_name TEXT NOT NULL DEFAULT '';
+ALTER TABLE pc_model_numbers ADD COLUMN IF NOT EXISTS default_warranty_period INTEGER
+    CHECK (default_warranty_period >= 0);
+ALTER TABLE pc_model_numbers ADD COLUMN IF NOT EXISTS cpu TEXT NOT NULL DEFAULT '';
+ALTER TABLE pc_model_numbers ADD COLUMN IF NOT EXISTS memory TEXT NOT NULL DEFAULT '';
+ALTER TABLE pc_model_numbers ADD COLUMN IF NOT EXISTS storage TEXT NOT NULL DEFAULT '';
+ALTER TABLE pc_model_numbers ADD COLUMN IF NOT EXISTS os TEXT NOT NULL DEFAULT '';
+ALTER TABLE pc_model_numbers ADD COLUMN IF NOT EXISTS active BOOLEAN NOT NULL DEFAULT TRUE;
+ALTER TABLE pc_model_numbers ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP;
+
+DROP TRIGGER IF EXISTS update_pc_model_numbers_updated_at ON pc_model_numbers;
+CREATE TRIGGER update_pc_model_numbers_updated_at
+    BEFORE UPDATE ON pc_model_numbers
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+-- 型番の変更を登録済みのPC詳細情報に反映する
+ALTER TABLE pc_details DROP CONSTRAINT IF EXISTS pc_details_model_number_fkey;
+ALTER TABLE pc_details ADD CONSTRAINT pc_details_model_number_fkey
+    FOREIGN KEY (model_number) REFERENCES pc_model_numbers(model_number) ON UPDATE CASCADE;
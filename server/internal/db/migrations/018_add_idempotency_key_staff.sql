@@ -0,0 +1,6 @@
+-- Add staff to idempotency keys
+-- 同じキーを別の担当者が送信した場合に他の担当者のレスポンスを返さないよう、キーは担当者ごとに管理する
+-- （ログイン導入前に保存されたキーの担当者は 0）
+ALTER TABLE idempotency_keys ADD COLUMN IF NOT EXISTS staff_id INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE idempotency_keys DROP CONSTRAINT IF EXISTS idempotency_keys_pkey;
+ALTER TABLE idempotency_keys ADD CONSTRAINT idempotency_keys_pkey PRIMARY KEY (staff_id, idempotency_key, endpoint);
@@ -0,0 +1,7 @@
+-- Add created_at to inbound and outbound records
+-- 入出庫日は日付のみのため、製品履歴で同日の入庫・出庫・取消・返品を登録順に並べるのに使用する
+-- 追加前の記録は NULL（入出庫日で並べる）
+ALTER TABLE inbound_records ADD COLUMN IF NOT EXISTS created_at TIMESTAMP;
+ALTER TABLE inbound_records ALTER COLUMN created_at SET DEFAULT CURRENT_TIMESTAMP;
+ALTER TABLE outbound_records ADD COLUMN IF NOT EXISTS created_at TIMESTAMP;
+ALTER TABLE outbound_records ALTER COLUMN created_at SET DEFAULT CURRENT_TIMESTAMP;
@@ -0,0 +1,526 @@
+package handler
+
+import (
+    "database/sql"
+    "encoding/json"
+    "fmt"
+    "log"
+    "net/http"
+    "regexp"
+    "strconv"
+    "strings"
+    "time"
+    "github.com/gin-gonic/gin"
+    "github.com/lib/pq"
+    "inventory-tracker/server/internal/audit"
+    "inventory-tracker/server/internal/db"
+    "inventory-tracker/server/internal/model"
+)
+
+// 追加項目のデータ型（attribute_definitions.data_type の CHECK 制約と同じ値）
+const (
+    attributeTypeText   = "text"
+    attributeTypeNumber = "number"
+    attributeTypeDate   = "date"
+    attributeTypeEnum   = "enum"
+)
+
+var attributeTypes = []string{attributeTypeText, attributeTypeNumber, attributeTypeDate, attributeTypeEnum}
+
+// 追加項目のキー（製品の attributes のキーに使用するため英字で始まる英数字・アンダースコアのみ）
+var attributeKeyPattern = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_]*$`)
+
+const attributeDefinitionColumns = `id, category, product_type_id, key, label, data_type, options,
+    required, active, sort_order, created_at, updated_at`
+
+func scanAttributeDefinition(row interface{ Scan(...interface{}) error }) (model.AttributeDefinition, error) {
+    var d model.AttributeDefinition
+    var productTypeID sql.NullInt64
+    err := row.Scan(
+        &d.ID, &d.Category, &productTypeID, &d.Key, &d.Label, &d.DataType, pq.Array(&d.Options),
+        &d.Required, &d.Active, &d.SortOrder, &d.CreatedAt, &d.UpdatedAt,
+    )
+    if productTypeID.Valid {
+        id := int(productTypeID.Int64)
+        d.ProductTypeID = &id
+    }
+    return d, err
+}
+
+// 追加項目の定義の取得（表示順）
+// db.DB・トランザクションのどちらからでも取得できるよう Query を持つ値を受け取る
+func loadAttributeDefinitions(
+    q interface {
+        Query(query string, args ...interface{}) (*sql.Rows, error)
+    },
+    category string, includeInactive bool,
+) ([]model.AttributeDefinition, error) {
+    rows, err := q.Query(`
+        SELECT `+attributeDefinitionColumns+`
+        FROM attribute_definitions
+        WHERE category = $1 AND (active OR $2)
+        ORDER BY sort_order, id
+    `, category, includeInactive)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    defs := []model.AttributeDefinition{}
+    for rows.Next() {
+        d, err := scanAttributeDefinition(rows)
+        if err != nil {
+            return nil, err
+        }
+        defs = append(defs, d)
+    }
+    return defs, rows.Err()
+}
+
+// 製品タイプに適用される追加項目か
+func attributeApplies(d model.AttributeDefinition, typeID int) bool {
+    return d.ProductTypeID == nil || *d.ProductTypeID == typeID
+}
+
+// 追加項目の値の正規化
+// 文字列で指定された数値・日付（CSV取り込みなど）も変換し、空の値は nil を返す
+func normalizeAttributeValue(d model.AttributeDefinition, v interface{}) (interface{}, error) {
+    if v == nil {
+        return nil, nil
+    }
+
+    if d.DataType == attributeTypeNumber {
+        switch n := v.(type) {
+        case float64:
+            return n, nil
+        case string:
+            s := strings.ReplaceAll(strings.TrimSpace(n), ",", "")
+            if s == "" {
+                return nil, nil
+            }
+            if f, err := strconv.ParseFloat(s, 64); err == nil {
+                return f, nil
+            }
+        }
+        return nil, fmt.Errorf("%sは数値で入力してください", d.Label)
+    }
+
+    s, ok := v.(string)
+    if !ok {
+        return nil, fmt.Errorf("%sは文字列で入力してください", d.Label)
+    }
+    s = strings.TrimSpace(s)
+    if s == "" {
+        return nil, nil
+    }
+
+    switch d.DataType {
+    case attributeTypeDate:
+        date, err := time.Parse("2006-01-02", s)
+        if err != nil {
+            return nil, fmt.Errorf("%sは日付（YYYY-MM-DD）で入力してください", d.Label)
+        }
+        return date.Format("2006-01-02"), nil
+    case attributeTypeEnum:
+        if !containsString(d.Options, s) {
+            return nil, fmt.Errorf("%sの値「%s」は選択肢にありません", d.Label, s)
+        }
+    }
+    return s, nil
+}
+
+// 追加項目の列の見出し
+func attributeExportHeaders(defs []model.AttributeDefinition) []string {
+    headers := make([]string, 0, len(defs))
+    for _, d := range defs {
+        headers = append(headers, d.Label)
+    }
+    return headers
+}
+
+// 追加項目の列の値（日付は日付のセルとして出力）
+func attributeExportValues(defs []model.AttributeDefinition, attributes []byte) []interface{} {
+    var data map[string]interface{}
+    if len(attributes) > 0 {
+        if err := json.Unmarshal(attributes, &data); err != nil {
+            log.Printf("追加項目の読み取りエラー: %v", err)
+        }
+    }
+
+    values := make([]interface{}, 0, len(defs))
+    for _, d := range defs {
+        v := data[d.Key]
+        if s, ok := v.(string); ok && d.DataType == attributeTypeDate {
+            if date, err := time.Parse("2006-01-02", s); err == nil {
+                v = date
+            }
+        }
+        values = append(values, v)
+    }
+    return values
+}
+
+// 追加項目の JSON（未設定の場合は空のオブジェクト）
+func attributesJSON(attributes []byte) json.RawMessage {
+    if len(attributes) == 0 {
+        return json.RawMessage("{}")
+    }
+    return json.RawMessage(attributes)
+}
+
+// 追加項目定義一覧取得ハンドラー
+// 既定では有効な項目のみ（入庫の入力項目）を返し、includeInactive=true の場合は無効化した項目も含める
+func GetAttributeDefinitions(c *gin.Context) {
+    category := c.Param("category")
+    defs, err := loadAttributeDefinitions(db.DB, category, c.Query("includeInactive") == "true")
+    if err != nil {
+        log.Printf("追加項目定義取得エラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "追加項目の取得に失敗しました"})
+        return
+    }
+
+    c.JSON(http.StatusOK, defs)
+}
+
+// 追加項目定義の登録・更新リクエスト（key と dataType は登録時のみ使用）
+type attributeDefinitionRequest struct {
+    Key           string   `json:"key"`
+    Label         string   `json:"label" binding:"required"`
+    DataType      string   `json:"dataType"`
+    Options       []string `json:"options"`
+    Required      bool     `json:"required"`
+    ProductTypeID *int     `json:"productTypeId"`
+}
+
+// リクエストの読み取り（前後の空白・空の選択肢は除去）
+func bindAttributeDefinitionRequest(c *gin.Context) (*attributeDefinitionRequest, bool) {
+    var req attributeDefinitionRequest
+    if err := c.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.Label) == "" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "項目名を入力してください"})
+        return nil, false
+    }
+    req.Key = strings.TrimSpace(req.Key)
+    req.Label = strings.TrimSpace(req.Label)
+    req.DataType = strings.TrimSpace(req.DataType)
+
+    options := []string{}
+    for _, o := range req.Options {
+        if o = strings.TrimSpace(o); o != "" && !containsString(options, o) {
+            options = append(options, o)
+        }
+    }
+    req.Options = options
+    return &req, true
+}
+
+// 選択肢と製品タイプの確認
+func validateAttributeDefinition(c *gin.Context, tx *sql.Tx, category string, dataType string, req *attributeDefinitionRequest) bool {
+    if dataType == attributeTypeEnum && len(req.Options) == 0 {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "選択肢を1つ以上入力してください"})
+        return false
+    }
+    if dataType != attributeTypeEnum {
+        req.Options = nil
+    }
+
+    if req.ProductTypeID != nil {
+        var exists bool
+        err := tx.QueryRow(
+            "SELECT EXISTS(SELECT 1 FROM product_types WHERE id = $1 AND category = $2)",
+            *req.ProductTypeID, category,
+        ).Scan(&exists)
+        if err != nil {
+            log.Printf("製品タイプ確認エラー: %v", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "製品タイプの確認に失敗しました"})
+            return false
+        }
+        if !exists {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "指定された製品タイプが見つかりません"})
+            return false
+        }
+    }
+    return true
+}
+
+// 追加項目定義追加ハンドラー
+// 追加した項目はカテゴリ内の最後に表示する
+func CreateAttributeDefinition(c *gin.Context) {
+    category := c.Param("category")
+    req, ok := bindAttributeDefinitionRequest(c)
+    if !ok {
+        return
+    }
+    if !attributeKeyPattern.MatchString(req.Key) {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "項目キーは英字で始まる英数字・アンダースコアで入力してください"})
+        return
+    }
+    if !containsString(attributeTypes, req.DataType) {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "データ型には text / number / date / enum を指定してください"})
+        return
+    }
+
+    tx, err := db.BeginTx()
+    if err != nil {
+        log.Printf("トランザクション開始エラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "トランザクションの開始に失敗しました"})
+        return
+    }
+    defer tx.Rollback()
+
+    if !validateAttributeDefinition(c, tx, category, req.DataType, req) {
+        return
+    }
+
+    d, err := scanAttributeDefinition(tx.QueryRow(`
+        INSERT INTO attribute_definitions (category, product_type_id, key, label, data_type, options, required, sort_order)
+        SELECT $1, $2, $3, $4, $5, $6, $7, COALESCE(MAX(sort_order), 0) + 1
+        FROM attribute_definitions
+        WHERE category = $1
+        RETURNING `+attributeDefinitionColumns,
+        category, req.ProductTypeID, req.Key, req.Label, req.DataType, pq.Array(req.Options), req.Required,
+    ))
+    if isUniqueViolation(err) {
+        c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("項目キー %s は既に登録されています", req.Key)})
+        return
+    }
+    if err != nil {
+        log.Printf("追加項目定義登録エラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "追加項目の登録に失敗しました"})
+        return
+    }
+
+    if !recordAudit(c, tx, audit.Entry{
+        Action:     audit.ActionCreate,
+        EntityType: "attribute_definition",
+        EntityID:   strconv.Itoa(d.ID),
+        After:      d,
+    }) {
+        return
+    }
+    if err := tx.Commit(); err != nil {
+        log.Printf("トランザクションコミットエラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "トランザクションのコミットに失敗しました"})
+        return
+    }
+
+    log.Printf("追加項目を登録しました: ID=%d, カテゴリ=%s, キー=%s", d.ID, category, d.Key)
+    c.JSON(http.StatusOK, d)
+}
+
+// 追加項目定義更新ハンドラー
+// 登録済みの値の形式が変わらないよう、キーとデータ型は変更できない
+// 適用する製品タイプは、対象外となる製品に値が登録されていない場合のみ変更できる
+func UpdateAttributeDefinition(c *gin.Context) {
+    req, ok := bindAttributeDefinitionRequest(c)
+    if !ok {
+        return
+    }
+
+    updateAttributeDefinition(c, func(tx *sql.Tx, before model.AttributeDefinition) bool {
+        if !validateAttributeDefinition(c, tx, before.Category, before.DataType, req) {
+            return false
+        }
+
+        // 適用範囲を製品タイプに狭める場合、他の製品タイプの製品に登録済みの値があれば変更できない
+        narrowed := req.ProductTypeID != nil &&
+            (before.ProductTypeID == nil || *before.ProductTypeID != *req.ProductTypeID)
+        if narrowed {
+            var inUse bool
+            err := tx.QueryRow(`
+                SELECT EXISTS(
+                    SELECT 1 FROM products p
+                    INNER JOIN product_types pt ON p.type_id = pt.id
+                    WHERE pt.category = $1 AND p.type_id <> $2 AND p.attributes ? $3
+                )
+            `, before.Category, *req.ProductTypeID, before.Key).Scan(&inUse)
+            if err != nil {
+                log.Printf("追加項目使用状況確認エラー: %v", err)
+                c.JSON(http.StatusInternalServerError, gin.H{"error": "追加項目の使用状況の確認に失敗しました"})
+                return false
+            }
+            if inUse {
+                c.JSON(http.StatusConflict, gin.H{"error": "他の製品タイプの製品に値が登録されているため、適用する製品タイプを変更できません"})
+                return false
+            }
+        }
+
+        _, err := tx.Exec(`
+            UPDATE attribute_definitions
+            SET label = $2, options = $3, required = $4, product_type_id = $5
+            WHERE id = $1
+        `, before.ID, req.Label, pq.Array(req.Options), req.Required, req.ProductTypeID)
+        if err != nil {
+            log.Printf("追加項目定義更新エラー: %v", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "追加項目の更新に失敗しました"})
+            return false
+        }
+        return true
+    })
+}
+
+// 追加項目定義無効化ハンドラー
+// 無効化した項目は入庫で入力できなくなる（登録済みの値はそのまま返す）
+func DeactivateAttributeDefinition(c *gin.Context) {
+    updateAttributeDefinition(c, func(tx *sql.Tx, before model.AttributeDefinition) bool {
+        if _, err := tx.Exec("UPDATE attribute_definitions SET active = FALSE WHERE id = $1 AND active", before.ID); err != nil {
+            log.Printf("追加項目定義無効化エラー: %v", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "追加項目の無効化に失敗しました"})
+            return false
+        }
+        return true
+    })
+}
+
+// 追加項目定義再有効化ハンドラー
+func ReactivateAttributeDefinition(c *gin.Context) {
+    updateAttributeDefinition(c, func(tx *sql.Tx, before model.AttributeDefinition) bool {
+        if _, err := tx.Exec("UPDATE attribute_definitions SET active = TRUE WHERE id = $1 AND NOT active", before.ID); err != nil {
+            log.Printf("追加項目定義再有効化エラー: %v", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "追加項目の再有効化に失敗しました"})
+            return false
+        }
+        return true
+    })
+}
+
+// 追加項目定義の更新処理（対象の行をロックして update を実行し、変更前後を監査ログに記録する）
+// update はエラー時にレスポンスを返して false を返す
+func updateAttributeDefinition(c *gin.Context, update func(tx *sql.Tx, before model.AttributeDefinition) bool) {
+    category := c.Param("category")
+    id, err := strconv.Atoi(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "無効な追加項目IDです"})
+        return
+    }
+
+    tx, err := db.BeginTx()
+    if err != nil {
+        log.Printf("トランザクション開始エラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "トランザクションの開始に失敗しました"})
+        return
+    }
+    defer tx.Rollback()
+
+    before, err := scanAttributeDefinition(tx.QueryRow(`
+        SELECT `+attributeDefinitionColumns+`
+        FROM attribute_definitions
+        WHERE id = $1 AND category = $2
+        FOR UPDATE
+    `, id, category))
+    if err == sql.ErrNoRows {
+        c.JSON(http.StatusNotFound, gin.H{"error": "追加項目が見つかりません"})
+        return
+    }
+    if err != nil {
+        log.Printf("追加項目定義取得エラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "追加項目の更新に失敗しました"})
+        return
+    }
+
+    if !update(tx, before) {
+        return
+    }
+
+    after, err := scanAttributeDefinition(tx.QueryRow(
+        "SELECT "+attributeDefinitionColumns+" FROM attribute_definitions WHERE id = $1", id,
+    ))
+    if err != nil {
+        log.Printf("追加項目定義取得エラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "追加項目の更新に失敗しました"})
+        return
+    }
+
+    if !recordAudit(c, tx, audit.Entry{
+        Action:     audit.ActionUpdate,
+        EntityType: "attribute_definition",
+        EntityID:   strconv.Itoa(id),
+        Before:     before,
+        After:      after,
+    }) {
+        return
+    }
+    if err := tx.Commit(); err != nil {
+        log.Printf("トランザクションコミットエラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "トランザクションのコミットに失敗しました"})
+        return
+    }
+
+    log.Printf("追加項目を更新しました: ID=%d", id)
+    c.JSON(http.StatusOK, after)
+}
+
+// 追加項目定義削除ハンドラー
+// 値が登録されている項目は削除できない（無効化を使用する）
+func DeleteAttributeDefinition(c *gin.Context) {
+    category := c.Param("category")
+    id, err := strconv.Atoi(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "無効な追加項目IDです"})
+        return
+    }
+
+    tx, err := db.BeginTx()
+    if err != nil {
+        log.Printf("トランザクション開始エラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "トランザクションの開始に失敗しました"})
+        return
+    }
+    defer tx.Rollback()
+
+    before, err := scanAttributeDefinition(tx.QueryRow(`
+        SELECT `+attributeDefinitionColumns+`
+        FROM attribute_definitions
+        WHERE id = $1 AND category = $2
+        FOR UPDATE
+    `, id, category))
+    if err == sql.ErrNoRows {
+        c.JSON(http.StatusNotFound, gin.H{"error": "追加項目が見つかりません"})
+        return
+    }
+    if err != nil {
+        log.Printf("追加項目定義取得エラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "追加項目の削除に失敗しました"})
+        return
+    }
+
+    var inUse bool
+    err = tx.QueryRow(`
+        SELECT EXISTS(
+            SELECT 1 FROM products p
+            INNER JOIN product_types pt ON p.type_id = pt.id
+            WHERE pt.category = $1 AND p.attributes ? $2
+        )
+    `, category, before.Key).Scan(&inUse)
+    if err != nil {
+        log.Printf("追加項目使用状況確認エラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "追加項目の使用状況の確認に失敗しました"})
+        return
+    }
+    if inUse {
+        c.JSON(http.StatusConflict, gin.H{"error": "値が登録されている追加項目は削除できません。無効化してください"})
+        return
+    }
+
+    if _, err := tx.Exec("DELETE FROM attribute_definitions WHERE id = $1", id); err != nil {
+        log.Printf("追加項目定義削除エラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "追加項目の削除に失敗しました"})
+        return
+    }
+
+    if !recordAudit(c, tx, audit.Entry{
+        Action:     audit.ActionDelete,
+        EntityType: "attribute_definition",
+        EntityID:   strconv.Itoa(id),
+        Before:     before,
+    }) {
+        return
+    }
+    if err := tx.Commit(); err != nil {
+        log.Printf("トランザクションコミットエラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "トランザクションのコミットに失敗しました"})
+        return
+    }
+
+    log.Printf("追加項目を削除しました: ID=%d", id)
+    c.JSON(http.StatusOK, gin.H{"message": "追加項目を削除しました"})
+}
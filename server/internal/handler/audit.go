@@ -0,0 +1,189 @@
+package handler
+
+import (
+    "database/sql"
+    "encoding/json"
+    "fmt"
+    "log"
+    "net/http"
+    "strings"
+    "time"
+    "github.com/gin-gonic/gin"
+    "inventory-tracker/server/internal/audit"
+    "inventory-tracker/server/internal/db"
+)
+
+var auditLogListSpec = listQuerySpec{
+    selectColumns: `
+        a.id, a.occurred_at, a.actor_staff_id, a.actor_name, a.action, a.entity_type, a.entity_id,
+        a.before_data, a.after_data, a.request_id, a.prev_hash, a.hash
+    `,
+    from:     "audit_log a",
+    idColumn: "a.id",
+    sorts: map[string]sortColumn{
+        "id":         {expr: "a.id", cast: "bigint"},
+        "occurredAt": {expr: "a.occurred_at", cast: "timestamp"},
+    },
+    defaultSort: "-id",
+    filters: listFilterColumns{
+        StaffID: "a.actor_staff_id",
+        Date:    "a.occurred_at",
+    },
+}
+
+// 監査ログ取得ハンドラー
+// listQuery の条件に加えて entityType, entityId, action, requestId で絞り込める
+func GetAuditLog(c *gin.Context) {
+    q, err := newListQuery(c, auditLogListSpec)
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    for param, column := range map[string]string{
+        "entityType": "a.entity_type",
+        "entityId":   "a.entity_id",
+        "action":     "a.action",
+        "requestId":  "a.request_id",
+    } {
+        if v := strings.TrimSpace(c.Query(param)); v != "" {
+            q.where(fmt.Sprintf("%s = %s", column, q.arg(v)))
+        }
+    }
+
+    var totalCount int
+    query, args := q.countSQL()
+    if err := db.DB.QueryRow(query, args...).Scan(&totalCount); err != nil {
+        log.Printf("監査ログ件数取得エラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "監査ログの取得に失敗しました"})
+        return
+    }
+
+    query, args = q.selectSQL(true)
+    rows, err := db.DB.Query(query, args...)
+    if err != nil {
+        log.Printf("監査ログ取得エラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "監査ログの取得に失敗しました"})
+        return
+    }
+    defer rows.Close()
+
+    var entries []gin.H
+    var cursors []listCursor
+    for rows.Next() {
+        var e struct {
+            ID           int
+            OccurredAt   time.Time
+            ActorStaffID sql.NullInt32
+            ActorName    sql.NullString
+            Action       string
+            EntityType   string
+            EntityID     string
+            Before       []byte
+            After        []byte
+            RequestID    sql.NullString
+            PrevHash     sql.NullString
+            Hash         string
+            SortValue    string
+        }
+        if err := rows.Scan(
+            &e.ID, &e.OccurredAt, &e.ActorStaffID, &e.ActorName, &e.Action, &e.EntityType, &e.EntityID,
+            &e.Before, &e.After, &e.RequestID, &e.PrevHash, &e.Hash, &e.SortValue,
+        ); err != nil {
+            log.Printf("監査ログ読み取りエラー: %v", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "監査ログの読み取りに失敗しました"})
+            return
+        }
+
+        entries = append(entries, gin.H{
+            "id":         e.ID,
+            "occurredAt": e.OccurredAt,
+            "actor":      staffJSON(e.ActorStaffID, e.ActorName),
+            "action":     e.Action,
+            "entityType": e.EntityType,
+            "entityId":   e.EntityID,
+            "before":     rawJSON(e.Before),
+            "after":      rawJSON(e.After),
+            "requestId":  nullableString(e.RequestID),
+            "prevHash":   nullableString(e.PrevHash),
+            "hash":       e.Hash,
+        })
+        cursors = append(cursors, listCursor{Value: e.SortValue, ID: e.ID})
+    }
+
+    c.JSON(http.StatusOK, q.response(entries, cursors, totalCount))
+}
+
+// 監査ログ検証ハンドラー
+// 前回の検証結果の lastId / lastHash を指定した場合は、その記録が残っていることも確認する
+func VerifyAuditLog(c *gin.Context) {
+    tx, err := db.BeginReadOnlyTx()
+    if err != nil {
+        log.Printf("トランザクション開始エラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "トランザクションの開始に失敗しました"})
+        return
+    }
+    defer tx.Rollback()
+
+    result, err := audit.Verify(tx)
+    if err != nil {
+        log.Printf("監査ログ検証エラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "監査ログの検証に失敗しました"})
+        return
+    }
+
+    if anchorID, anchorHash := c.Query("lastId"), c.Query("lastHash"); result.Valid && anchorID != "" && anchorHash != "" {
+        var exists bool
+        if err := tx.QueryRow(
+            "SELECT EXISTS(SELECT 1 FROM audit_log WHERE id::text = $1 AND hash = $2)", anchorID, anchorHash,
+        ).Scan(&exists); err != nil {
+            log.Printf("監査ログ検証エラー: %v", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "監査ログの検証に失敗しました"})
+            return
+        }
+        if !exists {
+            result.Valid = false
+            result.Reason = "前回確認した記録が見つかりません（記録が削除されています）"
+        }
+    }
+
+    if !result.Valid {
+        log.Printf("監査ログの改ざんを検出しました: %s", result.Reason)
+    }
+    c.JSON(http.StatusOK, result)
+}
+
+// 監査ログの記録（失敗した場合はエラーを返して false）
+func recordAudit(c *gin.Context, tx *sql.Tx, e audit.Entry) bool {
+    if err := audit.Record(tx, c, e); err != nil {
+        log.Printf("監査ログ記録エラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "監査ログの記録に失敗しました"})
+        return false
+    }
+    return true
+}
+
+// 監査ログ用の行データの取得（失敗した場合はエラーを返して false）
+func auditSnapshot(c *gin.Context, tx *sql.Tx, query string, args ...interface{}) (json.RawMessage, bool) {
+    data, err := audit.Snapshot(tx, query, args...)
+    if err != nil {
+        log.Printf("%v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "監査ログの記録に失敗しました"})
+        return nil, false
+    }
+    return data, true
+}
+
+// JSON 列の値（NULL の場合は null）
+func rawJSON(data []byte) interface{} {
+    if data == nil {
+        return nil
+    }
+    return json.RawMessage(data)
+}
+
+func nullableString(s sql.NullString) interface{} {
+    if !s.Valid {
+        return nil
+    }
+    return s.String
+}
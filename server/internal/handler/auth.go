@@ -0,0 +1,360 @@
+package handler
+
+import (
+    "database/sql"
+    "log"
+    "net/http"
+    "os"
+    "strconv"
+    "strings"
+    "github.com/gin-gonic/gin"
+    "github.com/lib/pq"
+    "inventory-tracker/server/internal/audit"
+    "inventory-tracker/server/internal/auth"
+    "inventory-tracker/server/internal/db"
+    "inventory-tracker/server/internal/model"
+)
+
+// ログインハンドラー
+// loginId + password、または共用タブレット向けに staffId + pin でログインする
+// セッションは HttpOnly Cookie で返し、Cookie を使えないクライアント向けに token も返す
+func Login(c *gin.Context) {
+    var req struct {
+        LoginID  string `json:"loginId"`
+        Password string `json:"password"`
+        StaffID  int    `json:"staffId"`
+        PIN      string `json:"pin"`
+    }
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "無効なリクエストデータです"})
+        return
+    }
+
+    var staff *model.Staff
+    var err error
+    switch {
+    case strings.TrimSpace(req.LoginID) != "" && req.Password != "":
+        staff, err = auth.AuthenticatePassword(strings.TrimSpace(req.LoginID), req.Password)
+    case req.StaffID != 0 && req.PIN != "":
+        staff, err = auth.AuthenticatePIN(req.StaffID, req.PIN)
+    default:
+        c.JSON(http.StatusBadRequest, gin.H{"error": "ログインIDとパスワード、または担当者とPINを入力してください"})
+        return
+    }
+    if err == auth.ErrInvalidCredentials || err == auth.ErrLocked {
+        log.Printf("ログイン失敗: loginId = %s, staffId = %d, %v", req.LoginID, req.StaffID, err)
+        status := http.StatusUnauthorized
+        if err == auth.ErrLocked {
+            status = http.StatusTooManyRequests
+        }
+        c.JSON(status, gin.H{"error": err.Error()})
+        return
+    }
+    if err != nil {
+        log.Printf("ログインエラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "ログインに失敗しました"})
+        return
+    }
+
+    token, expiresAt, err := auth.NewSession(staff.ID, c.Request.UserAgent())
+    if err != nil {
+        log.Printf("セッション作成エラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "ログインに失敗しました"})
+        return
+    }
+    setSessionCookie(c, token, int(auth.SessionTTL.Seconds()))
+
+    log.Printf("ログインしました: 担当者ID = %d", staff.ID)
+    c.JSON(http.StatusOK, gin.H{
+        "staff":     staff,
+        "token":     token,
+        "expiresAt": expiresAt,
+    })
+}
+
+// ログアウトハンドラー
+func Logout(c *gin.Context) {
+    if err := auth.RevokeSession(auth.CurrentToken(c)); err != nil {
+        log.Printf("ログアウトエラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "ログアウトに失敗しました"})
+        return
+    }
+    setSessionCookie(c, "", -1)
+
+    log.Printf("ログアウトしました: 担当者ID = %d", auth.CurrentStaffID(c))
+    c.JSON(http.StatusOK, gin.H{"message": "ログアウトしました"})
+}
+
+// ログイン中の担当者取得ハンドラー
+func GetCurrentStaff(c *gin.Context) {
+    staff, _ := auth.CurrentStaff(c)
+    c.JSON(http.StatusOK, staff)
+}
+
+// PINでログインできる担当者の一覧（共用タブレットのログイン画面用）
+func GetPINLoginStaff(c *gin.Context) {
+    rows, err := db.DB.Query("SELECT id, name FROM staff WHERE active AND pin_hash IS NOT NULL ORDER BY id")
+    if err != nil {
+        log.Printf("担当者一覧取得エラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "担当者一覧の取得に失敗しました"})
+        return
+    }
+    defer rows.Close()
+
+    staffList := []gin.H{}
+    for rows.Next() {
+        var id int
+        var name string
+        if err := rows.Scan(&id, &name); err != nil {
+            log.Printf("担当者読み取りエラー: %v", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "担当者の読み取りに失敗しました"})
+            return
+        }
+        staffList = append(staffList, gin.H{"id": id, "name": name})
+    }
+
+    c.JSON(http.StatusOK, staffList)
+}
+
+// 担当者の認証情報更新ハンドラー
+// 指定した項目（loginId / password / pin）のみ更新し、空文字の pin は PIN ログインの無効化とする
+// 更新後は対象の担当者の既存セッションをすべて無効化する
+func UpdateStaffCredentials(c *gin.Context) {
+    staffID, err := strconv.Atoi(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "無効なスタッフIDです"})
+        return
+    }
+
+    var req struct {
+        LoginID  *string `json:"loginId"`
+        Password *string `json:"password"`
+        PIN      *string `json:"pin"`
+    }
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "無効なリクエストデータです"})
+        return
+    }
+
+    var loginID, passwordHash, pinHash sql.NullString
+    clearPIN := false
+    if req.LoginID != nil {
+        if v := strings.TrimSpace(*req.LoginID); v != "" {
+            loginID = sql.NullString{String: v, Valid: true}
+        }
+    }
+    if req.Password != nil {
+        if err := auth.ValidatePassword(*req.Password); err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+            return
+        }
+        hash, err := auth.HashSecret(*req.Password)
+        if err != nil {
+            log.Printf("パスワードハッシュ化エラー: %v", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "認証情報の更新に失敗しました"})
+            return
+        }
+        passwordHash = sql.NullString{String: hash, Valid: true}
+    }
+    if req.PIN != nil {
+        if *req.PIN == "" {
+            clearPIN = true
+        } else {
+            if err := auth.ValidatePIN(*req.PIN); err != nil {
+                c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+                return
+            }
+            hash, err := auth.HashSecret(*req.PIN)
+            if err != nil {
+                log.Printf("PINハッシュ化エラー: %v", err)
+                c.JSON(http.StatusInternalServerError, gin.H{"error": "認証情報の更新に失敗しました"})
+                return
+            }
+            pinHash = sql.NullString{String: hash, Valid: true}
+        }
+    }
+
+    tx, err := db.BeginTx()
+    if err != nil {
+        log.Printf("トランザクション開始エラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "トランザクションの開始に失敗しました"})
+        return
+    }
+    defer tx.Rollback()
+
+    // 監査ログにはハッシュを含めず、ログインIDと変更した項目のみを記録する
+    var previousLoginID sql.NullString
+    err = tx.QueryRow("SELECT login_id FROM staff WHERE id = $1 FOR UPDATE", staffID).Scan(&previousLoginID)
+    if err == sql.ErrNoRows {
+        c.JSON(http.StatusNotFound, gin.H{"error": "スタッフが見つかりません"})
+        return
+    }
+    if err != nil {
+        log.Printf("認証情報更新エラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "認証情報の更新に失敗しました"})
+        return
+    }
+
+    var currentLoginID sql.NullString
+    err = tx.QueryRow(`
+        UPDATE staff
+        SET login_id = COALESCE($2, login_id),
+            password_hash = COALESCE($3, password_hash),
+            pin_hash = CASE WHEN $4 THEN NULL ELSE COALESCE($5, pin_hash) END,
+            failed_login_count = 0,
+            locked_until = NULL
+        WHERE id = $1
+        RETURNING login_id
+    `, staffID, loginID, passwordHash, clearPIN, pinHash).Scan(&currentLoginID)
+    if isUniqueViolation(err) {
+        c.JSON(http.StatusConflict, gin.H{"error": "このログインIDは既に使用されています"})
+        return
+    }
+    if err != nil {
+        log.Printf("認証情報更新エラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "認証情報の更新に失敗しました"})
+        return
+    }
+
+    if !recordAudit(c, tx, audit.Entry{
+        Action:     audit.ActionUpdate,
+        EntityType: "staff_credentials",
+        EntityID:   strconv.Itoa(staffID),
+        Before:     gin.H{"loginId": nullableString(previousLoginID)},
+        After: gin.H{
+            "loginId":         nullableString(currentLoginID),
+            "passwordChanged": passwordHash.Valid,
+            "pinChanged":      pinHash.Valid,
+            "pinCleared":      clearPIN,
+        },
+    }) {
+        return
+    }
+    if err := tx.Commit(); err != nil {
+        log.Printf("コミットエラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "認証情報の更新に失敗しました"})
+        return
+    }
+
+    // パスワード・PINを変更した場合は既存のセッションを無効化
+    if passwordHash.Valid || pinHash.Valid || clearPIN {
+        if err := auth.RevokeStaffSessions(staffID); err != nil {
+            log.Printf("セッション無効化エラー: %v", err)
+        }
+    }
+
+    log.Printf("認証情報を更新しました: 担当者ID = %d", staffID)
+    c.JSON(http.StatusOK, gin.H{"message": "認証情報を更新しました"})
+}
+
+// 担当者の権限更新ハンドラー
+// categories が null の場合はすべてのカテゴリを担当する
+func UpdateStaffPermissions(c *gin.Context) {
+    staffID, err := strconv.Atoi(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "無効なスタッフIDです"})
+        return
+    }
+
+    var req struct {
+        Role       string   `json:"role" binding:"required"`
+        Categories []string `json:"categories"`
+    }
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "無効なリクエストデータです"})
+        return
+    }
+    if !auth.IsValidRole(req.Role) {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "ロールは admin / operator / viewer のいずれかを指定してください"})
+        return
+    }
+
+    tx, err := db.BeginTx()
+    if err != nil {
+        log.Printf("トランザクション開始エラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "トランザクションの開始に失敗しました"})
+        return
+    }
+    defer tx.Rollback()
+
+    for _, category := range req.Categories {
+        var exists bool
+        if err := tx.QueryRow(
+            "SELECT EXISTS(SELECT 1 FROM categories WHERE code = $1)", category,
+        ).Scan(&exists); err != nil {
+            log.Printf("カテゴリ確認エラー: %v", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "権限の更新に失敗しました"})
+            return
+        }
+        if !exists {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "不明なカテゴリです: " + category})
+            return
+        }
+    }
+
+    // 管理者を1人以上残す
+    if req.Role != auth.RoleAdmin {
+        lastAdmin, err := isLastActiveAdmin(tx, staffID)
+        if err != nil {
+            log.Printf("管理者数確認エラー: %v", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "権限の更新に失敗しました"})
+            return
+        }
+        if lastAdmin {
+            c.JSON(http.StatusConflict, gin.H{"error": "管理者が1人もいなくなるため変更できません"})
+            return
+        }
+    }
+
+    before, ok := auditSnapshot(c, tx, staffAuditQuery+" FOR UPDATE", staffID)
+    if !ok {
+        return
+    }
+    if before == nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "スタッフが見つかりません"})
+        return
+    }
+
+    var categories interface{}
+    if req.Categories != nil {
+        categories = pq.Array(req.Categories)
+    }
+    _, err = tx.Exec(
+        "UPDATE staff SET role = $2, categories = $3 WHERE id = $1",
+        staffID, req.Role, categories,
+    )
+    if err != nil {
+        log.Printf("権限更新エラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "権限の更新に失敗しました"})
+        return
+    }
+
+    after, ok := auditSnapshot(c, tx, staffAuditQuery, staffID)
+    if !ok {
+        return
+    }
+    if !recordAudit(c, tx, audit.Entry{
+        Action:     audit.ActionUpdate,
+        EntityType: "staff",
+        EntityID:   strconv.Itoa(staffID),
+        Before:     before,
+        After:      after,
+    }) {
+        return
+    }
+    if err := tx.Commit(); err != nil {
+        log.Printf("コミットエラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "権限の更新に失敗しました"})
+        return
+    }
+
+    log.Printf("権限を更新しました: 担当者ID = %d, ロール = %s, カテゴリ = %v", staffID, req.Role, req.Categories)
+    c.JSON(http.StatusOK, gin.H{"message": "権限を更新しました"})
+}
+
+// セッションCookieの設定（maxAge が負の場合は削除）
+// SESSION_COOKIE_SECURE=true の場合は HTTPS のみで送信する
+func setSessionCookie(c *gin.Context, token string, maxAge int) {
+    c.SetSameSite(http.SameSiteLaxMode)
+    c.SetCookie(auth.SessionCookieName, token, maxAge, "/", "", os.Getenv("SESSION_COOKIE_SECURE") == "true", true)
+}
@@ -0,0 +1,325 @@
+package handler
+
+import (
+    "database/sql"
+    "fmt"
+    "log"
+    "net/http"
+    "regexp"
+    "strings"
+    "github.com/gin-gonic/gin"
+    "github.com/lib/pq"
+    "inventory-tracker/server/internal/audit"
+    "inventory-tracker/server/internal/db"
+    "inventory-tracker/server/internal/model"
+)
+
+// カテゴリコード（URL の :category に使用するため英小文字・数字・アンダースコアのみ）
+var categoryCodePattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+const categoryColumns = "code, name, name_en, sort_order, created_at, updated_at"
+
+func scanCategory(row interface{ Scan(...interface{}) error }) (model.Category, error) {
+    var cat model.Category
+    err := row.Scan(&cat.Code, &cat.Name, &cat.NameEn, &cat.SortOrder, &cat.CreatedAt, &cat.UpdatedAt)
+    return cat, err
+}
+
+// カテゴリ一覧取得ハンドラー（表示順）
+func GetCategories(c *gin.Context) {
+    categories, err := db.GetCategories()
+    if err != nil {
+        log.Printf("カテゴリ一覧取得エラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "カテゴリ一覧の取得に失敗しました"})
+        return
+    }
+
+    c.JSON(http.StatusOK, categories)
+}
+
+// カテゴリの登録・更新リクエスト（code は登録時のみ使用）
+type categoryRequest struct {
+    Code   string `json:"code"`
+    Name   string `json:"name" binding:"required"`
+    NameEn string `json:"nameEn"`
+}
+
+// リクエストの読み取り（前後の空白は除去）
+func bindCategoryRequest(c *gin.Context) (*categoryRequest, bool) {
+    var req categoryRequest
+    if err := c.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.Name) == "" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "カテゴリの表示名を入力してください"})
+        return nil, false
+    }
+    req.Code = strings.TrimSpace(req.Code)
+    req.Name = strings.TrimSpace(req.Name)
+    req.NameEn = strings.TrimSpace(req.NameEn)
+    return &req, true
+}
+
+// カテゴリ追加ハンドラー
+// 追加したカテゴリは最後に表示する
+func CreateCategory(c *gin.Context) {
+    req, ok := bindCategoryRequest(c)
+    if !ok {
+        return
+    }
+    if !categoryCodePattern.MatchString(req.Code) {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "カテゴリコードは英小文字で始まる英小文字・数字・アンダースコアで入力してください"})
+        return
+    }
+
+    tx, err := db.BeginTx()
+    if err != nil {
+        log.Printf("トランザクション開始エラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "トランザクションの開始に失敗しました"})
+        return
+    }
+    defer tx.Rollback()
+
+    cat, err := scanCategory(tx.QueryRow(`
+        INSERT INTO categories (code, name, name_en, sort_order)
+        SELECT $1, $2, $3, COALESCE(MAX(sort_order), 0) + 1 FROM categories
+        RETURNING `+categoryColumns,
+        req.Code, req.Name, req.NameEn,
+    ))
+    if isUniqueViolation(err) {
+        c.JSON(http.StatusConflict, gin.H{"error": "同じコードまたは表示名のカテゴリが既に登録されています"})
+        return
+    }
+    if err != nil {
+        log.Printf("カテゴリ登録エラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "カテゴリの登録に失敗しました"})
+        return
+    }
+
+    if !recordAudit(c, tx, audit.Entry{
+        Action:     audit.ActionCreate,
+        EntityType: "category",
+        EntityID:   cat.Code,
+        After:      cat,
+    }) {
+        return
+    }
+    if err := tx.Commit(); err != nil {
+        log.Printf("トランザクションコミットエラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "トランザクションのコミットに失敗しました"})
+        return
+    }
+
+    log.Printf("カテゴリを登録しました: %s", cat.Code)
+    c.JSON(http.StatusOK, cat)
+}
+
+// カテゴリ更新ハンドラー
+// コードは URL や各テーブルから参照されるため変更できない（表示名のみ変更する）
+func UpdateCategory(c *gin.Context) {
+    code := c.Param("code")
+    req, ok := bindCategoryRequest(c)
+    if !ok {
+        return
+    }
+
+    tx, err := db.BeginTx()
+    if err != nil {
+        log.Printf("トランザクション開始エラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "トランザクションの開始に失敗しました"})
+        return
+    }
+    defer tx.Rollback()
+
+    before, err := scanCategory(tx.QueryRow("SELECT "+categoryColumns+" FROM categories WHERE code = $1 FOR UPDATE", code))
+    if err == sql.ErrNoRows {
+        c.JSON(http.StatusNotFound, gin.H{"error": "カテゴリが見つかりません"})
+        return
+    }
+    if err != nil {
+        log.Printf("カテゴリ取得エラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "カテゴリの更新に失敗しました"})
+        return
+    }
+
+    cat, err := scanCategory(tx.QueryRow(`
+        UPDATE categories SET name = $2, name_en = $3
+        WHERE code = $1
+        RETURNING `+categoryColumns,
+        code, req.Name, req.NameEn,
+    ))
+    if isUniqueViolation(err) {
+        c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("表示名「%s」のカテゴリは既に登録されています", req.Name)})
+        return
+    }
+    if err != nil {
+        log.Printf("カテゴリ更新エラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "カテゴリの更新に失敗しました"})
+        return
+    }
+
+    if !recordAudit(c, tx, audit.Entry{
+        Action:     audit.ActionUpdate,
+        EntityType: "category",
+        EntityID:   code,
+        Before:     before,
+        After:      cat,
+    }) {
+        return
+    }
+    if err := tx.Commit(); err != nil {
+        log.Printf("トランザクションコミットエラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "トランザクションのコミットに失敗しました"})
+        return
+    }
+
+    log.Printf("カテゴリを更新しました: %s", code)
+    c.JSON(http.StatusOK, cat)
+}
+
+// カテゴリ並び替えハンドラー
+// すべてのカテゴリのコードを表示順に指定する
+func ReorderCategories(c *gin.Context) {
+    var req struct {
+        Codes []string `json:"codes" binding:"required"`
+    }
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "カテゴリコードを表示順に指定してください"})
+        return
+    }
+
+    tx, err := db.BeginTx()
+    if err != nil {
+        log.Printf("トランザクション開始エラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "トランザクションの開始に失敗しました"})
+        return
+    }
+    defer tx.Rollback()
+
+    // 並び替え中にカテゴリが追加・変更されないよう、すべての行をロックする
+    var before []string
+    err = tx.QueryRow(`
+        SELECT COALESCE(array_agg(code ORDER BY sort_order, code), '{}')
+        FROM (SELECT code, sort_order FROM categories FOR UPDATE) c
+    `).Scan(pq.Array(&before))
+    if err != nil {
+        log.Printf("カテゴリ取得エラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "カテゴリの並び替えに失敗しました"})
+        return
+    }
+
+    // 指定されたコードが登録済みのカテゴリと過不足なく一致すること
+    current := make(map[string]bool)
+    for _, code := range before {
+        current[code] = true
+    }
+    seen := make(map[string]bool)
+    for _, code := range req.Codes {
+        if !current[code] || seen[code] {
+            c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("カテゴリ %s は並び替えに指定できません", code)})
+            return
+        }
+        seen[code] = true
+    }
+    if len(seen) != len(current) {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "すべてのカテゴリを指定してください"})
+        return
+    }
+
+    _, err = tx.Exec(`
+        UPDATE categories c SET sort_order = o.ord
+        FROM unnest($1::text[]) WITH ORDINALITY AS o(code, ord)
+        WHERE c.code = o.code AND c.sort_order <> o.ord
+    `, pq.Array(req.Codes))
+    if err != nil {
+        log.Printf("カテゴリ並び替えエラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "カテゴリの並び替えに失敗しました"})
+        return
+    }
+
+    if !recordAudit(c, tx, audit.Entry{
+        Action:     audit.ActionUpdate,
+        EntityType: "category_order",
+        EntityID:   "categories",
+        Before:     before,
+        After:      req.Codes,
+    }) {
+        return
+    }
+    if err := tx.Commit(); err != nil {
+        log.Printf("トランザクションコミットエラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "トランザクションのコミットに失敗しました"})
+        return
+    }
+
+    log.Printf("カテゴリを並び替えました")
+    c.JSON(http.StatusOK, gin.H{"message": "カテゴリを並び替えました"})
+}
+
+// カテゴリ削除ハンドラー
+// 製品タイプ・入庫取消記録・伝票番号書式・担当者の担当カテゴリで使用されているカテゴリは削除できない
+func DeleteCategory(c *gin.Context) {
+    code := c.Param("code")
+
+    tx, err := db.BeginTx()
+    if err != nil {
+        log.Printf("トランザクション開始エラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "トランザクションの開始に失敗しました"})
+        return
+    }
+    defer tx.Rollback()
+
+    before, err := scanCategory(tx.QueryRow("SELECT "+categoryColumns+" FROM categories WHERE code = $1 FOR UPDATE", code))
+    if err == sql.ErrNoRows {
+        c.JSON(http.StatusNotFound, gin.H{"error": "カテゴリが見つかりません"})
+        return
+    }
+    if err != nil {
+        log.Printf("カテゴリ取得エラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "カテゴリの削除に失敗しました"})
+        return
+    }
+
+    var referenced bool
+    err = tx.QueryRow(`
+        SELECT
+            EXISTS(SELECT 1 FROM product_types WHERE category = $1)
+            OR EXISTS(SELECT 1 FROM inbound_voids WHERE category = $1)
+            OR EXISTS(SELECT 1 FROM document_number_formats WHERE category = $1)
+            OR EXISTS(SELECT 1 FROM staff WHERE $1 = ANY(categories))
+    `, code).Scan(&referenced)
+    if err != nil {
+        log.Printf("カテゴリ参照確認エラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "カテゴリの削除に失敗しました"})
+        return
+    }
+    if referenced {
+        c.JSON(http.StatusConflict, gin.H{"error": "製品タイプや担当者などで使用されているカテゴリは削除できません"})
+        return
+    }
+
+    _, err = tx.Exec("DELETE FROM categories WHERE code = $1", code)
+    if isForeignKeyViolation(err) {
+        c.JSON(http.StatusConflict, gin.H{"error": "製品タイプや担当者などで使用されているカテゴリは削除できません"})
+        return
+    }
+    if err != nil {
+        log.Printf("カテゴリ削除エラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "カテゴリの削除に失敗しました"})
+        return
+    }
+
+    if !recordAudit(c, tx, audit.Entry{
+        Action:     audit.ActionDelete,
+        EntityType: "category",
+        EntityID:   code,
+        Before:     before,
+    }) {
+        return
+    }
+    if err := tx.Commit(); err != nil {
+        log.Printf("トランザクションコミットエラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "トランザクションのコミットに失敗しました"})
+        return
+    }
+
+    log.Printf("カテゴリを削除しました: %s", code)
+    c.JSON(http.StatusOK, gin.H{"message": "カテゴリを削除しました"})
+}
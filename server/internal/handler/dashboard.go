@@ -87,15 +87,16 @@ func getRecentActivities() ([]gin.H, error) {
     outboundQuery := `
         SELECT 
             'outbound' as type,
-            or.outbound_date as date,
+            obr.outbound_date as date,
             p.product_id,
             pt.category,
             s.name as staff_name
-        FROM outbound_records or
-        INNER JOIN products p ON or.product_id = p.product_id
+        FROM outbound_records obr
+        INNER JOIN products p ON obr.product_id = p.product_id
         INNER JOIN product_types pt ON p.type_id = pt.id
-        INNER JOIN staff s ON or.staff_id = s.id
-        ORDER BY or.outbound_date DESC
+        INNER JOIN staff s ON obr.staff_id = s.id
+        WHERE obr.voided_at IS NULL
+        ORDER BY obr.outbound_date DESC
         LIMIT 5
     `
 
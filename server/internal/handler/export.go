@@ -0,0 +1,241 @@
+package handler
+
+import (
+    "database/sql"
+    "encoding/csv"
+    "fmt"
+    "net/http"
+    "net/url"
+    "time"
+    "github.com/gin-gonic/gin"
+    "github.com/xuri/excelize/v2"
+)
+
+// エクスポート形式
+const (
+    exportFormatCSV  = "csv"
+    exportFormatXLSX = "xlsx"
+)
+
+// 一覧のエクスポート先
+// 一覧取得ハンドラーは format が指定された場合に JSON の代わりに1行ずつ書き出す
+type exportWriter interface {
+    WriteRow(values []interface{}) error
+    Close() error
+}
+
+// format クエリパラメータの確認（未指定の場合は空文字）
+func exportFormat(c *gin.Context) (string, bool) {
+    format := c.Query("format")
+    switch format {
+    case "", exportFormatCSV, exportFormatXLSX:
+        return format, true
+    }
+    c.JSON(http.StatusBadRequest, gin.H{"error": "format には csv または xlsx を指定してください"})
+    return "", false
+}
+
+// エクスポートの開始（見出し行を書き出す）
+func newExportWriter(c *gin.Context, format string, name string, headers []string) (exportWriter, error) {
+    filename := fmt.Sprintf("%s_%s.%s", name, time.Now().Format("20060102"), format)
+    disposition := fmt.Sprintf("attachment; filename=%q; filename*=UTF-8''%s", filename, url.PathEscape(filename))
+
+    values := make([]interface{}, len(headers))
+    for i, h := range headers {
+        values[i] = h
+    }
+
+    var w exportWriter
+    switch format {
+    case exportFormatCSV:
+        c.Header("Content-Type", "text/csv; charset=utf-8")
+        c.Header("Content-Disposition", disposition)
+        c.Status(http.StatusOK)
+        // Excelで文字化けしないようBOMを付与
+        if _, err := c.Writer.Write([]byte("\xef\xbb\xbf")); err != nil {
+            return nil, err
+        }
+        cw := csv.NewWriter(c.Writer)
+        cw.UseCRLF = true
+        w = &csvExportWriter{w: cw}
+    case exportFormatXLSX:
+        xw, err := newXLSXExportWriter(c)
+        if err != nil {
+            return nil, err
+        }
+        c.Header("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+        c.Header("Content-Disposition", disposition)
+        w = xw
+    default:
+        return nil, fmt.Errorf("unknown export format: %s", format)
+    }
+
+    if err := w.WriteRow(values); err != nil {
+        return nil, err
+    }
+    return w, nil
+}
+
+// CSVのエクスポート（レスポンスへ逐次書き出し）
+type csvExportWriter struct {
+    w *csv.Writer
+}
+
+func (w *csvExportWriter) WriteRow(values []interface{}) error {
+    record := make([]string, len(values))
+    for i, v := range values {
+        record[i] = exportCellString(v)
+    }
+    return w.w.Write(record)
+}
+
+func (w *csvExportWriter) Close() error {
+    w.w.Flush()
+    return w.w.Error()
+}
+
+// XLSXのエクスポート（ストリーム書き込みし、Close 時にレスポンスへ出力）
+type xlsxExportWriter struct {
+    c      *gin.Context
+    f      *excelize.File
+    sw     *excelize.StreamWriter
+    row    int
+    dateID int
+}
+
+func newXLSXExportWriter(c *gin.Context) (*xlsxExportWriter, error) {
+    f := excelize.NewFile()
+    sw, err := f.NewStreamWriter("Sheet1")
+    if err != nil {
+        f.Close()
+        return nil, err
+    }
+    dateFormat := "yyyy-mm-dd"
+    dateID, err := f.NewStyle(&excelize.Style{CustomNumFmt: &dateFormat})
+    if err != nil {
+        f.Close()
+        return nil, err
+    }
+    return &xlsxExportWriter{c: c, f: f, sw: sw, dateID: dateID}, nil
+}
+
+func (w *xlsxExportWriter) WriteRow(values []interface{}) error {
+    w.row++
+    cells := make([]interface{}, len(values))
+    for i, v := range values {
+        switch v := v.(type) {
+        case time.Time:
+            cells[i] = excelize.Cell{StyleID: w.dateID, Value: v}
+        case nil:
+            cells[i] = ""
+        default:
+            cells[i] = v
+        }
+    }
+    cell, err := excelize.CoordinatesToCellName(1, w.row)
+    if err != nil {
+        return err
+    }
+    return w.sw.SetRow(cell, cells)
+}
+
+func (w *xlsxExportWriter) Close() error {
+    defer w.f.Close()
+    if err := w.sw.Flush(); err != nil {
+        return err
+    }
+    w.c.Status(http.StatusOK)
+    return w.f.Write(w.c.Writer)
+}
+
+// CSV用のセル値の文字列化
+func exportCellString(v interface{}) string {
+    switch v := v.(type) {
+    case nil:
+        return ""
+    case string:
+        return v
+    case time.Time:
+        return v.Format("2006-01-02")
+    default:
+        return fmt.Sprint(v)
+    }
+}
+
+// 製品ステータスの表示名
+func statusLabel(status string) string {
+    switch status {
+    case "in_stock":
+        return "在庫中"
+    case "out_of_stock":
+        return "出庫済み"
+    }
+    return status
+}
+
+// ベストのロゴ有無の表示名
+func hasLogoLabel(hasLogo bool) string {
+    if hasLogo {
+        return "あり"
+    }
+    return "なし"
+}
+
+// 未設定の値は空欄として出力
+func nullableTime(t sql.NullTime) interface{} {
+    if !t.Valid {
+        return nil
+    }
+    return t.Time
+}
+
+// 未設定の数値は null として出力
+func nullableInt(v sql.NullInt32) interface{} {
+    if !v.Valid {
+        return nil
+    }
+    return int(v.Int32)
+}
+
+// カテゴリ固有の列の見出し
+func detailExportHeaders(category string) []string {
+    switch category {
+    case "pc":
+        return []string{"型番", "シリアル番号", "購入日", "保証期間（月）"}
+    case "vest":
+        return []string{"ベスト種類", "サイズ", "ロゴ"}
+    }
+    return nil
+}
+
+// カテゴリ固有の列の値
+func detailExportValues(
+    category string,
+    modelNumber, serialNumber sql.NullString, purchaseDate sql.NullTime, warrantyPeriod sql.NullInt32,
+    vestType, vestSize sql.NullString, vestHasLogo sql.NullBool,
+) []interface{} {
+    switch category {
+    case "pc":
+        return []interface{}{
+            modelNumber.String, serialNumber.String,
+            nullableTime(purchaseDate), nullableInt(warrantyPeriod),
+        }
+    case "vest":
+        var hasLogo interface{}
+        if vestHasLogo.Valid {
+            hasLogo = hasLogoLabel(vestHasLogo.Bool)
+        }
+        return []interface{}{vestTypeLabel(vestType.String), vestSize.String, hasLogo}
+    }
+    return nil
+}
+
+// ベストの種類の表示名
+func vestTypeLabel(code string) string {
+    for label, c := range vestTypeLabels {
+        if c == code {
+            return label
+        }
+    }
+    return code
+}
@@ -0,0 +1,340 @@
+package handler
+
+import (
+    "database/sql"
+    "encoding/json"
+    "fmt"
+    "log"
+    "net/http"
+    "strings"
+    "time"
+    "github.com/gin-gonic/gin"
+    "github.com/lib/pq"
+    "inventory-tracker/server/internal/audit"
+    "inventory-tracker/server/internal/auth"
+    "inventory-tracker/server/internal/db"
+    "inventory-tracker/server/internal/model"
+    "inventory-tracker/server/internal/numbering"
+)
+
+// 入庫番号を採番し、製品・詳細情報・入庫記録を登録する
+// リクエストは validateInboundRequest で検証済みであること
+func createInbound(c *gin.Context, tx *sql.Tx, category string, req *model.InboundRequest) (string, error) {
+    // 入庫番号の生成
+    inboundNumber, err := numbering.Next(tx, numbering.Inbound, category)
+    if err != nil {
+        return "", fmt.Errorf("入庫番号生成エラー: %v", err)
+    }
+
+    // 製品の登録と入庫記録の作成
+    for _, p := range req.Products {
+        // 追加項目（検証で正規化済み）
+        attributes := []byte("{}")
+        if len(p.Attributes) > 0 {
+            if attributes, err = json.Marshal(p.Attributes); err != nil {
+                return "", fmt.Errorf("追加項目の変換エラー: %v", err)
+            }
+        }
+
+        // 製品の登録
+        var productID string
+        err = tx.QueryRow(`
+            INSERT INTO products (product_id, type_id, lot_number, inbound_number, status, attributes)
+            VALUES ($1, $2, $3, $4, 'in_stock', $5)
+            RETURNING product_id
+        `, p.ProductID, p.TypeID, p.LotNumber, inboundNumber, string(attributes)).Scan(&productID)
+        if err != nil {
+            if isUniqueViolation(err) {
+                return "", &requestError{
+                    Status:  http.StatusConflict,
+                    Message: fmt.Sprintf("製品ID %s は他の処理で既に登録されています", p.ProductID),
+                }
+            }
+            return "", fmt.Errorf("製品登録エラー: %v", err)
+        }
+
+        // ベストの場合、詳細情報も登録
+        if category == "vest" && p.VestDetails != nil {
+            _, err = tx.Exec(`
+                INSERT INTO vest_details (product_id, type, size, has_logo)
+                VALUES ($1, $2, $3, $4)
+            `, productID, p.VestDetails.Type, p.VestDetails.Size, *p.VestDetails.HasLogo)
+            if err != nil {
+                return "", fmt.Errorf("ベスト詳細情報登録エラー: %v", err)
+            }
+        }
+
+        // PCの場合、詳細情報も登録
+        if category == "pc" && p.PCDetails != nil {
+            _, err = tx.Exec(`
+                INSERT INTO pc_details (product_id, model_number, serial_number, purchase_date, warranty_period)
+                VALUES ($1, $2, $3, $4, $5)
+            `, productID, p.PCDetails.ModelNumber, p.PCDetails.SerialNumber,
+                p.PCDetails.PurchaseDate, p.PCDetails.WarrantyPeriod)
+            if err != nil {
+                if isUniqueViolation(err) {
+                    return "", &requestError{
+                        Status:  http.StatusConflict,
+                        Message: fmt.Sprintf("シリアル番号 %s は他の処理で既に登録されています", p.PCDetails.SerialNumber),
+                    }
+                }
+                return "", fmt.Errorf("PC詳細情報登録エラー: %v", err)
+            }
+        }
+
+        // 入庫記録の作成
+        _, err = tx.Exec(`
+            INSERT INTO inbound_records (product_id, staff_id, inbound_number, inbound_date)
+            VALUES ($1, $2, $3, $4)
+        `, productID, req.StaffID, inboundNumber, req.InboundDate)
+        if err != nil {
+            return "", fmt.Errorf("入庫記録作成エラー: %v", err)
+        }
+    }
+
+    err = audit.Record(tx, c, audit.Entry{
+        Action:     audit.ActionCreate,
+        EntityType: "inbound",
+        EntityID:   inboundNumber,
+        After: gin.H{
+            "category":    category,
+            "staffId":     req.StaffID,
+            "inboundDate": req.InboundDate,
+            "products":    req.Products,
+        },
+    })
+    if err != nil {
+        return "", err
+    }
+
+    return inboundNumber, nil
+}
+
+// 入庫取消ハンドラー
+// 入庫伝票で登録した製品がすべて未出庫の場合のみ、製品・詳細情報・入庫記録を削除する
+// 取消済みの出庫記録は出庫とみなさず、製品と合わせて削除する（削除した内容は inbound_voids に保存する）
+func VoidInbound(c *gin.Context) {
+    category := c.Param("category")
+    inboundNumber := c.Param("inboundNumber")
+    log.Printf("入庫取消開始: カテゴリ = %s, 入庫番号 = %s", category, inboundNumber)
+
+    var req model.VoidRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        log.Printf("リクエストデータバインドエラー: %v", err)
+        c.JSON(http.StatusBadRequest, gin.H{"error": "無効なリクエストデータです"})
+        return
+    }
+
+    // 担当者はログイン中のセッションから取得
+    req.StaffID = auth.CurrentStaffID(c)
+    if strings.TrimSpace(req.Reason) == "" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "取消理由を入力してください"})
+        return
+    }
+
+    // トランザクション開始
+    tx, err := db.BeginTx()
+    if err != nil {
+        log.Printf("トランザクション開始エラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "トランザクションの開始に失敗しました"})
+        return
+    }
+    defer tx.Rollback()
+
+    // 入庫伝票の製品を取得（取消処理が完了するまでロック）
+    rows, err := tx.Query(`
+        SELECT
+            p.product_id, p.status,
+            EXISTS(
+                SELECT 1 FROM outbound_records obr
+                WHERE obr.product_id = p.product_id
+                AND obr.voided_at IS NULL
+            ) AS has_outbound
+        FROM inbound_records ir
+        INNER JOIN products p ON ir.product_id = p.product_id
+        INNER JOIN product_types pt ON p.type_id = pt.id
+        WHERE ir.inbound_number = $1
+        AND pt.category = $2
+        ORDER BY p.product_id
+        FOR UPDATE OF p
+    `, inboundNumber, category)
+    if err != nil {
+        log.Printf("入庫記録取得エラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "入庫記録の取得に失敗しました"})
+        return
+    }
+
+    var productIDs []string
+    var blocking []gin.H
+    for rows.Next() {
+        var productID, status string
+        var hasOutbound bool
+        if err := rows.Scan(&productID, &status, &hasOutbound); err != nil {
+            rows.Close()
+            log.Printf("入庫記録読み取りエラー: %v", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "入庫記録の読み取りに失敗しました"})
+            return
+        }
+
+        productIDs = append(productIDs, productID)
+        switch {
+        case status != "in_stock":
+            blocking = append(blocking, gin.H{"productId": productID, "reason": "出庫済みです"})
+        case hasOutbound:
+            blocking = append(blocking, gin.H{"productId": productID, "reason": "出庫記録があります"})
+        }
+    }
+    rows.Close()
+    if err := rows.Err(); err != nil {
+        log.Printf("入庫記録読み取りエラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "入庫記録の読み取りに失敗しました"})
+        return
+    }
+
+    if len(productIDs) == 0 {
+        c.JSON(http.StatusNotFound, gin.H{"error": "指定された入庫番号が見つかりません"})
+        return
+    }
+    if len(blocking) > 0 {
+        log.Printf("取消できない製品があります: %+v", blocking)
+        c.JSON(http.StatusConflict, gin.H{
+            "error":    "出庫済みの製品が含まれているため取消できません",
+            "failures": blocking,
+        })
+        return
+    }
+
+    // 削除前の内容を保存
+    var snapshot []byte
+    err = tx.QueryRow(`
+        SELECT json_build_object(
+            'products', (SELECT COALESCE(json_agg(row_to_json(p)), '[]'::json) FROM products p WHERE p.product_id = ANY($1)),
+            'pcDetails', (SELECT COALESCE(json_agg(row_to_json(pc)), '[]'::json) FROM pc_details pc WHERE pc.product_id = ANY($1)),
+            'vestDetails', (SELECT COALESCE(json_agg(row_to_json(vd)), '[]'::json) FROM vest_details vd WHERE vd.product_id = ANY($1)),
+            'outboundRecords', (SELECT COALESCE(json_agg(row_to_json(obr)), '[]'::json) FROM outbound_records obr WHERE obr.product_id = ANY($1)),
+            'returnRecords', (SELECT COALESCE(json_agg(row_to_json(rr)), '[]'::json) FROM return_records rr WHERE rr.product_id = ANY($1)),
+            'inboundRecords', (SELECT COALESCE(json_agg(row_to_json(ir)), '[]'::json) FROM inbound_records ir WHERE ir.inbound_number = $2)
+        )
+    `, pq.Array(productIDs), inboundNumber).Scan(&snapshot)
+    if err != nil {
+        log.Printf("取消内容の取得エラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "取消内容の取得に失敗しました"})
+        return
+    }
+
+    _, err = tx.Exec(`
+        INSERT INTO inbound_voids (inbound_number, category, staff_id, reason, product_count, snapshot)
+        VALUES ($1, $2, $3, $4, $5, $6)
+    `, inboundNumber, category, req.StaffID, strings.TrimSpace(req.Reason), len(productIDs), snapshot)
+    if err != nil {
+        log.Printf("入庫取消記録作成エラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "入庫取消記録の作成に失敗しました"})
+        return
+    }
+
+    // 削除した内容を変更前、取消理由を変更後として記録
+    if !recordAudit(c, tx, audit.Entry{
+        Action:     audit.ActionDelete,
+        EntityType: "inbound",
+        EntityID:   inboundNumber,
+        Before:     json.RawMessage(snapshot),
+        After:      gin.H{"reason": strings.TrimSpace(req.Reason)},
+    }) {
+        return
+    }
+
+    // 参照元から順に削除（出庫記録は取消済みのもののみ残っている）
+    deletes := []struct {
+        Query   string
+        Message string
+    }{
+        {"DELETE FROM pc_details WHERE product_id = ANY($1)", "PC詳細情報の削除に失敗しました"},
+        {"DELETE FROM vest_details WHERE product_id = ANY($1)", "ベスト詳細情報の削除に失敗しました"},
+        {"DELETE FROM return_records WHERE product_id = ANY($1)", "返品記録の削除に失敗しました"},
+        {"DELETE FROM outbound_records WHERE product_id = ANY($1)", "出庫記録の削除に失敗しました"},
+        {"DELETE FROM inbound_records WHERE product_id = ANY($1)", "入庫記録の削除に失敗しました"},
+        {"DELETE FROM products WHERE product_id = ANY($1)", "製品の削除に失敗しました"},
+    }
+    for _, d := range deletes {
+        if _, err := tx.Exec(d.Query, pq.Array(productIDs)); err != nil {
+            log.Printf("%s: %v", d.Message, err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": d.Message})
+            return
+        }
+    }
+
+    // トランザクションのコミット
+    if err := tx.Commit(); err != nil {
+        log.Printf("トランザクションコミットエラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "トランザクションのコミットに失敗しました"})
+        return
+    }
+
+    log.Printf("入庫取消が完了しました。入庫番号: %s, 製品: %v", inboundNumber, productIDs)
+    c.JSON(http.StatusOK, gin.H{
+        "success":       true,
+        "inboundNumber": inboundNumber,
+        "voidedCount":   len(productIDs),
+        "products":      productIDs,
+    })
+}
+
+// 入庫取消履歴取得ハンドラー
+func GetInboundVoids(c *gin.Context) {
+    category := c.Param("category")
+
+    rows, err := db.DB.Query(`
+        SELECT
+            iv.id, iv.inbound_number, iv.reason, iv.product_count, iv.snapshot, iv.voided_at,
+            s.id, s.name
+        FROM inbound_voids iv
+        LEFT JOIN staff s ON iv.staff_id = s.id
+        WHERE iv.category = $1
+        ORDER BY iv.voided_at DESC, iv.id DESC
+    `, category)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("入庫取消履歴の取得に失敗しました: %v", err)})
+        return
+    }
+    defer rows.Close()
+
+    var voids []gin.H
+    for rows.Next() {
+        var v struct {
+            ID            int
+            InboundNumber string
+            Reason        string
+            ProductCount  int
+            Snapshot      []byte
+            VoidedAt      time.Time
+            StaffID       *int
+            StaffName     *string
+        }
+        if err := rows.Scan(
+            &v.ID, &v.InboundNumber, &v.Reason, &v.ProductCount, &v.Snapshot, &v.VoidedAt,
+            &v.StaffID, &v.StaffName,
+        ); err != nil {
+            c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("データの読み取りに失敗しました: %v", err)})
+            return
+        }
+
+        record := gin.H{
+            "id":            v.ID,
+            "inboundNumber": v.InboundNumber,
+            "reason":        v.Reason,
+            "productCount":  v.ProductCount,
+            "snapshot":      json.RawMessage(v.Snapshot),
+            "voidedAt":      v.VoidedAt,
+        }
+        if v.StaffID != nil {
+            record["staff"] = gin.H{
+                "id":   *v.StaffID,
+                "name": *v.StaffName,
+            }
+        }
+
+        voids = append(voids, record)
+    }
+
+    c.JSON(http.StatusOK, voids)
+}
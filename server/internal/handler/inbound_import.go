@@ -0,0 +1,513 @@
+package handler
+
+import (
+    "bytes"
+    "encoding/csv"
+    "encoding/json"
+    "fmt"
+    "io"
+    "log"
+    "net/http"
+    "path/filepath"
+    "strconv"
+    "strings"
+    "time"
+    "unicode/utf8"
+    "github.com/gin-gonic/gin"
+    "github.com/xuri/excelize/v2"
+    "golang.org/x/text/encoding/japanese"
+    "golang.org/x/text/transform"
+    "inventory-tracker/server/internal/auth"
+    "inventory-tracker/server/internal/db"
+    "inventory-tracker/server/internal/model"
+)
+
+const (
+    // 取り込みファイルのサイズ上限
+    maxImportFileSize = 10 << 20
+    // 取り込みできる行数の上限
+    maxImportRows = 5000
+)
+
+// 取り込み項目と既定の列見出し（mapping で上書き可能）
+var importColumnAliases = map[string][]string{
+    "productId":      {"製品ID", "productId", "product_id"},
+    "type":           {"製品タイプ", "タイプ", "type", "typeName", "typeId"},
+    "lotNumber":      {"ロット番号", "ロット", "lotNumber", "lot_number"},
+    "modelNumber":    {"型番", "modelNumber", "model_number"},
+    "serialNumber":   {"シリアル番号", "serialNumber", "serial_number"},
+    "purchaseDate":   {"購入日", "purchaseDate", "purchase_date"},
+    "warrantyPeriod": {"保証期間", "warrantyPeriod", "warranty_period"},
+    "vestType":       {"ベスト種類", "種類", "vestType", "vest_type"},
+    "vestSize":       {"サイズ", "vestSize", "vest_size", "size"},
+    "vestHasLogo":    {"ロゴ", "ロゴ有無", "vestHasLogo", "hasLogo", "has_logo"},
+}
+
+// 追加項目の取り込み列（mapping では "attributes.<キー>" で指定し、既定の列見出しは項目名またはキー）
+const importAttributePrefix = "attributes."
+
+type importAttributeColumn struct {
+    def   model.AttributeDefinition
+    index int
+}
+
+// 入庫ファイル取り込みハンドラー
+// CSV / XLSX の入庫データを HandleInbound と同じ検証にかけ、confirm=true の場合のみ1つの入庫番号で登録する
+// フォーム項目: file, inboundDate, confirm, typeId（タイプ列がない場合の既定値）, mapping（項目→列見出しのJSON）, sheet
+// エラーの row はファイル上の行番号（1行目は見出し）
+func ImportInbound(c *gin.Context) {
+    category := c.Param("category")
+    log.Printf("入庫ファイル取り込み開始: カテゴリ = %s", category)
+
+    fileHeader, err := c.FormFile("file")
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "取り込むファイルを指定してください"})
+        return
+    }
+    if fileHeader.Size > maxImportFileSize {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "ファイルサイズが大きすぎます（上限10MB）"})
+        return
+    }
+
+    // 列の対応付け
+    mapping := make(map[string]string)
+    if raw := c.PostForm("mapping"); raw != "" {
+        if err := json.Unmarshal([]byte(raw), &mapping); err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "列の対応付け（mapping）の形式が正しくありません"})
+            return
+        }
+        for field := range mapping {
+            if strings.HasPrefix(field, importAttributePrefix) {
+                continue
+            }
+            if _, ok := importColumnAliases[field]; !ok {
+                c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("列の対応付けに不明な項目があります: %s", field)})
+                return
+            }
+        }
+    }
+
+    // リクエスト全体の項目
+    // 担当者はログイン中のセッションから取得
+    req := model.InboundRequest{StaffID: auth.CurrentStaffID(c)}
+    if v := c.PostForm("inboundDate"); v != "" {
+        if req.InboundDate, err = time.Parse("2006-01-02", v); err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "無効な日付フォーマットです"})
+            return
+        }
+    }
+    defaultTypeID := 0
+    if v := c.PostForm("typeId"); v != "" {
+        if defaultTypeID, err = strconv.Atoi(v); err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "無効な製品タイプIDです"})
+            return
+        }
+    }
+    confirm := c.PostForm("confirm") == "true"
+
+    // ファイルの読み込み
+    file, err := fileHeader.Open()
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "ファイルを開けませんでした"})
+        return
+    }
+    defer file.Close()
+
+    var records [][]string
+    switch strings.ToLower(filepath.Ext(fileHeader.Filename)) {
+    case ".csv":
+        records, err = readImportCSV(file)
+    case ".xlsx":
+        records, err = readImportXLSX(file, c.PostForm("sheet"))
+    default:
+        c.JSON(http.StatusBadRequest, gin.H{"error": "CSVまたはXLSXファイルを指定してください"})
+        return
+    }
+    if err != nil {
+        log.Printf("ファイル読み込みエラー: %v", err)
+        c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("ファイルを読み込めませんでした: %v", err)})
+        return
+    }
+    if len(records) < 2 {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "取り込むデータがありません（1行目は見出し行としてください）"})
+        return
+    }
+    if len(records)-1 > maxImportRows {
+        c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("一度に取り込めるのは%d行までです", maxImportRows)})
+        return
+    }
+
+    columns, err := resolveImportColumns(records[0], mapping)
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+
+    // 製品タイプ名からIDへの変換表
+    // 無効化した製品タイプも含め、名前で指定された場合は入庫データの検証で無効化済みとして報告する
+    types, err := db.GetProductTypes(category, true)
+    if err != nil {
+        log.Printf("製品タイプ取得エラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "製品タイプの取得に失敗しました"})
+        return
+    }
+    typeIDs := make(map[string]int)
+    for _, t := range types {
+        typeIDs[t.Name] = t.ID
+    }
+
+    // 追加項目の列
+    attributeDefs, err := loadAttributeDefinitions(db.DB, category, false)
+    if err != nil {
+        log.Printf("追加項目取得エラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "追加項目の取得に失敗しました"})
+        return
+    }
+    attributeColumns, err := resolveImportAttributeColumns(records[0], mapping, attributeDefs)
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+
+    // 行データを入庫リクエストに変換
+    // 検証は空行を除いた products の順番（1始まり）で行い、fileRows でファイル上の行に戻す
+    parseErrors := []model.InboundValidationError{}
+    fileRows := make([]int, 0, len(records)-1)
+    for i, record := range records[1:] {
+        if isBlankRecord(record) {
+            continue
+        }
+        product, errs := parseImportRow(category, record, columns, attributeColumns, typeIDs, defaultTypeID, len(req.Products)+1)
+        req.Products = append(req.Products, product)
+        fileRows = append(fileRows, i+2)
+        parseErrors = append(parseErrors, errs...)
+    }
+    if len(req.Products) == 0 {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "取り込むデータがありません"})
+        return
+    }
+
+    // トランザクション開始
+    tx, err := db.BeginTx()
+    if err != nil {
+        log.Printf("トランザクション開始エラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "トランザクションの開始に失敗しました"})
+        return
+    }
+    defer tx.Rollback()
+
+    validationErrors, err := validateInboundRequest(tx, category, &req)
+    if err != nil {
+        log.Printf("入庫データ検証エラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "入庫データの検証に失敗しました"})
+        return
+    }
+
+    // 変換時のエラーと同じ項目の検証エラーは重複するため除外
+    reported := make(map[string]bool)
+    for _, e := range parseErrors {
+        reported[fmt.Sprintf("%d:%s", e.Row, e.Field)] = true
+    }
+    errs := parseErrors
+    for _, e := range validationErrors {
+        if !reported[fmt.Sprintf("%d:%s", e.Row, e.Field)] {
+            errs = append(errs, e)
+        }
+    }
+    errorRows := make(map[int]bool)
+    for i := range errs {
+        if errs[i].Row > 0 {
+            errorRows[errs[i].Row] = true
+            errs[i].Row = fileRows[errs[i].Row-1]
+        }
+    }
+
+    if !confirm {
+        preview := make([]gin.H, 0, len(req.Products))
+        for i, p := range req.Products {
+            preview = append(preview, gin.H{
+                "row":     fileRows[i],
+                "valid":   !errorRows[i+1],
+                "product": p,
+            })
+        }
+        c.JSON(http.StatusOK, gin.H{
+            "rows":       preview,
+            "errors":     errs,
+            "totalCount": len(req.Products),
+            "errorCount": len(errorRows),
+            "canConfirm": len(errs) == 0,
+        })
+        return
+    }
+
+    if len(errs) > 0 {
+        c.JSON(http.StatusUnprocessableEntity, gin.H{
+            "error":  fmt.Sprintf("入力内容に%d件の誤りがあります", len(errs)),
+            "errors": errs,
+        })
+        return
+    }
+
+    inboundNumber, err := createInbound(c, tx, category, &req)
+    if err != nil {
+        respondRequestError(c, err, "入庫処理に失敗しました")
+        return
+    }
+
+    // トランザクションのコミット
+    if err := tx.Commit(); err != nil {
+        log.Printf("トランザクションコミットエラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "トランザクションのコミットに失敗しました"})
+        return
+    }
+
+    log.Printf("入庫ファイル取り込みが完了しました。入庫番号: %s, 件数: %d", inboundNumber, len(req.Products))
+    c.JSON(http.StatusOK, gin.H{
+        "success":        true,
+        "inboundNumber":  inboundNumber,
+        "processedCount": len(req.Products),
+    })
+}
+
+// CSVの読み込み（UTF-8 / BOM付きUTF-8 / Shift_JIS に対応）
+func readImportCSV(r io.Reader) ([][]string, error) {
+    data, err := io.ReadAll(r)
+    if err != nil {
+        return nil, err
+    }
+    data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+    if !utf8.Valid(data) {
+        if data, _, err = transform.Bytes(japanese.ShiftJIS.NewDecoder(), data); err != nil {
+            return nil, fmt.Errorf("文字コードを判別できません")
+        }
+    }
+
+    reader := csv.NewReader(bytes.NewReader(data))
+    reader.FieldsPerRecord = -1
+    reader.TrimLeadingSpace = true
+    return reader.ReadAll()
+}
+
+// XLSXの読み込み（シート名の指定がなければ先頭のシート）
+// 日付セルはシリアル値のまま読み込み、parseImportDate で変換する
+func readImportXLSX(r io.Reader, sheet string) ([][]string, error) {
+    f, err := excelize.OpenReader(r)
+    if err != nil {
+        return nil, err
+    }
+    defer f.Close()
+
+    if sheet == "" {
+        sheet = f.GetSheetName(0)
+    }
+    return f.GetRows(sheet, excelize.Options{RawCellValue: true})
+}
+
+// 見出しごとの列番号（同じ見出しが複数ある場合は最初の列）
+func importHeaderIndex(header []string) map[string]int {
+    index := make(map[string]int)
+    for i, h := range header {
+        h = strings.TrimSpace(strings.TrimPrefix(h, "\ufeff"))
+        if _, ok := index[h]; !ok {
+            index[h] = i
+        }
+    }
+    return index
+}
+
+// 見出し行から項目ごとの列番号を決定
+func resolveImportColumns(header []string, mapping map[string]string) (map[string]int, error) {
+    index := importHeaderIndex(header)
+
+    columns := make(map[string]int)
+    for field, aliases := range importColumnAliases {
+        if name, ok := mapping[field]; ok {
+            i, found := index[strings.TrimSpace(name)]
+            if !found {
+                return nil, fmt.Errorf("列「%s」がファイルにありません", name)
+            }
+            columns[field] = i
+            continue
+        }
+        for _, alias := range aliases {
+            if i, found := index[alias]; found {
+                columns[field] = i
+                break
+            }
+        }
+    }
+
+    if _, ok := columns["productId"]; !ok {
+        return nil, fmt.Errorf("製品IDの列が見つかりません")
+    }
+    return columns, nil
+}
+
+// 見出し行から追加項目の列番号を決定（列がない項目は取り込まない）
+func resolveImportAttributeColumns(
+    header []string, mapping map[string]string, defs []model.AttributeDefinition,
+) ([]importAttributeColumn, error) {
+    index := importHeaderIndex(header)
+    known := make(map[string]bool)
+
+    var columns []importAttributeColumn
+    for _, d := range defs {
+        known[d.Key] = true
+        if name, ok := mapping[importAttributePrefix+d.Key]; ok {
+            i, found := index[strings.TrimSpace(name)]
+            if !found {
+                return nil, fmt.Errorf("列「%s」がファイルにありません", name)
+            }
+            columns = append(columns, importAttributeColumn{d, i})
+            continue
+        }
+        for _, alias := range []string{d.Label, d.Key} {
+            if i, found := index[alias]; found {
+                columns = append(columns, importAttributeColumn{d, i})
+                break
+            }
+        }
+    }
+
+    for field := range mapping {
+        if key := strings.TrimPrefix(field, importAttributePrefix); key != field && !known[key] {
+            return nil, fmt.Errorf("列の対応付けに不明な追加項目があります: %s", key)
+        }
+    }
+    return columns, nil
+}
+
+// 1行分のデータを入庫製品に変換
+func parseImportRow(
+    category string, record []string, columns map[string]int, attributeColumns []importAttributeColumn,
+    typeIDs map[string]int, defaultTypeID int, row int,
+) (model.InboundProduct, []model.InboundValidationError) {
+    var errs []model.InboundValidationError
+    value := func(field string) string {
+        i, ok := columns[field]
+        if !ok || i >= len(record) {
+            return ""
+        }
+        return strings.TrimSpace(record[i])
+    }
+
+    p := model.InboundProduct{ProductID: value("productId")}
+    addErr := func(field string, message string) {
+        errs = append(errs, model.InboundValidationError{
+            Row: row, ProductID: p.ProductID, Field: field, Code: codeInvalid, Message: message,
+        })
+    }
+
+    // 製品タイプは名前またはIDで指定
+    p.TypeID = defaultTypeID
+    if t := value("type"); t != "" {
+        if id, ok := typeIDs[t]; ok {
+            p.TypeID = id
+        } else if id, err := strconv.Atoi(t); err == nil {
+            p.TypeID = id
+        } else {
+            addErr("typeId", fmt.Sprintf("製品タイプ「%s」が見つかりません", t))
+        }
+    }
+    if lot := value("lotNumber"); lot != "" {
+        p.LotNumber = &lot
+    }
+
+    switch category {
+    case "pc":
+        d := &model.InboundPCDetails{
+            ModelNumber:  value("modelNumber"),
+            SerialNumber: value("serialNumber"),
+        }
+        if v := value("purchaseDate"); v != "" {
+            date, err := parseImportDate(v)
+            if err != nil {
+                addErr("pcDetails.purchaseDate", fmt.Sprintf("購入日「%s」を日付として読み取れません", v))
+            }
+            d.PurchaseDate = date
+        }
+        if v := value("warrantyPeriod"); v != "" {
+            months, err := strconv.Atoi(strings.TrimSuffix(v, "ヶ月"))
+            if err != nil {
+                addErr("pcDetails.warrantyPeriod", fmt.Sprintf("保証期間「%s」を数値として読み取れません", v))
+            } else {
+                d.WarrantyPeriod = &months
+            }
+        }
+        p.PCDetails = d
+    case "vest":
+        d := &model.InboundVestDetails{
+            Type: value("vestType"),
+            Size: value("vestSize"),
+        }
+        if v := value("vestHasLogo"); v != "" {
+            hasLogo, err := parseImportBool(v)
+            if err != nil {
+                addErr("vestDetails.hasLogo", fmt.Sprintf("ロゴ有無「%s」を読み取れません", v))
+            } else {
+                d.HasLogo = &hasLogo
+            }
+        }
+        p.VestDetails = d
+    }
+
+    // 追加項目（日付は Excel のシリアル値などを変換し、それ以外は入庫データの検証で変換する）
+    for _, col := range attributeColumns {
+        if col.index >= len(record) {
+            continue
+        }
+        v := strings.TrimSpace(record[col.index])
+        if v == "" {
+            continue
+        }
+        if p.Attributes == nil {
+            p.Attributes = make(map[string]interface{})
+        }
+        if col.def.DataType == attributeTypeDate {
+            date, err := parseImportDate(v)
+            if err != nil {
+                addErr(importAttributePrefix+col.def.Key, fmt.Sprintf("%s「%s」を日付として読み取れません", col.def.Label, v))
+                continue
+            }
+            v = date.Format("2006-01-02")
+        }
+        p.Attributes[col.def.Key] = v
+    }
+
+    return p, errs
+}
+
+// 日付の変換（YYYY-MM-DD / YYYY/MM/DD / Excelのシリアル値）
+func parseImportDate(v string) (time.Time, error) {
+    for _, layout := range []string{"2006-01-02", "2006/01/02", "2006/1/2", "2006-1-2"} {
+        if t, err := time.Parse(layout, v); err == nil {
+            return t, nil
+        }
+    }
+    if serial, err := strconv.ParseFloat(v, 64); err == nil {
+        return excelize.ExcelDateToTime(serial, false)
+    }
+    return time.Time{}, fmt.Errorf("invalid date: %s", v)
+}
+
+// 真偽値の変換（あり/なし、有/無、true/false、1/0）
+func parseImportBool(v string) (bool, error) {
+    switch strings.ToLower(v) {
+    case "あり", "有", "true", "1", "yes", "○":
+        return true, nil
+    case "なし", "無", "false", "0", "no", "×":
+        return false, nil
+    }
+    return false, fmt.Errorf("invalid bool: %s", v)
+}
+
+// 空行の判定
+func isBlankRecord(record []string) bool {
+    for _, v := range record {
+        if strings.TrimSpace(v) != "" {
+            return false
+        }
+    }
+    return true
+}
@@ -0,0 +1,345 @@
+package handler
+
+import (
+    "database/sql"
+    "fmt"
+    "sort"
+    "strconv"
+    "strings"
+    "github.com/gin-gonic/gin"
+    "github.com/lib/pq"
+    "inventory-tracker/server/internal/model"
+)
+
+// 入庫データ検証のエラーコード
+const (
+    codeRequired             = "required"
+    codeInvalid              = "invalid"
+    codeTypeNotFound         = "type_not_found"
+    codeTypeCategoryMismatch = "type_category_mismatch"
+    codeTypeInactive         = "type_inactive"
+    codeDuplicateInRequest   = "duplicate_in_request"
+    codeAlreadyExists        = "already_exists"
+    codeDetailsRequired      = "details_required"
+    codeDetailsNotAllowed    = "details_not_allowed"
+    codeModelNumberNotFound  = "model_number_not_found"
+    codeModelNumberInactive  = "model_number_inactive"
+    codeStaffNotFound        = "staff_not_found"
+    codeAttributeNotFound    = "attribute_not_found"
+)
+
+// ベストの種類（vest_details.type の CHECK 制約と同じ値）
+var vestTypes = []string{"DS", "thin", "thick"}
+
+// ベストの種類の表示名
+var vestTypeLabels = map[string]string{
+    "DSタイプ":  "DS",
+    "薄型タイプ": "thin",
+    "厚型タイプ": "thick",
+}
+
+// ベストのサイズ
+var vestSizes = []string{"SSS", "SS", "S", "M", "L", "XL", "2XL", "3XL"}
+
+// ベストの種類を正規化（表示名で指定された場合はコードに変換）
+func normalizeVestType(vestType string) string {
+    vestType = strings.TrimSpace(vestType)
+    if code, ok := vestTypeLabels[vestType]; ok {
+        return code
+    }
+    return vestType
+}
+
+// 入庫データの検証
+// 登録前にリクエスト全体を検証し、すべての不備を行・項目ごとに返す
+func validateInboundRequest(tx *sql.Tx, category string, req *model.InboundRequest) ([]model.InboundValidationError, error) {
+    errs := []model.InboundValidationError{}
+    var add addValidationError = func(row int, productID string, field string, code string, message string) {
+        errs = append(errs, model.InboundValidationError{
+            Row:       row,
+            ProductID: productID,
+            Field:     field,
+            Code:      code,
+            Message:   message,
+        })
+    }
+
+    // リクエスト全体の項目
+    if len(req.Products) == 0 {
+        add(0, "", "products", codeRequired, "入庫する製品を指定してください")
+    }
+    if req.InboundDate.IsZero() {
+        add(0, "", "inboundDate", codeRequired, "入庫日を指定してください")
+    }
+    if req.StaffID == 0 {
+        add(0, "", "staffId", codeRequired, "担当者を指定してください")
+    } else {
+        var exists bool
+        if err := tx.QueryRow("SELECT EXISTS(SELECT 1 FROM staff WHERE id = $1 AND active)", req.StaffID).Scan(&exists); err != nil {
+            return nil, fmt.Errorf("担当者の確認エラー: %v", err)
+        }
+        if !exists {
+            add(0, "", "staffId", codeStaffNotFound, "指定された担当者が見つかりません")
+        }
+    }
+
+    // 照合用の値を収集
+    var productIDs, serialNumbers, modelNumbers []string
+    var typeIDs []int64
+    for i := range req.Products {
+        p := &req.Products[i]
+        p.ProductID = strings.TrimSpace(p.ProductID)
+        productIDs = append(productIDs, p.ProductID)
+        typeIDs = append(typeIDs, int64(p.TypeID))
+        if p.PCDetails != nil {
+            p.PCDetails.ModelNumber = strings.TrimSpace(p.PCDetails.ModelNumber)
+            p.PCDetails.SerialNumber = strings.TrimSpace(p.PCDetails.SerialNumber)
+            serialNumbers = append(serialNumbers, p.PCDetails.SerialNumber)
+            modelNumbers = append(modelNumbers, p.PCDetails.ModelNumber)
+        }
+    }
+
+    typeCategories, err := queryStringMap(tx, "SELECT id::text, category FROM product_types WHERE id = ANY($1)", pq.Array(typeIDs))
+    if err != nil {
+        return nil, fmt.Errorf("製品タイプの確認エラー: %v", err)
+    }
+    inactiveTypes, err := queryStringMap(tx, "SELECT id::text, name FROM product_types WHERE id = ANY($1) AND NOT active", pq.Array(typeIDs))
+    if err != nil {
+        return nil, fmt.Errorf("製品タイプの確認エラー: %v", err)
+    }
+    existingProducts, err := queryStringMap(tx, "SELECT product_id, product_id FROM products WHERE product_id = ANY($1)", pq.Array(productIDs))
+    if err != nil {
+        return nil, fmt.Errorf("製品IDの確認エラー: %v", err)
+    }
+    existingSerials, err := queryStringMap(tx, "SELECT serial_number, product_id FROM pc_details WHERE serial_number = ANY($1)", pq.Array(serialNumbers))
+    if err != nil {
+        return nil, fmt.Errorf("シリアル番号の確認エラー: %v", err)
+    }
+    // 型番ごとの既定の保証期間（未設定の場合は空文字）
+    knownModels, err := queryStringMap(tx, "SELECT model_number, COALESCE(default_warranty_period::text, '') FROM pc_model_numbers WHERE model_number = ANY($1)", pq.Array(modelNumbers))
+    if err != nil {
+        return nil, fmt.Errorf("PC型番の確認エラー: %v", err)
+    }
+    inactiveModels, err := queryStringMap(tx, "SELECT model_number, model_number FROM pc_model_numbers WHERE model_number = ANY($1) AND NOT active", pq.Array(modelNumbers))
+    if err != nil {
+        return nil, fmt.Errorf("PC型番の確認エラー: %v", err)
+    }
+    attributeDefs, err := loadAttributeDefinitions(tx, category, false)
+    if err != nil {
+        return nil, fmt.Errorf("追加項目の取得エラー: %v", err)
+    }
+
+    // 行ごとの検証
+    productRows := make(map[string]int)
+    serialRows := make(map[string]int)
+    for i := range req.Products {
+        p := &req.Products[i]
+        row := i + 1
+
+        if p.ProductID == "" {
+            add(row, "", "productId", codeRequired, "製品IDを入力してください")
+        } else if first, ok := productRows[p.ProductID]; ok {
+            add(row, p.ProductID, "productId", codeDuplicateInRequest, fmt.Sprintf("製品IDが%d行目と重複しています", first))
+        } else {
+            productRows[p.ProductID] = row
+            if _, ok := existingProducts[p.ProductID]; ok {
+                add(row, p.ProductID, "productId", codeAlreadyExists, "この製品IDは既に登録されています")
+            }
+        }
+
+        if p.TypeID == 0 {
+            add(row, p.ProductID, "typeId", codeRequired, "製品タイプを選択してください")
+        } else if typeCategory, ok := typeCategories[fmt.Sprint(p.TypeID)]; !ok {
+            add(row, p.ProductID, "typeId", codeTypeNotFound, "指定された製品タイプが見つかりません")
+        } else if typeCategory != category {
+            add(row, p.ProductID, "typeId", codeTypeCategoryMismatch, "製品タイプがカテゴリと一致しません")
+        } else if name, ok := inactiveTypes[fmt.Sprint(p.TypeID)]; ok {
+            add(row, p.ProductID, "typeId", codeTypeInactive, fmt.Sprintf("製品タイプ「%s」は無効化されています", name))
+        }
+
+        // カテゴリ別の詳細情報
+        if category == "pc" {
+            if p.PCDetails == nil {
+                add(row, p.ProductID, "pcDetails", codeDetailsRequired, "PC詳細情報を入力してください")
+            } else {
+                validatePCDetails(p.PCDetails, row, p.ProductID, add, knownModels, inactiveModels, existingSerials, serialRows)
+            }
+        } else if p.PCDetails != nil {
+            add(row, p.ProductID, "pcDetails", codeDetailsNotAllowed, "PC以外のカテゴリではPC詳細情報は入力できません")
+        }
+
+        if category == "vest" {
+            if p.VestDetails == nil {
+                add(row, p.ProductID, "vestDetails", codeDetailsRequired, "ベスト詳細情報を入力してください")
+            } else {
+                validateVestDetails(p.VestDetails, row, p.ProductID, add)
+            }
+        } else if p.VestDetails != nil {
+            add(row, p.ProductID, "vestDetails", codeDetailsNotAllowed, "ベスト以外のカテゴリではベスト詳細情報は入力できません")
+        }
+
+        // カテゴリ・製品タイプごとの追加項目
+        validateAttributes(p, attributeDefs, row, add)
+    }
+
+    return errs, nil
+}
+
+// 検証エラーの追加関数
+type addValidationError func(row int, productID string, field string, code string, message string)
+
+// PC詳細情報の検証
+// 保証期間が省略された場合は型番の既定の保証期間を設定する
+func validatePCDetails(
+    d *model.InboundPCDetails, row int, productID string, add addValidationError,
+    knownModels map[string]string, inactiveModels map[string]string,
+    existingSerials map[string]string, serialRows map[string]int,
+) {
+    if d.ModelNumber == "" {
+        add(row, productID, "pcDetails.modelNumber", codeRequired, "型番を入力してください")
+    } else if defaultWarranty, ok := knownModels[d.ModelNumber]; !ok {
+        add(row, productID, "pcDetails.modelNumber", codeModelNumberNotFound, "登録されていない型番です")
+    } else if _, ok := inactiveModels[d.ModelNumber]; ok {
+        add(row, productID, "pcDetails.modelNumber", codeModelNumberInactive, fmt.Sprintf("型番「%s」は無効化されています", d.ModelNumber))
+    } else if d.WarrantyPeriod == nil && defaultWarranty != "" {
+        if period, err := strconv.Atoi(defaultWarranty); err == nil {
+            d.WarrantyPeriod = &period
+        }
+    }
+
+    if d.SerialNumber == "" {
+        add(row, productID, "pcDetails.serialNumber", codeRequired, "シリアル番号を入力してください")
+    } else if first, ok := serialRows[d.SerialNumber]; ok {
+        add(row, productID, "pcDetails.serialNumber", codeDuplicateInRequest, fmt.Sprintf("シリアル番号が%d行目と重複しています", first))
+    } else {
+        serialRows[d.SerialNumber] = row
+        if owner, ok := existingSerials[d.SerialNumber]; ok {
+            add(row, productID, "pcDetails.serialNumber", codeAlreadyExists, fmt.Sprintf("このシリアル番号は製品ID %s で登録されています", owner))
+        }
+    }
+
+    if d.PurchaseDate.IsZero() {
+        add(row, productID, "pcDetails.purchaseDate", codeRequired, "購入日を入力してください")
+    }
+    if d.WarrantyPeriod != nil && *d.WarrantyPeriod < 0 {
+        add(row, productID, "pcDetails.warrantyPeriod", codeInvalid, "保証期間は0以上で入力してください")
+    }
+}
+
+// 追加項目の検証（値はデータ型ごとの形式に正規化する）
+// 製品タイプに適用されない項目・無効化した項目の値は受け付けない
+func validateAttributes(p *model.InboundProduct, defs []model.AttributeDefinition, row int, add addValidationError) {
+    applicable := make(map[string]model.AttributeDefinition)
+    for _, d := range defs {
+        if attributeApplies(d, p.TypeID) {
+            applicable[d.Key] = d
+        }
+    }
+
+    // エラーの順序が一定になるようキー順に検証する
+    keys := make([]string, 0, len(p.Attributes))
+    for key := range p.Attributes {
+        keys = append(keys, key)
+    }
+    sort.Strings(keys)
+
+    values := make(map[string]interface{})
+    invalid := make(map[string]bool)
+    for _, key := range keys {
+        v := p.Attributes[key]
+        field := "attributes." + key
+        d, ok := applicable[key]
+        if !ok {
+            add(row, p.ProductID, field, codeAttributeNotFound, fmt.Sprintf("追加項目 %s はこの製品タイプでは使用できません", key))
+            continue
+        }
+        value, err := normalizeAttributeValue(d, v)
+        if err != nil {
+            add(row, p.ProductID, field, codeInvalid, err.Error())
+            invalid[key] = true
+            continue
+        }
+        if value != nil {
+            values[key] = value
+        }
+    }
+
+    for _, d := range defs {
+        if _, ok := applicable[d.Key]; !ok || !d.Required || invalid[d.Key] {
+            continue
+        }
+        if _, ok := values[d.Key]; !ok {
+            add(row, p.ProductID, "attributes."+d.Key, codeRequired, fmt.Sprintf("%sを入力してください", d.Label))
+        }
+    }
+    p.Attributes = values
+}
+
+// ベスト詳細情報の検証（種類は表示名からコードに正規化する）
+func validateVestDetails(d *model.InboundVestDetails, row int, productID string, add addValidationError) {
+    d.Type = normalizeVestType(d.Type)
+    d.Size = strings.ToUpper(strings.TrimSpace(d.Size))
+    if d.Type == "" {
+        add(row, productID, "vestDetails.type", codeRequired, "ベストの種類を選択してください")
+    } else if !containsString(vestTypes, d.Type) {
+        add(row, productID, "vestDetails.type", codeInvalid, "ベストの種類が正しくありません")
+    }
+    if d.Size == "" {
+        add(row, productID, "vestDetails.size", codeRequired, "サイズを選択してください")
+    } else if !containsString(vestSizes, d.Size) {
+        add(row, productID, "vestDetails.size", codeInvalid, "サイズが正しくありません")
+    }
+    if d.HasLogo == nil {
+        add(row, productID, "vestDetails.hasLogo", codeRequired, "ロゴの有無を選択してください")
+    }
+}
+
+// 2列の検索結果をマップとして取得
+func queryStringMap(tx *sql.Tx, query string, args ...interface{}) (map[string]string, error) {
+    rows, err := tx.Query(query, args...)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    result := make(map[string]string)
+    for rows.Next() {
+        var key, value string
+        if err := rows.Scan(&key, &value); err != nil {
+            return nil, err
+        }
+        result[key] = value
+    }
+    return result, rows.Err()
+}
+
+// 一意制約違反の判定
+func isUniqueViolation(err error) bool {
+    pqErr, ok := err.(*pq.Error)
+    return ok && pqErr.Code == "23505"
+}
+
+// 外部キー制約違反の判定
+func isForeignKeyViolation(err error) bool {
+    pqErr, ok := err.(*pq.Error)
+    return ok && pqErr.Code == "23503"
+}
+
+// 文字列スライスに値が含まれるか判定
+func containsString(values []string, value string) bool {
+    for _, v := range values {
+        if v == value {
+            return true
+        }
+    }
+    return false
+}
+
+// ベスト詳細情報のレスポンス
+func vestDetailsJSON(vestType sql.NullString, size sql.NullString, hasLogo sql.NullBool) gin.H {
+    return gin.H{
+        "type":    vestType.String,
+        "size":    size.String,
+        "hasLogo": hasLogo.Bool,
+    }
+}
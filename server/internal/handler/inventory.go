@@ -7,8 +7,11 @@ import (
     "time"
     "log"
     "github.com/gin-gonic/gin"
+    "inventory-tracker/server/internal/audit"
+    "inventory-tracker/server/internal/auth"
     "inventory-tracker/server/internal/db"
     "inventory-tracker/server/internal/model"
+    "inventory-tracker/server/internal/numbering"
 )
 
 // 製品ID存在チェックハンドラー
@@ -33,26 +36,30 @@ func CheckProductID(c *gin.Context) {
         SELECT
             pt.name,
             p.status,
-            CASE
-                WHEN pt.category = 'pc' THEN 'PC'
-                WHEN pt.category = 'vest' THEN 'ベスト'
-                WHEN pt.category = 'heart_rate_monitor' THEN '心拍計'
-                ELSE pt.category
-            END as location
+            vd.type, vd.size, vd.has_logo,
+            cat.name as location
         FROM products p
         INNER JOIN product_types pt ON p.type_id = pt.id
+        INNER JOIN categories cat ON pt.category = cat.code
+        LEFT JOIN vest_details vd ON p.product_id = vd.product_id
         WHERE p.product_id = $1 AND pt.category = $2
     `
 
     var result struct {
-        TypeName string
-        Status   string
-        Location string
+        TypeName    string
+        Status      string
+        VestType    sql.NullString
+        VestSize    sql.NullString
+        VestHasLogo sql.NullBool
+        Location    string
     }
 
     err := db.DB.QueryRow(query, productID, category).Scan(
         &result.TypeName,
         &result.Status,
+        &result.VestType,
+        &result.VestSize,
+        &result.VestHasLogo,
         &result.Location,
     )
 
@@ -84,11 +91,15 @@ func CheckProductID(c *gin.Context) {
 
     if isInStock {
         message = fmt.Sprintf("その製品IDは%sの在庫に存在します", result.Location)
-        existingProduct = gin.H{
+        product := gin.H{
             "category":  category,
             "typeName": result.TypeName,
             "status":   result.Status,
         }
+        if result.VestType.Valid {
+            product["vestDetails"] = vestDetailsJSON(result.VestType, result.VestSize, result.VestHasLogo)
+        }
+        existingProduct = product
     }
 
     response := gin.H{
@@ -110,6 +121,9 @@ func HandleInbound(c *gin.Context) {
         return
     }
 
+    // 担当者はログイン中のセッションから取得
+    req.StaffID = auth.CurrentStaffID(c)
+
     // トランザクション開始
     tx, err := db.BeginTx()
     if err != nil {
@@ -118,57 +132,26 @@ func HandleInbound(c *gin.Context) {
     }
     defer tx.Rollback()
 
-    // 入庫番号の生成
-    today := time.Now().Format("20060102")
-    var lastSeq int
-    err = tx.QueryRow(`
-        SELECT COALESCE(MAX(CAST(SUBSTRING(inbound_number FROM 10) AS INTEGER)), 0)
-        FROM inbound_records
-        WHERE inbound_number LIKE $1
-    `, today+"-%").Scan(&lastSeq)
-    if err != nil && err != sql.ErrNoRows {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "入庫番号生成エラー"})
-        return
-    }
-
-    inboundNumber := fmt.Sprintf("%s-%04d", today, lastSeq+1)
-
-    // 製品の登録と入庫記録の作成
-    for _, p := range req.Products {
-        // 製品の登録
-        var productID string
-        err = tx.QueryRow(`
-            INSERT INTO products (product_id, type_id, lot_number, inbound_number, status)
-            VALUES ($1, $2, $3, $4, 'in_stock')
-            RETURNING product_id
-        `, p.ProductID, p.TypeID, p.LotNumber, inboundNumber).Scan(&productID)
-        if err != nil {
-            c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("製品登録エラー: %v", err)})
-            return
-        }
-
-        // PCの場合、詳細情報も登録
-        if category == "pc" && p.PCDetails != nil {
-            _, err = tx.Exec(`
-                INSERT INTO pc_details (product_id, model_number, serial_number, purchase_date, warranty_period)
-                VALUES ($1, $2, $3, $4, $5)
-            `, productID, p.PCDetails.ModelNumber, p.PCDetails.SerialNumber,
-                p.PCDetails.PurchaseDate, p.PCDetails.WarrantyPeriod)
-            if err != nil {
-                c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("PC詳細情報登録エラー: %v", err)})
-                return
-            }
-        }
+    // 登録前に全行を検証
+    validationErrors, err := validateInboundRequest(tx, category, &req)
+    if err != nil {
+        log.Printf("入庫データ検証エラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "入庫データの検証に失敗しました"})
+        return
+    }
+    if len(validationErrors) > 0 {
+        c.JSON(http.StatusUnprocessableEntity, gin.H{
+            "error":  fmt.Sprintf("入力内容に%d件の誤りがあります", len(validationErrors)),
+            "errors": validationErrors,
+        })
+        return
+    }
 
-        // 入庫記録の作成
-        _, err = tx.Exec(`
-            INSERT INTO inbound_records (product_id, staff_id, inbound_number, inbound_date)
-            VALUES ($1, $2, $3, $4)
-        `, productID, req.StaffID, inboundNumber, req.InboundDate)
-        if err != nil {
-            c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("入庫記録作成エラー: %v", err)})
-            return
-        }
+    // 入庫番号の生成と製品の登録
+    inboundNumber, err := createInbound(c, tx, category, &req)
+    if err != nil {
+        respondRequestError(c, err, "入庫処理に失敗しました")
+        return
     }
 
     // トランザクションのコミット
@@ -184,29 +167,22 @@ func HandleInbound(c *gin.Context) {
 }
 
 // 出庫処理ハンドラー
+// productIds が指定された場合はリストの製品を、それ以外は開始ID〜終了IDの範囲の製品を出庫する
 func HandleOutbound(c *gin.Context) {
     category := c.Param("category")
     log.Printf("出庫処理開始: カテゴリ = %s", category)
 
     // リクエストデータのバインド
-    var req struct {
-        ProductIDStart   string    `json:"productIdStart"`
-        ProductIDEnd     string    `json:"productIdEnd"`
-        StaffID         int       `json:"staffId"`
-        OutboundDate    string    `json:"outboundDate"`
-        CustomerNumber  *string   `json:"customerNumber,omitempty"`
-        CustomerName    *string   `json:"customerName,omitempty"`
-        PurchaserNumber *string   `json:"purchaserNumber,omitempty"`
-        PurchaserName   *string   `json:"purchaserName,omitempty"`
-        Notes           *string   `json:"notes,omitempty"`
-    }
-
+    var req model.OutboundRequest
     if err := c.ShouldBindJSON(&req); err != nil {
         log.Printf("リクエストデータバインドエラー: %v", err)
         c.JSON(http.StatusBadRequest, gin.H{"error": "無効なリクエストデータです"})
         return
     }
 
+    // 担当者はログイン中のセッションから取得
+    req.StaffID = auth.CurrentStaffID(c)
+
     log.Printf("リクエストデータ: %+v", req)
 
     // 日付文字列をtime.Time型に変換
@@ -226,140 +202,98 @@ func HandleOutbound(c *gin.Context) {
     }
     defer tx.Rollback()
 
-    // 出庫番号の生成
-    today := time.Now().Format("20060102")
-    var lastSeq int
-    err = tx.QueryRow(`
-        SELECT COALESCE(MAX(CAST(SUBSTRING(outbound_number FROM 10) AS INTEGER)), 0)
-        FROM outbound_records
-        WHERE outbound_number LIKE $1
-    `, today+"-%").Scan(&lastSeq)
-    if err != nil && err != sql.ErrNoRows {
-        log.Printf("出庫番号生成エラー: %v", err)
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "出庫番号生成エラー"})
+    // 出庫対象の選定
+    var targetIDs []string
+    var gaps []gin.H
+    if len(req.ProductIDs) > 0 {
+        targetIDs, err = selectOutboundByList(tx, category, req.ProductIDs)
+    } else {
+        targetIDs, gaps, err = selectOutboundByRange(tx, category, req.ProductIDStart, req.ProductIDEnd)
+    }
+    if err != nil {
+        respondRequestError(c, err, "出庫対象の取得に失敗しました")
         return
     }
 
-    outboundNumber := fmt.Sprintf("%s-%04d", today, lastSeq+1)
-    log.Printf("生成された出庫番号: %s", outboundNumber)
-
-    // 開始IDと終了IDの型番を取得して一致を確認
-    var startTypeID, endTypeID int
-    var startTypeName, endTypeName string
+    // 範囲内の欠番・出庫済みの製品は allowGaps が指定された場合のみ除外して出庫する
+    if len(gaps) > 0 && !req.AllowGaps {
+        c.JSON(http.StatusBadRequest, gin.H{
+            "error":    fmt.Sprintf("指定された範囲に出庫できない製品IDが%d件あります", len(gaps)),
+            "failures": gaps,
+        })
+        return
+    }
 
-    // 開始IDの型番取得
-    err = tx.QueryRow(`
-        SELECT p.type_id, pt.name
-        FROM products p
-        INNER JOIN product_types pt ON p.type_id = pt.id
-        WHERE p.product_id = $1
-        AND pt.category = $2
-        AND p.status = 'in_stock'
-    `, req.ProductIDStart, category).Scan(&startTypeID, &startTypeName)
+    // 顧客・購入者の確定
+    customerID, customerNumber, customerName, err := resolvePartner(
+        tx, customerMaster, req.CustomerID, req.CustomerNumber, req.CustomerName,
+    )
     if err != nil {
-        if err == sql.ErrNoRows {
-            log.Printf("開始製品ID %s は在庫に存在しないか、既に出庫済みです", req.ProductIDStart)
-            c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("開始製品ID %s は在庫に存在しないか、既に出庫済みです", req.ProductIDStart)})
-            return
-        }
-        log.Printf("開始ID型番取得エラー: %v", err)
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "開始IDの型番取得に失敗しました"})
+        respondRequestError(c, err, "顧客の取得に失敗しました")
         return
     }
-
-    // 終了IDの型番取得
-    err = tx.QueryRow(`
-        SELECT p.type_id, pt.name
-        FROM products p
-        INNER JOIN product_types pt ON p.type_id = pt.id
-        WHERE p.product_id = $1
-        AND pt.category = $2
-        AND p.status = 'in_stock'
-    `, req.ProductIDEnd, category).Scan(&endTypeID, &endTypeName)
+    purchaserID, purchaserNumber, purchaserName, err := resolvePartner(
+        tx, purchaserMaster, req.PurchaserID, req.PurchaserNumber, req.PurchaserName,
+    )
     if err != nil {
-        if err == sql.ErrNoRows {
-            log.Printf("終了製品ID %s は在庫に存在しないか、既に出庫済みです", req.ProductIDEnd)
-            c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("終了製品ID %s は在庫に存在しないか、既に出庫済みです", req.ProductIDEnd)})
-            return
-        }
-        log.Printf("終了ID型番取得エラー: %v", err)
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "終了IDの型番取得に失敗しました"})
+        respondRequestError(c, err, "購入者の取得に失敗しました")
         return
     }
 
-    // 型番の一致を確認
-    if startTypeID != endTypeID {
-        log.Printf("型番が一致しません: 開始ID=%s（%s）, 終了ID=%s（%s）",
-            req.ProductIDStart, startTypeName, req.ProductIDEnd, endTypeName)
-        c.JSON(http.StatusBadRequest, gin.H{
-            "error": fmt.Sprintf("開始IDと終了IDの型番が一致しません（開始ID: %s, 終了ID: %s）", startTypeName, endTypeName),
-        })
+    // 出庫番号の生成
+    outboundNumber, err := numbering.Next(tx, numbering.Outbound, category)
+    if err != nil {
+        log.Printf("出庫番号生成エラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "出庫番号生成エラー"})
         return
     }
+    log.Printf("生成された出庫番号: %s", outboundNumber)
 
-    // 対象製品の取得と更新をメインのトランザクション内で実行
-    var processedProducts []string
-    rows, err := tx.Query(`
-        UPDATE products p
-        SET status = 'out_of_stock'
-        FROM product_types pt
-        WHERE p.type_id = pt.id
-        AND p.product_id >= $1
-        AND p.product_id <= $2
-        AND p.status = 'in_stock'
-        AND pt.category = $3
-        AND pt.id = $4
-        RETURNING p.product_id
-    `, req.ProductIDStart, req.ProductIDEnd, category, startTypeID)
+    // 対象製品の更新をメインのトランザクション内で実行
+    processedProducts, err := markProductsOutOfStock(tx, targetIDs)
     if err != nil {
-        log.Printf("製品更新エラー: %v", err)
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "製品の更新に失敗しました"})
+        respondRequestError(c, err, "製品の更新に失敗しました")
         return
     }
-    defer rows.Close()
 
-    // 更新された製品IDを収集
-    for rows.Next() {
-        var productID string
-        if err := rows.Scan(&productID); err != nil {
-            log.Printf("製品データ読み取りエラー: %v", err)
-            c.JSON(http.StatusInternalServerError, gin.H{"error": "製品データの読み取りに失敗しました"})
-            return
-        }
-        processedProducts = append(processedProducts, productID)
+    // 出庫記録の一括作成
+    stmt, err := tx.Prepare(`
+        INSERT INTO outbound_records (
+            product_id, staff_id, outbound_number, outbound_date,
+            customer_id, customer_number, customer_name,
+            purchaser_id, purchaser_number, purchaser_name, notes
+        ) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+    `)
+    if err != nil {
+        log.Printf("ステートメント準備エラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "出庫記録の準備に失敗しました"})
+        return
     }
+    defer stmt.Close()
 
-    // 出庫記録の一括作成
-    if len(processedProducts) > 0 {
-        stmt, err := tx.Prepare(`
-            INSERT INTO outbound_records (
-                product_id, staff_id, outbound_number, outbound_date,
-                customer_number, customer_name, purchaser_number, purchaser_name, notes
-            ) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
-        `)
+    for _, productID := range processedProducts {
+        _, err = stmt.Exec(
+            productID, req.StaffID, outboundNumber, outboundDate,
+            customerID, customerNumber, customerName,
+            purchaserID, purchaserNumber, purchaserName, req.Notes,
+        )
         if err != nil {
-            log.Printf("ステートメント準備エラー: %v", err)
-            c.JSON(http.StatusInternalServerError, gin.H{"error": "出庫記録の準備に失敗しました"})
+            log.Printf("出庫記録作成エラー: %v", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("出庫記録の作成に失敗しました: %v", err)})
             return
         }
-        defer stmt.Close()
-
-        for _, productID := range processedProducts {
-            _, err = stmt.Exec(
-                productID, req.StaffID, outboundNumber, outboundDate,
-                req.CustomerNumber, req.CustomerName, req.PurchaserNumber, req.PurchaserName, req.Notes,
-            )
-            if err != nil {
-                log.Printf("出庫記録作成エラー: %v", err)
-                c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("出庫記録の作成に失敗しました: %v", err)})
-                return
-            }
-        }
     }
 
-    if len(processedProducts) == 0 {
-        log.Printf("対象製品が見つかりません: %s から %s", req.ProductIDStart, req.ProductIDEnd)
-        c.JSON(http.StatusNotFound, gin.H{"error": "指定された範囲の製品が見つかりません"})
+    after, ok := auditSnapshot(c, tx, outboundAuditQuery, outboundNumber, category)
+    if !ok {
+        return
+    }
+    if !recordAudit(c, tx, audit.Entry{
+        Action:     audit.ActionCreate,
+        EntityType: "outbound",
+        EntityID:   outboundNumber,
+        After:      after,
+    }) {
         return
     }
 
@@ -376,74 +310,181 @@ func HandleOutbound(c *gin.Context) {
         "outboundNumber": outboundNumber,
         "processedCount": len(processedProducts),
         "products": processedProducts,
+        "gaps": gaps,
     })
 }
 
+// 在庫一覧のクエリ定義
+// 入庫担当者・最新の返品は製品ごとに1件のみ LATERAL で取得する
+var inventoryListSpec = listQuerySpec{
+    selectColumns: `
+        p.id, p.product_id, p.lot_number, p.inbound_number,
+        p.status, p.created_at, p.updated_at,
+        pt.id, pt.category, pt.name,
+        pc.model_number, pc.serial_number, pc.purchase_date, pc.warranty_period,
+        ` + pcWarrantyEndColumn + `,
+        vd.type, vd.size, vd.has_logo, p.attributes,
+        s.id, s.name, ir.inbound_date,
+        rr.return_number, rr.return_date, rr.reason`,
+    from: `products p
+        INNER JOIN product_types pt ON p.type_id = pt.id
+        LEFT JOIN pc_details pc ON p.product_id = pc.product_id
+        LEFT JOIN vest_details vd ON p.product_id = vd.product_id
+        LEFT JOIN LATERAL (
+            SELECT staff_id, inbound_date
+            FROM inbound_records
+            WHERE product_id = p.product_id
+            ORDER BY id DESC
+            LIMIT 1
+        ) ir ON true
+        LEFT JOIN staff s ON ir.staff_id = s.id
+        LEFT JOIN LATERAL (
+            SELECT return_number, return_date, reason
+            FROM return_records
+            WHERE product_id = p.product_id
+            ORDER BY id DESC
+            LIMIT 1
+        ) rr ON true`,
+    idColumn: "p.id",
+    sorts: map[string]sortColumn{
+        "createdAt":     {"p.created_at", "timestamp"},
+        "productId":     {"p.product_id", "text"},
+        "lotNumber":     {"COALESCE(p.lot_number, '')", "text"},
+        "inboundNumber": {"p.inbound_number", "text"},
+        "status":        {"p.status", "text"},
+        "typeName":      {"pt.name", "text"},
+    },
+    defaultSort: "-createdAt",
+    filters: listFilterColumns{
+        Status:      "p.status",
+        TypeID:      "pt.id",
+        LotNumber:   "p.lot_number",
+        StaffID:     "s.id",
+        Date:        "ir.inbound_date",
+        ModelNumber: "pc.model_number",
+        VestType:    "vd.type",
+        VestSize:    "vd.size",
+        VestHasLogo: "vd.has_logo",
+    },
+}
+
 // 在庫一覧取得ハンドラー
+// limit / cursor によるページングと絞り込み・並び替えに対応し、{items, totalCount, nextCursor, hasMore} を返す
 func GetInventory(c *gin.Context) {
     category := c.Param("category")
+    format, ok := exportFormat(c)
+    if !ok {
+        return
+    }
 
-    query := `
-        WITH latest_products AS (
-            SELECT DISTINCT ON (p.product_id) 
-                p.id, p.product_id, p.type_id, p.lot_number, p.inbound_number,
-                p.status, p.created_at, p.updated_at,
-                pt.id as type_id, pt.category, pt.name as type_name,
-                pc.model_number, pc.serial_number, pc.purchase_date, pc.warranty_period,
-                s.id as staff_id, s.name as staff_name
-            FROM products p
-            INNER JOIN product_types pt ON p.type_id = pt.id
-            LEFT JOIN pc_details pc ON p.product_id = pc.product_id
-            LEFT JOIN inbound_records ir ON p.product_id = ir.product_id
-            LEFT JOIN staff s ON ir.staff_id = s.id
-            WHERE pt.category = $1
-            ORDER BY p.product_id, p.created_at DESC
-        )
-        SELECT * FROM latest_products
-        ORDER BY created_at DESC
-    `
+    q, err := newListQuery(c, inventoryListSpec)
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    q.where("pt.category = " + q.arg(category))
+
+    // 総件数（エクスポート時は不要）
+    var totalCount int
+    if format == "" {
+        query, args := q.countSQL()
+        if err := db.DB.QueryRow(query, args...).Scan(&totalCount); err != nil {
+            c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("在庫データの取得に失敗しました: %v", err)})
+            return
+        }
+    }
 
-    rows, err := db.DB.Query(query, category)
+    // エクスポート時はページングせず絞り込み結果をすべて出力する
+    query, args := q.selectSQL(format == "")
+    rows, err := db.DB.Query(query, args...)
     if err != nil {
         c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("在庫データの取得に失敗しました: %v", err)})
         return
     }
     defer rows.Close()
 
+    // エクスポート指定時は JSON の代わりに表形式で書き出す
+    var export exportWriter
+    var attributeDefs []model.AttributeDefinition
+    if format != "" {
+        if attributeDefs, err = loadAttributeDefinitions(db.DB, category, true); err != nil {
+            log.Printf("追加項目取得エラー: %v", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "追加項目の取得に失敗しました"})
+            return
+        }
+        headers := []string{"製品ID", "タイプ", "ロット番号", "入庫番号", "ステータス"}
+        headers = append(headers, detailExportHeaders(category)...)
+        headers = append(headers, attributeExportHeaders(attributeDefs)...)
+        headers = append(headers, "担当者", "登録日", "最終返品番号", "最終返品日")
+        if export, err = newExportWriter(c, format, "inventory_"+category, headers); err != nil {
+            log.Printf("エクスポート開始エラー: %v", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "エクスポートの開始に失敗しました"})
+            return
+        }
+    }
+
     var products []gin.H
+    var cursors []listCursor
     for rows.Next() {
         var p struct {
-            ID            int
-            ProductID     string
-            TypeID       int
-            LotNumber    sql.NullString
-            InboundNumber string
-            Status       string
-            CreatedAt    time.Time
-            UpdatedAt    time.Time
-            TypeID2      int
-            Category     string
-            TypeName     string
-            ModelNumber  sql.NullString
-            SerialNumber sql.NullString
-            PurchaseDate sql.NullTime
+            ID             int
+            ProductID      string
+            LotNumber      sql.NullString
+            InboundNumber  string
+            Status         string
+            CreatedAt      time.Time
+            UpdatedAt      time.Time
+            TypeID         int
+            Category       string
+            TypeName       string
+            ModelNumber    sql.NullString
+            SerialNumber   sql.NullString
+            PurchaseDate   sql.NullTime
             WarrantyPeriod sql.NullInt32
-            StaffID      sql.NullInt32
-            StaffName    sql.NullString
+            WarrantyEnd    sql.NullTime
+            VestType       sql.NullString
+            VestSize       sql.NullString
+            VestHasLogo    sql.NullBool
+            Attributes     []byte
+            StaffID        sql.NullInt32
+            StaffName      sql.NullString
+            InboundDate    sql.NullTime
+            ReturnNumber   sql.NullString
+            ReturnDate     sql.NullTime
+            ReturnReason   sql.NullString
+            SortValue      string
         }
 
         err := rows.Scan(
-            &p.ID, &p.ProductID, &p.TypeID, &p.LotNumber, &p.InboundNumber,
+            &p.ID, &p.ProductID, &p.LotNumber, &p.InboundNumber,
             &p.Status, &p.CreatedAt, &p.UpdatedAt,
-            &p.TypeID2, &p.Category, &p.TypeName,
-            &p.ModelNumber, &p.SerialNumber, &p.PurchaseDate, &p.WarrantyPeriod,
-            &p.StaffID, &p.StaffName,
+            &p.TypeID, &p.Category, &p.TypeName,
+            &p.ModelNumber, &p.SerialNumber, &p.PurchaseDate, &p.WarrantyPeriod, &p.WarrantyEnd,
+            &p.VestType, &p.VestSize, &p.VestHasLogo, &p.Attributes,
+            &p.StaffID, &p.StaffName, &p.InboundDate,
+            &p.ReturnNumber, &p.ReturnDate, &p.ReturnReason,
+            &p.SortValue,
         )
         if err != nil {
             c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("データの読み取りに失敗しました: %v", err)})
             return
         }
 
+        if export != nil {
+            values := []interface{}{p.ProductID, p.TypeName, p.LotNumber.String, p.InboundNumber, statusLabel(p.Status)}
+            values = append(values, detailExportValues(
+                category, p.ModelNumber, p.SerialNumber, p.PurchaseDate, p.WarrantyPeriod,
+                p.VestType, p.VestSize, p.VestHasLogo,
+            )...)
+            values = append(values, attributeExportValues(attributeDefs, p.Attributes)...)
+            values = append(values, p.StaffName.String, p.CreatedAt, p.ReturnNumber.String, nullableTime(p.ReturnDate))
+            if err := export.WriteRow(values); err != nil {
+                log.Printf("エクスポート書き込みエラー: %v", err)
+                return
+            }
+            continue
+        }
+
         product := gin.H{
             "id":            p.ID,
             "productId":     p.ProductID,
@@ -457,6 +498,11 @@ func GetInventory(c *gin.Context) {
                 "category": p.Category,
                 "name":     p.TypeName,
             },
+            "attributes": attributesJSON(p.Attributes),
+        }
+
+        if p.InboundDate.Valid {
+            product["inboundDate"] = p.InboundDate.Time
         }
 
         if p.StaffID.Valid {
@@ -466,136 +512,346 @@ func GetInventory(c *gin.Context) {
             }
         }
 
+        // 返品により在庫に戻った製品は最新の返品情報を付与
+        if p.ReturnNumber.Valid {
+            product["lastReturn"] = gin.H{
+                "returnNumber": p.ReturnNumber.String,
+                "returnDate":   p.ReturnDate.Time,
+                "reason":       p.ReturnReason.String,
+            }
+        }
+
+        if category == "vest" && p.VestType.Valid {
+            product["vestDetails"] = vestDetailsJSON(p.VestType, p.VestSize, p.VestHasLogo)
+        }
+
         if category == "pc" && p.ModelNumber.Valid {
             product["pcDetails"] = gin.H{
-                "modelNumber":    p.ModelNumber.String,
-                "serialNumber":   p.SerialNumber.String,
-                "purchaseDate":   p.PurchaseDate.Time,
-                "warrantyPeriod": p.WarrantyPeriod.Int32,
+                "modelNumber":     p.ModelNumber.String,
+                "serialNumber":    p.SerialNumber.String,
+                "purchaseDate":    p.PurchaseDate.Time,
+                "warrantyPeriod":  p.WarrantyPeriod.Int32,
+                "warrantyEndDate": nullableTime(p.WarrantyEnd),
             }
         }
 
         products = append(products, product)
+        cursors = append(cursors, listCursor{Value: p.SortValue, ID: p.ID})
     }
 
-    c.JSON(http.StatusOK, products)
-}
+    if export != nil {
+        if err := export.Close(); err != nil {
+            log.Printf("エクスポート書き込みエラー: %v", err)
+        }
+        return
+    }
 
-// 入庫履歴取得ハンドラー
-func GetInboundHistory(c *gin.Context) {
-    category := c.Param("category")
+    c.JSON(http.StatusOK, q.response(products, cursors, totalCount))
+}
 
-    query := `
-        SELECT
-            ir.id, ir.inbound_number, ir.inbound_date,
-            p.product_id, p.lot_number,
-            pt.id as type_id, pt.name as type_name,
-            s.id as staff_id, s.name as staff_name,
-            pc.model_number, pc.serial_number
-        FROM inbound_records ir
+// 入庫履歴のクエリ定義
+var inboundHistoryListSpec = listQuerySpec{
+    selectColumns: `
+        ir.id, ir.inbound_number, ir.inbound_date,
+        p.product_id, p.lot_number, p.status,
+        pt.id, pt.name,
+        s.id, s.name,
+        pc.model_number, pc.serial_number, pc.purchase_date, pc.warranty_period,
+        vd.type, vd.size, vd.has_logo, p.attributes`,
+    from: `inbound_records ir
         INNER JOIN products p ON ir.product_id = p.product_id
         INNER JOIN product_types pt ON p.type_id = pt.id
         INNER JOIN staff s ON ir.staff_id = s.id
         LEFT JOIN pc_details pc ON p.product_id = pc.product_id
-        WHERE pt.category = $1
-        ORDER BY ir.inbound_date DESC, ir.id DESC
-    `
+        LEFT JOIN vest_details vd ON p.product_id = vd.product_id`,
+    idColumn: "ir.id",
+    sorts: map[string]sortColumn{
+        "inboundDate":   {"ir.inbound_date", "timestamp"},
+        "inboundNumber": {"ir.inbound_number", "text"},
+        "productId":     {"p.product_id", "text"},
+        "lotNumber":     {"COALESCE(p.lot_number, '')", "text"},
+        "typeName":      {"pt.name", "text"},
+        "staffName":     {"s.name", "text"},
+    },
+    defaultSort: "-inboundDate",
+    filters: listFilterColumns{
+        Status:      "p.status",
+        TypeID:      "pt.id",
+        LotNumber:   "p.lot_number",
+        StaffID:     "s.id",
+        Date:        "ir.inbound_date",
+        ModelNumber: "pc.model_number",
+        VestType:    "vd.type",
+        VestSize:    "vd.size",
+        VestHasLogo: "vd.has_logo",
+    },
+}
 
-    rows, err := db.DB.Query(query, category)
+// 入庫履歴取得ハンドラー
+func GetInboundHistory(c *gin.Context) {
+    category := c.Param("category")
+    format, ok := exportFormat(c)
+    if !ok {
+        return
+    }
+
+    q, err := newListQuery(c, inboundHistoryListSpec)
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    q.where("pt.category = " + q.arg(category))
+
+    var totalCount int
+    if format == "" {
+        query, args := q.countSQL()
+        if err := db.DB.QueryRow(query, args...).Scan(&totalCount); err != nil {
+            c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("入庫履歴の取得に失敗しました: %v", err)})
+            return
+        }
+    }
+
+    query, args := q.selectSQL(format == "")
+    rows, err := db.DB.Query(query, args...)
     if err != nil {
         c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("入庫履歴の取得に失敗しました: %v", err)})
         return
     }
     defer rows.Close()
 
+    // エクスポート指定時は JSON の代わりに表形式で書き出す
+    var export exportWriter
+    var attributeDefs []model.AttributeDefinition
+    if format != "" {
+        if attributeDefs, err = loadAttributeDefinitions(db.DB, category, true); err != nil {
+            log.Printf("追加項目取得エラー: %v", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "追加項目の取得に失敗しました"})
+            return
+        }
+        headers := []string{"入庫番号", "入庫日", "製品ID", "タイプ", "ロット番号"}
+        headers = append(headers, detailExportHeaders(category)...)
+        headers = append(headers, attributeExportHeaders(attributeDefs)...)
+        headers = append(headers, "担当者")
+        if export, err = newExportWriter(c, format, "inbound_history_"+category, headers); err != nil {
+            log.Printf("エクスポート開始エラー: %v", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "エクスポートの開始に失敗しました"})
+            return
+        }
+    }
+
     var history []gin.H
+    var cursors []listCursor
     for rows.Next() {
         var h struct {
-            ID            int
-            InboundNumber string
-            InboundDate   time.Time
-            ProductID     string
-            LotNumber     sql.NullString
-            TypeID       int
-            TypeName     string
-            StaffID      int
-            StaffName    string
-            ModelNumber  sql.NullString
-            SerialNumber sql.NullString
+            ID             int
+            InboundNumber  string
+            InboundDate    time.Time
+            ProductID      string
+            LotNumber      sql.NullString
+            Status         string
+            TypeID         int
+            TypeName       string
+            StaffID        int
+            StaffName      string
+            ModelNumber    sql.NullString
+            SerialNumber   sql.NullString
+            PurchaseDate   sql.NullTime
+            WarrantyPeriod sql.NullInt32
+            VestType       sql.NullString
+            VestSize       sql.NullString
+            VestHasLogo    sql.NullBool
+            Attributes     []byte
+            SortValue      string
         }
 
         err := rows.Scan(
             &h.ID, &h.InboundNumber, &h.InboundDate,
-            &h.ProductID, &h.LotNumber,
+            &h.ProductID, &h.LotNumber, &h.Status,
             &h.TypeID, &h.TypeName,
             &h.StaffID, &h.StaffName,
-            &h.ModelNumber, &h.SerialNumber,
+            &h.ModelNumber, &h.SerialNumber, &h.PurchaseDate, &h.WarrantyPeriod,
+            &h.VestType, &h.VestSize, &h.VestHasLogo, &h.Attributes,
+            &h.SortValue,
         )
         if err != nil {
             c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("データの読み取りに失敗しました: %v", err)})
             return
         }
 
+        if export != nil {
+            values := []interface{}{h.InboundNumber, h.InboundDate, h.ProductID, h.TypeName, h.LotNumber.String}
+            values = append(values, detailExportValues(
+                category, h.ModelNumber, h.SerialNumber, h.PurchaseDate, h.WarrantyPeriod,
+                h.VestType, h.VestSize, h.VestHasLogo,
+            )...)
+            values = append(values, attributeExportValues(attributeDefs, h.Attributes)...)
+            values = append(values, h.StaffName)
+            if err := export.WriteRow(values); err != nil {
+                log.Printf("エクスポート書き込みエラー: %v", err)
+                return
+            }
+            continue
+        }
+
         record := gin.H{
             "id":            h.ID,
             "inboundNumber": h.InboundNumber,
             "inboundDate":   h.InboundDate,
             "productId":     h.ProductID,
             "lotNumber":     h.LotNumber.String,
+            "status":        h.Status,
             "type": gin.H{
                 "id":   h.TypeID,
                 "name": h.TypeName,
             },
+            "attributes": attributesJSON(h.Attributes),
             "staff": gin.H{
                 "id":   h.StaffID,
                 "name": h.StaffName,
             },
         }
 
+        if category == "vest" && h.VestType.Valid {
+            record["vestDetails"] = vestDetailsJSON(h.VestType, h.VestSize, h.VestHasLogo)
+        }
+
         if category == "pc" && h.ModelNumber.Valid {
             record["pcDetails"] = gin.H{
-                "modelNumber":  h.ModelNumber.String,
-                "serialNumber": h.SerialNumber.String,
+                "modelNumber":    h.ModelNumber.String,
+                "serialNumber":   h.SerialNumber.String,
+                "purchaseDate":   h.PurchaseDate.Time,
+                "warrantyPeriod": h.WarrantyPeriod.Int32,
             }
         }
 
         history = append(history, record)
+        cursors = append(cursors, listCursor{Value: h.SortValue, ID: h.ID})
+    }
+
+    if export != nil {
+        if err := export.Close(); err != nil {
+            log.Printf("エクスポート書き込みエラー: %v", err)
+        }
+        return
     }
 
-    c.JSON(http.StatusOK, history)
+    c.JSON(http.StatusOK, q.response(history, cursors, totalCount))
+}
+
+// 出庫記録の状態（shipped: 出庫中, returned: 返品済み, voided: 取消済み）
+const outboundStatusColumn = `CASE
+    WHEN obr.voided_at IS NOT NULL THEN 'voided'
+    WHEN rr.id IS NOT NULL THEN 'returned'
+    ELSE 'shipped' END`
+
+// 出庫履歴のクエリ定義
+var outboundHistoryListSpec = listQuerySpec{
+    selectColumns: `
+        obr.id, obr.outbound_number, obr.outbound_date,
+        p.product_id, p.lot_number,
+        pt.id, pt.name,
+        s.id, s.name,
+        obr.customer_id, obr.customer_number, obr.customer_name,
+        obr.purchaser_id, obr.purchaser_number, obr.purchaser_name,
+        obr.notes,
+        pc.model_number, pc.serial_number, pc.purchase_date, pc.warranty_period,
+        vd.type, vd.size, vd.has_logo, p.attributes,
+        rr.return_number, rr.return_date, rr.reason,
+        obr.voided_at, vs.id, vs.name, obr.void_reason,
+        ` + outboundStatusColumn,
+    from: `outbound_records obr
+        INNER JOIN products p ON obr.product_id = p.product_id
+        INNER JOIN product_types pt ON p.type_id = pt.id
+        INNER JOIN staff s ON obr.staff_id = s.id
+        LEFT JOIN pc_details pc ON p.product_id = pc.product_id
+        LEFT JOIN vest_details vd ON p.product_id = vd.product_id
+        LEFT JOIN return_records rr ON rr.outbound_record_id = obr.id
+        LEFT JOIN staff vs ON obr.voided_by = vs.id`,
+    idColumn: "obr.id",
+    sorts: map[string]sortColumn{
+        "outboundDate":   {"obr.outbound_date", "timestamp"},
+        "outboundNumber": {"obr.outbound_number", "text"},
+        "productId":      {"p.product_id", "text"},
+        "lotNumber":      {"COALESCE(p.lot_number, '')", "text"},
+        "customerName":   {"COALESCE(obr.customer_name, '')", "text"},
+        "staffName":      {"s.name", "text"},
+    },
+    defaultSort: "-outboundDate",
+    filters: listFilterColumns{
+        Status:    outboundStatusColumn,
+        TypeID:    "pt.id",
+        LotNumber: "p.lot_number",
+        StaffID:   "s.id",
+        Date:      "obr.outbound_date",
+        Customer: []string{
+            "obr.customer_number", "obr.customer_name",
+            "obr.purchaser_number", "obr.purchaser_name",
+        },
+        ModelNumber: "pc.model_number",
+        VestType:    "vd.type",
+        VestSize:    "vd.size",
+        VestHasLogo: "vd.has_logo",
+    },
 }
 
 // 出庫履歴取得ハンドラー
+// status には shipped / returned / voided を指定できる
 func GetOutboundHistory(c *gin.Context) {
     category := c.Param("category")
+    format, ok := exportFormat(c)
+    if !ok {
+        return
+    }
 
-    query := `
-        SELECT
-            or.id, or.outbound_number, or.outbound_date,
-            p.product_id, p.lot_number,
-            pt.id as type_id, pt.name as type_name,
-            s.id as staff_id, s.name as staff_name,
-            or.customer_number, or.customer_name,
-            or.purchaser_number, or.purchaser_name,
-            or.notes,
-            pc.model_number, pc.serial_number
-        FROM outbound_records or
-        INNER JOIN products p ON or.product_id = p.product_id
-        INNER JOIN product_types pt ON p.type_id = pt.id
-        INNER JOIN staff s ON or.staff_id = s.id
-        LEFT JOIN pc_details pc ON p.product_id = pc.product_id
-        WHERE pt.category = $1
-        ORDER BY or.outbound_date DESC, or.id DESC
-    `
+    q, err := newListQuery(c, outboundHistoryListSpec)
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    q.where("pt.category = " + q.arg(category))
 
-    rows, err := db.DB.Query(query, category)
+    var totalCount int
+    if format == "" {
+        query, args := q.countSQL()
+        if err := db.DB.QueryRow(query, args...).Scan(&totalCount); err != nil {
+            c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("出庫履歴の取得に失敗しました: %v", err)})
+            return
+        }
+    }
+
+    query, args := q.selectSQL(format == "")
+    rows, err := db.DB.Query(query, args...)
     if err != nil {
         c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("出庫履歴の取得に失敗しました: %v", err)})
         return
     }
     defer rows.Close()
 
+    // エクスポート指定時は JSON の代わりに表形式で書き出す
+    var export exportWriter
+    var attributeDefs []model.AttributeDefinition
+    if format != "" {
+        if attributeDefs, err = loadAttributeDefinitions(db.DB, category, true); err != nil {
+            log.Printf("追加項目取得エラー: %v", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "追加項目の取得に失敗しました"})
+            return
+        }
+        headers := []string{"出庫番号", "出庫日", "製品ID", "タイプ", "ロット番号"}
+        headers = append(headers, detailExportHeaders(category)...)
+        headers = append(headers, attributeExportHeaders(attributeDefs)...)
+        headers = append(headers,
+            "顧客番号", "顧客名", "購入者番号", "購入者名", "備考", "担当者",
+            "返品番号", "返品日", "取消日", "取消理由",
+        )
+        if export, err = newExportWriter(c, format, "outbound_history_"+category, headers); err != nil {
+            log.Printf("エクスポート開始エラー: %v", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "エクスポートの開始に失敗しました"})
+            return
+        }
+    }
+
     var history []gin.H
+    var cursors []listCursor
     for rows.Next() {
         var h struct {
             ID              int
@@ -603,17 +859,34 @@ func GetOutboundHistory(c *gin.Context) {
             OutboundDate    time.Time
             ProductID       string
             LotNumber       sql.NullString
-            TypeID         int
-            TypeName       string
-            StaffID        int
-            StaffName      string
-            CustomerNumber sql.NullString
-            CustomerName   sql.NullString
+            TypeID          int
+            TypeName        string
+            StaffID         int
+            StaffName       string
+            CustomerID      sql.NullInt32
+            CustomerNumber  sql.NullString
+            CustomerName    sql.NullString
+            PurchaserID     sql.NullInt32
             PurchaserNumber sql.NullString
             PurchaserName   sql.NullString
-            Notes          sql.NullString
-            ModelNumber    sql.NullString
-            SerialNumber   sql.NullString
+            Notes           sql.NullString
+            ModelNumber     sql.NullString
+            SerialNumber    sql.NullString
+            PurchaseDate    sql.NullTime
+            WarrantyPeriod  sql.NullInt32
+            VestType        sql.NullString
+            VestSize        sql.NullString
+            VestHasLogo     sql.NullBool
+            Attributes      []byte
+            ReturnNumber    sql.NullString
+            ReturnDate      sql.NullTime
+            ReturnReason    sql.NullString
+            VoidedAt        sql.NullTime
+            VoidedByID      sql.NullInt32
+            VoidedByName    sql.NullString
+            VoidReason      sql.NullString
+            Status          string
+            SortValue       string
         }
 
         err := rows.Scan(
@@ -621,16 +894,42 @@ func GetOutboundHistory(c *gin.Context) {
             &h.ProductID, &h.LotNumber,
             &h.TypeID, &h.TypeName,
             &h.StaffID, &h.StaffName,
-            &h.CustomerNumber, &h.CustomerName,
-            &h.PurchaserNumber, &h.PurchaserName,
+            &h.CustomerID, &h.CustomerNumber, &h.CustomerName,
+            &h.PurchaserID, &h.PurchaserNumber, &h.PurchaserName,
             &h.Notes,
-            &h.ModelNumber, &h.SerialNumber,
+            &h.ModelNumber, &h.SerialNumber, &h.PurchaseDate, &h.WarrantyPeriod,
+            &h.VestType, &h.VestSize, &h.VestHasLogo, &h.Attributes,
+            &h.ReturnNumber, &h.ReturnDate, &h.ReturnReason,
+            &h.VoidedAt, &h.VoidedByID, &h.VoidedByName, &h.VoidReason,
+            &h.Status,
+            &h.SortValue,
         )
         if err != nil {
             c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("データの読み取りに失敗しました: %v", err)})
             return
         }
 
+        if export != nil {
+            values := []interface{}{h.OutboundNumber, h.OutboundDate, h.ProductID, h.TypeName, h.LotNumber.String}
+            values = append(values, detailExportValues(
+                category, h.ModelNumber, h.SerialNumber, h.PurchaseDate, h.WarrantyPeriod,
+                h.VestType, h.VestSize, h.VestHasLogo,
+            )...)
+            values = append(values, attributeExportValues(attributeDefs, h.Attributes)...)
+            values = append(values,
+                h.CustomerNumber.String, h.CustomerName.String,
+                h.PurchaserNumber.String, h.PurchaserName.String,
+                h.Notes.String, h.StaffName,
+                h.ReturnNumber.String, nullableTime(h.ReturnDate),
+                nullableTime(h.VoidedAt), h.VoidReason.String,
+            )
+            if err := export.WriteRow(values); err != nil {
+                log.Printf("エクスポート書き込みエラー: %v", err)
+                return
+            }
+            continue
+        }
+
         record := gin.H{
             "id":             h.ID,
             "outboundNumber": h.OutboundNumber,
@@ -641,26 +940,65 @@ func GetOutboundHistory(c *gin.Context) {
                 "id":   h.TypeID,
                 "name": h.TypeName,
             },
+            "attributes": attributesJSON(h.Attributes),
             "staff": gin.H{
                 "id":   h.StaffID,
                 "name": h.StaffName,
             },
-            "customerNumber": h.CustomerNumber.String,
-            "customerName":   h.CustomerName.String,
+            "customerId":      nullableInt(h.CustomerID),
+            "customerNumber":  h.CustomerNumber.String,
+            "customerName":    h.CustomerName.String,
+            "purchaserId":     nullableInt(h.PurchaserID),
             "purchaserNumber": h.PurchaserNumber.String,
             "purchaserName":   h.PurchaserName.String,
-            "notes":          h.Notes.String,
+            "notes":           h.Notes.String,
+            "status":          h.Status,
+            "voided":          h.VoidedAt.Valid,
+        }
+
+        // 取消済みの出庫伝票は取消情報を付与
+        if h.VoidedAt.Valid {
+            record["voidedAt"] = h.VoidedAt.Time
+            record["voidReason"] = h.VoidReason.String
+            if h.VoidedByID.Valid {
+                record["voidedBy"] = gin.H{
+                    "id":   h.VoidedByID.Int32,
+                    "name": h.VoidedByName.String,
+                }
+            }
+        }
+
+        if h.ReturnNumber.Valid {
+            record["return"] = gin.H{
+                "returnNumber": h.ReturnNumber.String,
+                "returnDate":   h.ReturnDate.Time,
+                "reason":       h.ReturnReason.String,
+            }
+        }
+
+        if category == "vest" && h.VestType.Valid {
+            record["vestDetails"] = vestDetailsJSON(h.VestType, h.VestSize, h.VestHasLogo)
         }
 
         if category == "pc" && h.ModelNumber.Valid {
             record["pcDetails"] = gin.H{
-                "modelNumber":  h.ModelNumber.String,
-                "serialNumber": h.SerialNumber.String,
+                "modelNumber":    h.ModelNumber.String,
+                "serialNumber":   h.SerialNumber.String,
+                "purchaseDate":   h.PurchaseDate.Time,
+                "warrantyPeriod": h.WarrantyPeriod.Int32,
             }
         }
 
         history = append(history, record)
+        cursors = append(cursors, listCursor{Value: h.SortValue, ID: h.ID})
+    }
+
+    if export != nil {
+        if err := export.Close(); err != nil {
+            log.Printf("エクスポート書き込みエラー: %v", err)
+        }
+        return
     }
 
-    c.JSON(http.StatusOK, history)
+    c.JSON(http.StatusOK, q.response(history, cursors, totalCount))
 }
\ No newline at end of file
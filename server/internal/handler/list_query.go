@@ -0,0 +1,312 @@
+package handler
+
+import (
+    "encoding/base64"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "strconv"
+    "strings"
+    "time"
+    "github.com/gin-gonic/gin"
+)
+
+// 一覧取得の件数
+const (
+    defaultPageSize = 50
+    maxPageSize     = 500
+)
+
+// 並び替えに使用できる列
+// 値は NULL にならない式とし、cast はカーソルの値を戻す際の型
+type sortColumn struct {
+    expr string
+    cast string
+}
+
+// 一覧取得で使用できる絞り込み条件の列（空の項目は絞り込み不可）
+type listFilterColumns struct {
+    Status      string
+    TypeID      string
+    LotNumber   string
+    StaffID     string
+    Date        string
+    Customer    []string
+    ModelNumber string
+    VestType    string
+    VestSize    string
+    VestHasLogo string
+}
+
+// 一覧取得のクエリ定義
+type listQuerySpec struct {
+    selectColumns string
+    from          string
+    idColumn      string
+    sorts         map[string]sortColumn
+    defaultSort   string
+    filters       listFilterColumns
+}
+
+// 一覧取得のクエリ
+// 絞り込み条件とカーソルを順にプレースホルダーへ割り当てる
+type listQuery struct {
+    spec       listQuerySpec
+    conditions []string
+    args       []interface{}
+    sort       sortColumn
+    desc       bool
+    limit      int
+    cursor     *listCursor
+}
+
+// キーセットページングのカーソル（最後の行の並び替え値とID）
+type listCursor struct {
+    Value string `json:"v"`
+    ID    int    `json:"id"`
+}
+
+// プレースホルダーの追加
+func (q *listQuery) arg(v interface{}) string {
+    q.args = append(q.args, v)
+    return fmt.Sprintf("$%d", len(q.args))
+}
+
+// 絞り込み条件の追加
+func (q *listQuery) where(condition string) {
+    q.conditions = append(q.conditions, condition)
+}
+
+// クエリパラメータから一覧取得のクエリを作成
+// limit, cursor, sort（"-" を付けると降順）と listFilterColumns の各絞り込み条件に対応する
+func newListQuery(c *gin.Context, spec listQuerySpec) (*listQuery, error) {
+    q := &listQuery{spec: spec, limit: defaultPageSize}
+
+    // 件数
+    if v := c.Query("limit"); v != "" {
+        limit, err := strconv.Atoi(v)
+        if err != nil || limit < 1 {
+            return nil, errors.New("limit には1以上の数値を指定してください")
+        }
+        if limit > maxPageSize {
+            limit = maxPageSize
+        }
+        q.limit = limit
+    }
+
+    // 並び替え
+    sortKey := c.DefaultQuery("sort", spec.defaultSort)
+    q.desc = strings.HasPrefix(sortKey, "-")
+    sort, ok := spec.sorts[strings.TrimPrefix(sortKey, "-")]
+    if !ok {
+        return nil, fmt.Errorf("並び替えに指定できない項目です: %s", sortKey)
+    }
+    q.sort = sort
+
+    // カーソル
+    if v := c.Query("cursor"); v != "" {
+        cursor, err := decodeListCursor(v)
+        if err != nil {
+            return nil, errors.New("無効なカーソルです")
+        }
+        q.cursor = cursor
+    }
+
+    if err := q.applyFilters(c); err != nil {
+        return nil, err
+    }
+    return q, nil
+}
+
+// 絞り込み条件の適用
+func (q *listQuery) applyFilters(c *gin.Context) error {
+    f := q.spec.filters
+    unsupported := func(name string) error {
+        return fmt.Errorf("%s による絞り込みには対応していません", name)
+    }
+
+    if v := strings.TrimSpace(c.Query("status")); v != "" {
+        if f.Status == "" {
+            return unsupported("status")
+        }
+        q.where(fmt.Sprintf("%s = %s", f.Status, q.arg(v)))
+    }
+    if v := c.Query("typeId"); v != "" {
+        typeID, err := strconv.Atoi(v)
+        if err != nil {
+            return errors.New("typeId には数値を指定してください")
+        }
+        if f.TypeID == "" {
+            return unsupported("typeId")
+        }
+        q.where(fmt.Sprintf("%s = %s", f.TypeID, q.arg(typeID)))
+    }
+    if v := strings.TrimSpace(c.Query("lotNumber")); v != "" {
+        if f.LotNumber == "" {
+            return unsupported("lotNumber")
+        }
+        q.where(fmt.Sprintf("%s ILIKE %s", f.LotNumber, q.arg(likePattern(v))))
+    }
+    if v := c.Query("staffId"); v != "" {
+        staffID, err := strconv.Atoi(v)
+        if err != nil {
+            return errors.New("staffId には数値を指定してください")
+        }
+        if f.StaffID == "" {
+            return unsupported("staffId")
+        }
+        q.where(fmt.Sprintf("%s = %s", f.StaffID, q.arg(staffID)))
+    }
+    if v := c.Query("dateFrom"); v != "" {
+        date, err := time.Parse("2006-01-02", v)
+        if err != nil {
+            return errors.New("dateFrom は YYYY-MM-DD 形式で指定してください")
+        }
+        if f.Date == "" {
+            return unsupported("dateFrom")
+        }
+        q.where(fmt.Sprintf("%s >= %s", f.Date, q.arg(date)))
+    }
+    if v := c.Query("dateTo"); v != "" {
+        date, err := time.Parse("2006-01-02", v)
+        if err != nil {
+            return errors.New("dateTo は YYYY-MM-DD 形式で指定してください")
+        }
+        if f.Date == "" {
+            return unsupported("dateTo")
+        }
+        // 終了日はその日の終わりまで含める
+        q.where(fmt.Sprintf("%s < %s", f.Date, q.arg(date.AddDate(0, 0, 1))))
+    }
+    if v := strings.TrimSpace(c.Query("customer")); v != "" {
+        if len(f.Customer) == 0 {
+            return unsupported("customer")
+        }
+        pattern := q.arg(likePattern(v))
+        var conditions []string
+        for _, column := range f.Customer {
+            conditions = append(conditions, fmt.Sprintf("%s ILIKE %s", column, pattern))
+        }
+        q.where("(" + strings.Join(conditions, " OR ") + ")")
+    }
+    if v := strings.TrimSpace(c.Query("modelNumber")); v != "" {
+        if f.ModelNumber == "" {
+            return unsupported("modelNumber")
+        }
+        q.where(fmt.Sprintf("%s ILIKE %s", f.ModelNumber, q.arg(likePattern(v))))
+    }
+
+    // ベストの種類・サイズ・ロゴ有無による絞り込み
+    if v := normalizeVestType(c.Query("vestType")); v != "" {
+        if f.VestType == "" {
+            return unsupported("vestType")
+        }
+        q.where(fmt.Sprintf("%s = %s", f.VestType, q.arg(v)))
+    }
+    if v := strings.TrimSpace(c.Query("vestSize")); v != "" {
+        if f.VestSize == "" {
+            return unsupported("vestSize")
+        }
+        q.where(fmt.Sprintf("%s = %s", f.VestSize, q.arg(v)))
+    }
+    if v := c.Query("vestHasLogo"); v != "" {
+        hasLogo, err := strconv.ParseBool(v)
+        if err != nil {
+            return errors.New("vestHasLogo には true または false を指定してください")
+        }
+        if f.VestHasLogo == "" {
+            return unsupported("vestHasLogo")
+        }
+        q.where(fmt.Sprintf("%s = %s", f.VestHasLogo, q.arg(hasLogo)))
+    }
+    return nil
+}
+
+// 件数取得のクエリ（カーソルを含まない絞り込み条件のみ）
+func (q *listQuery) countSQL() (string, []interface{}) {
+    return fmt.Sprintf("SELECT COUNT(*) FROM %s %s", q.spec.from, whereClause(q.conditions)), q.args
+}
+
+// 一覧取得のクエリ
+// 最後の列に並び替え値を文字列で返す。paged が false の場合はカーソル・件数を無視して全件を返す
+func (q *listQuery) selectSQL(paged bool) (string, []interface{}) {
+    conditions := q.conditions
+    args := q.args
+    direction, comparison := "ASC", ">"
+    if q.desc {
+        direction, comparison = "DESC", "<"
+    }
+
+    if paged && q.cursor != nil {
+        args = append(append([]interface{}{}, q.args...), q.cursor.Value, q.cursor.ID)
+        conditions = append(append([]string{}, q.conditions...), fmt.Sprintf(
+            "(%s, %s) %s ($%d::%s, $%d)",
+            q.sort.expr, q.spec.idColumn, comparison, len(args)-1, q.sort.cast, len(args),
+        ))
+    }
+
+    query := fmt.Sprintf(
+        "SELECT %s, (%s)::text AS sort_value FROM %s %s ORDER BY %s %s, %s %s",
+        q.spec.selectColumns, q.sort.expr, q.spec.from, whereClause(conditions),
+        q.sort.expr, direction, q.spec.idColumn, direction,
+    )
+    if paged {
+        // 次のページの有無を判定するため1件多く取得する
+        query += fmt.Sprintf(" LIMIT %d", q.limit+1)
+    }
+    return query, args
+}
+
+// ページングした一覧のレスポンス
+// items には limit+1 件まで渡し、超過分は次のカーソルの判定に使用する
+func (q *listQuery) response(items []gin.H, lastValues []listCursor, totalCount int) gin.H {
+    hasMore := len(items) > q.limit
+    var nextCursor interface{}
+    if hasMore {
+        items = items[:q.limit]
+        nextCursor = encodeListCursor(lastValues[q.limit-1])
+    }
+    if items == nil {
+        items = []gin.H{}
+    }
+    return gin.H{
+        "items":      items,
+        "totalCount": totalCount,
+        "nextCursor": nextCursor,
+        "hasMore":    hasMore,
+    }
+}
+
+func whereClause(conditions []string) string {
+    if len(conditions) == 0 {
+        return ""
+    }
+    return "WHERE " + strings.Join(conditions, " AND ")
+}
+
+// 部分一致検索のパターン（ワイルドカード文字はエスケープ）
+func likePattern(v string) string {
+    return "%" + escapeLike(v) + "%"
+}
+
+// LIKE のワイルドカード文字のエスケープ
+func escapeLike(v string) string {
+    return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(v)
+}
+
+func encodeListCursor(cursor listCursor) string {
+    data, _ := json.Marshal(cursor)
+    return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeListCursor(v string) (*listCursor, error) {
+    data, err := base64.RawURLEncoding.DecodeString(v)
+    if err != nil {
+        return nil, err
+    }
+    var cursor listCursor
+    if err := json.Unmarshal(data, &cursor); err != nil {
+        return nil, err
+    }
+    return &cursor, nil
+}
@@ -0,0 +1,163 @@
+package handler
+
+import (
+    "encoding/json"
+    "log"
+    "net/http"
+    "strings"
+    "time"
+    "github.com/gin-gonic/gin"
+    "inventory-tracker/server/internal/audit"
+    "inventory-tracker/server/internal/db"
+    "inventory-tracker/server/internal/numbering"
+)
+
+// 伝票番号書式一覧取得ハンドラー
+func GetDocumentNumberFormats(c *gin.Context) {
+    rows, err := db.DB.Query(`
+        SELECT id, doc_type, category, format, per_category, updated_at
+        FROM document_number_formats
+        ORDER BY doc_type, category NULLS FIRST
+    `)
+    if err != nil {
+        log.Printf("[GetDocumentNumberFormats] エラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "伝票番号書式の取得に失敗しました"})
+        return
+    }
+    defer rows.Close()
+
+    formats := []gin.H{}
+    for rows.Next() {
+        var f struct {
+            ID          int
+            DocType     string
+            Category    *string
+            Format      string
+            PerCategory bool
+            UpdatedAt   time.Time
+        }
+        if err := rows.Scan(&f.ID, &f.DocType, &f.Category, &f.Format, &f.PerCategory, &f.UpdatedAt); err != nil {
+            log.Printf("[GetDocumentNumberFormats] 読み取りエラー: %v", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "伝票番号書式の読み取りに失敗しました"})
+            return
+        }
+
+        formats = append(formats, gin.H{
+            "id":          f.ID,
+            "docType":     f.DocType,
+            "category":    f.Category,
+            "format":      f.Format,
+            "perCategory": f.PerCategory,
+            "example":     numbering.Format(f.Format, stringValue(f.Category), time.Now(), 1),
+            "updatedAt":   f.UpdatedAt,
+        })
+    }
+
+    c.JSON(http.StatusOK, formats)
+}
+
+// 伝票番号書式更新ハンドラー
+// category を省略した場合は全カテゴリ共通の書式を更新する
+func UpdateDocumentNumberFormat(c *gin.Context) {
+    docType := c.Param("docType")
+    if !numbering.IsDocType(docType) {
+        c.JSON(http.StatusNotFound, gin.H{"error": "指定された伝票種別が見つかりません"})
+        return
+    }
+
+    var req struct {
+        Category    *string `json:"category"`
+        Format      string  `json:"format" binding:"required"`
+        PerCategory bool    `json:"perCategory"`
+    }
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "無効なリクエストデータです"})
+        return
+    }
+
+    format := strings.TrimSpace(req.Format)
+    if err := numbering.Validate(format, req.PerCategory); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    var category *string
+    if req.Category != nil && strings.TrimSpace(*req.Category) != "" {
+        trimmed := strings.TrimSpace(*req.Category)
+        category = &trimmed
+    }
+
+    tx, err := db.BeginTx()
+    if err != nil {
+        log.Printf("[UpdateDocumentNumberFormat] トランザクション開始エラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "トランザクションの開始に失敗しました"})
+        return
+    }
+    defer tx.Rollback()
+
+    before, ok := auditSnapshot(c, tx, `
+        SELECT to_jsonb(f) FROM document_number_formats f
+        WHERE f.doc_type = $1 AND COALESCE(f.category, '') = COALESCE($2, '')
+        FOR UPDATE
+    `, docType, category)
+    if !ok {
+        return
+    }
+
+    var after []byte
+    err = tx.QueryRow(`
+        INSERT INTO document_number_formats (doc_type, category, format, per_category)
+        VALUES ($1, $2, $3, $4)
+        ON CONFLICT (doc_type, (COALESCE(category, ''))) DO UPDATE
+        SET format = EXCLUDED.format, per_category = EXCLUDED.per_category
+        RETURNING to_jsonb(document_number_formats)
+    `, docType, category, format, req.PerCategory).Scan(&after)
+    if isForeignKeyViolation(err) {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "不明なカテゴリです: " + *category})
+        return
+    }
+    if err != nil {
+        log.Printf("[UpdateDocumentNumberFormat] エラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "伝票番号書式の更新に失敗しました"})
+        return
+    }
+
+    action, entityID := audit.ActionUpdate, docType
+    if before == nil {
+        action = audit.ActionCreate
+    }
+    if category != nil {
+        entityID += "/" + *category
+    }
+    if !recordAudit(c, tx, audit.Entry{
+        Action:     action,
+        EntityType: "document_number_format",
+        EntityID:   entityID,
+        Before:     before,
+        After:      json.RawMessage(after),
+    }) {
+        return
+    }
+
+    if err := tx.Commit(); err != nil {
+        log.Printf("[UpdateDocumentNumberFormat] コミットエラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "トランザクションのコミットに失敗しました"})
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "success":     true,
+        "docType":     docType,
+        "category":    category,
+        "format":      format,
+        "perCategory": req.PerCategory,
+        "example":     numbering.Format(format, stringValue(category), time.Now(), 1),
+    })
+}
+
+// 文字列ポインタの値を取得（nilの場合は空文字）
+func stringValue(s *string) string {
+    if s == nil {
+        return ""
+    }
+    return *s
+}
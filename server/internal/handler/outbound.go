@@ -0,0 +1,597 @@
+package handler
+
+import (
+    "database/sql"
+    "fmt"
+    "log"
+    "net/http"
+    "strings"
+    "time"
+    "github.com/gin-gonic/gin"
+    "github.com/lib/pq"
+    "inventory-tracker/server/internal/audit"
+    "inventory-tracker/server/internal/auth"
+    "inventory-tracker/server/internal/db"
+    "inventory-tracker/server/internal/model"
+    "inventory-tracker/server/internal/productid"
+)
+
+// 処理中の業務エラー（HTTPステータスと製品ごとの失敗理由を保持）
+type requestError struct {
+    Status   int
+    Message  string
+    Failures []gin.H
+}
+
+func (e *requestError) Error() string {
+    return e.Message
+}
+
+// レスポンス用のJSONを生成
+func (e *requestError) response() gin.H {
+    res := gin.H{"error": e.Message}
+    if len(e.Failures) > 0 {
+        res["failures"] = e.Failures
+    }
+    return res
+}
+
+// 監査ログ用の出庫伝票の内容（$1: 出庫番号, $2: カテゴリ）
+const outboundAuditQuery = `
+    SELECT json_build_object('category', $2::text, 'records', json_agg(row_to_json(obr) ORDER BY obr.product_id))
+    FROM outbound_records obr
+    WHERE obr.outbound_number = $1
+`
+
+// 製品IDリストの正規化（前後の空白除去・空行除外）
+func normalizeProductIDs(ids []string) []string {
+    var normalized []string
+    for _, id := range ids {
+        if id = strings.TrimSpace(id); id != "" {
+            normalized = append(normalized, id)
+        }
+    }
+    return normalized
+}
+
+// 製品IDリスト指定による出庫対象の選定
+// すべての製品IDがカテゴリ内の在庫に存在する場合のみ対象を返す
+func selectOutboundByList(tx *sql.Tx, category string, productIDs []string) ([]string, error) {
+    ids := normalizeProductIDs(productIDs)
+    if len(ids) == 0 {
+        return nil, &requestError{Status: http.StatusBadRequest, Message: "出庫する製品IDを指定してください"}
+    }
+
+    rows, err := tx.Query(`
+        SELECT p.product_id, p.status, pt.category
+        FROM products p
+        INNER JOIN product_types pt ON p.type_id = pt.id
+        WHERE p.product_id = ANY($1)
+    `, pq.Array(ids))
+    if err != nil {
+        return nil, fmt.Errorf("製品取得エラー: %v", err)
+    }
+    defer rows.Close()
+
+    type productState struct {
+        Status   string
+        Category string
+    }
+    states := make(map[string]productState)
+    for rows.Next() {
+        var id string
+        var s productState
+        if err := rows.Scan(&id, &s.Status, &s.Category); err != nil {
+            return nil, fmt.Errorf("製品データ読み取りエラー: %v", err)
+        }
+        states[id] = s
+    }
+    if err := rows.Err(); err != nil {
+        return nil, fmt.Errorf("製品データ読み取りエラー: %v", err)
+    }
+
+    // 製品IDごとの検証
+    var targets []string
+    var failures []gin.H
+    seen := make(map[string]bool)
+    for _, id := range ids {
+        if seen[id] {
+            failures = append(failures, gin.H{"productId": id, "reason": "製品IDが重複しています"})
+            continue
+        }
+        seen[id] = true
+
+        s, ok := states[id]
+        switch {
+        case !ok:
+            failures = append(failures, gin.H{"productId": id, "reason": "在庫に存在しません"})
+        case s.Category != category:
+            failures = append(failures, gin.H{"productId": id, "reason": "別のカテゴリの製品です"})
+        case s.Status != "in_stock":
+            failures = append(failures, gin.H{"productId": id, "reason": "既に出庫済みです"})
+        default:
+            targets = append(targets, id)
+        }
+    }
+
+    if len(failures) > 0 {
+        log.Printf("出庫できない製品IDがあります: %+v", failures)
+        return nil, &requestError{
+            Status:   http.StatusBadRequest,
+            Message:  fmt.Sprintf("出庫できない製品IDが%d件あります", len(failures)),
+            Failures: failures,
+        }
+    }
+    return targets, nil
+}
+
+// 開始ID・終了ID指定による出庫対象の選定
+// 範囲は製品IDの数字部分で展開し、範囲内で出庫できない製品IDは gaps として返す
+func selectOutboundByRange(tx *sql.Tx, category string, productIDStart string, productIDEnd string) ([]string, []gin.H, error) {
+    // 範囲の展開と同じく前後の空白を除いた製品IDで照合する
+    productIDStart = strings.TrimSpace(productIDStart)
+    productIDEnd = strings.TrimSpace(productIDEnd)
+
+    // 範囲の展開
+    candidates, err := productid.ExpandRange(productIDStart, productIDEnd)
+    if err != nil {
+        return nil, nil, &requestError{Status: http.StatusBadRequest, Message: err.Error()}
+    }
+
+    // 開始IDと終了IDの型番を取得して一致を確認
+    var startTypeID, endTypeID int
+    var startTypeName, endTypeName string
+
+    // 開始IDの型番取得
+    err = tx.QueryRow(`
+        SELECT p.type_id, pt.name
+        FROM products p
+        INNER JOIN product_types pt ON p.type_id = pt.id
+        WHERE p.product_id = $1
+        AND pt.category = $2
+        AND p.status = 'in_stock'
+    `, productIDStart, category).Scan(&startTypeID, &startTypeName)
+    if err != nil {
+        if err == sql.ErrNoRows {
+            log.Printf("開始製品ID %s は在庫に存在しないか、既に出庫済みです", productIDStart)
+            return nil, nil, &requestError{
+                Status:  http.StatusNotFound,
+                Message: fmt.Sprintf("開始製品ID %s は在庫に存在しないか、既に出庫済みです", productIDStart),
+            }
+        }
+        return nil, nil, fmt.Errorf("開始ID型番取得エラー: %v", err)
+    }
+
+    // 終了IDの型番取得
+    err = tx.QueryRow(`
+        SELECT p.type_id, pt.name
+        FROM products p
+        INNER JOIN product_types pt ON p.type_id = pt.id
+        WHERE p.product_id = $1
+        AND pt.category = $2
+        AND p.status = 'in_stock'
+    `, productIDEnd, category).Scan(&endTypeID, &endTypeName)
+    if err != nil {
+        if err == sql.ErrNoRows {
+            log.Printf("終了製品ID %s は在庫に存在しないか、既に出庫済みです", productIDEnd)
+            return nil, nil, &requestError{
+                Status:  http.StatusNotFound,
+                Message: fmt.Sprintf("終了製品ID %s は在庫に存在しないか、既に出庫済みです", productIDEnd),
+            }
+        }
+        return nil, nil, fmt.Errorf("終了ID型番取得エラー: %v", err)
+    }
+
+    // 型番の一致を確認
+    if startTypeID != endTypeID {
+        log.Printf("型番が一致しません: 開始ID=%s（%s）, 終了ID=%s（%s）",
+            productIDStart, startTypeName, productIDEnd, endTypeName)
+        return nil, nil, &requestError{
+            Status:  http.StatusBadRequest,
+            Message: fmt.Sprintf("開始IDと終了IDの型番が一致しません（開始ID: %s, 終了ID: %s）", startTypeName, endTypeName),
+        }
+    }
+
+    // 範囲内の製品を取得
+    rows, err := tx.Query(`
+        SELECT p.product_id, p.status, p.type_id, pt.category
+        FROM products p
+        INNER JOIN product_types pt ON p.type_id = pt.id
+        WHERE p.product_id = ANY($1)
+    `, pq.Array(candidates))
+    if err != nil {
+        return nil, nil, fmt.Errorf("製品取得エラー: %v", err)
+    }
+    defer rows.Close()
+
+    type productState struct {
+        Status   string
+        TypeID   int
+        Category string
+    }
+    states := make(map[string]productState)
+    for rows.Next() {
+        var id string
+        var s productState
+        if err := rows.Scan(&id, &s.Status, &s.TypeID, &s.Category); err != nil {
+            return nil, nil, fmt.Errorf("製品データ読み取りエラー: %v", err)
+        }
+        states[id] = s
+    }
+    if err := rows.Err(); err != nil {
+        return nil, nil, fmt.Errorf("製品データ読み取りエラー: %v", err)
+    }
+
+    // 範囲内の製品IDごとに出庫可否を判定
+    var targets []string
+    var gaps []gin.H
+    for _, id := range candidates {
+        s, ok := states[id]
+        switch {
+        case !ok:
+            gaps = append(gaps, gin.H{"productId": id, "reason": "在庫に存在しません"})
+        case s.Category != category:
+            gaps = append(gaps, gin.H{"productId": id, "reason": "別のカテゴリの製品です"})
+        case s.TypeID != startTypeID:
+            gaps = append(gaps, gin.H{"productId": id, "reason": "型番が異なります"})
+        case s.Status != "in_stock":
+            gaps = append(gaps, gin.H{"productId": id, "reason": "既に出庫済みです"})
+        default:
+            targets = append(targets, id)
+        }
+    }
+
+    if len(gaps) > 0 {
+        log.Printf("範囲内に出庫できない製品IDがあります: %+v", gaps)
+    }
+    return targets, gaps, nil
+}
+
+// 出庫対象の製品を出庫済みに更新
+// 他の処理で先に出庫された製品がある場合はエラーを返す
+func markProductsOutOfStock(tx *sql.Tx, productIDs []string) ([]string, error) {
+    rows, err := tx.Query(`
+        UPDATE products
+        SET status = 'out_of_stock'
+        WHERE product_id = ANY($1)
+        AND status = 'in_stock'
+        RETURNING product_id
+    `, pq.Array(productIDs))
+    if err != nil {
+        return nil, fmt.Errorf("製品更新エラー: %v", err)
+    }
+    defer rows.Close()
+
+    var updated []string
+    for rows.Next() {
+        var productID string
+        if err := rows.Scan(&productID); err != nil {
+            return nil, fmt.Errorf("製品データ読み取りエラー: %v", err)
+        }
+        updated = append(updated, productID)
+    }
+    if err := rows.Err(); err != nil {
+        return nil, fmt.Errorf("製品データ読み取りエラー: %v", err)
+    }
+
+    if len(updated) != len(productIDs) {
+        log.Printf("製品の状態が変更されています: 更新件数 = %d, 対象件数 = %d", len(updated), len(productIDs))
+        return nil, &requestError{
+            Status:  http.StatusConflict,
+            Message: "処理中に製品の状態が変更されました。もう一度お試しください",
+        }
+    }
+    return updated, nil
+}
+
+// 業務エラーのレスポンス出力（それ以外のエラーは message で500を返す）
+func respondRequestError(c *gin.Context, err error, message string) {
+    if oerr, ok := err.(*requestError); ok {
+        c.JSON(oerr.Status, oerr.response())
+        return
+    }
+    log.Printf("%s: %v", message, err)
+    c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+}
+
+// 出庫プレビューハンドラー
+// 出庫処理と同じ検証・対象選定を行い、対象製品の一覧を返す（データの更新・出庫番号の採番は行わない）
+func PreviewOutbound(c *gin.Context) {
+    category := c.Param("category")
+    log.Printf("出庫プレビュー開始: カテゴリ = %s", category)
+
+    var req model.OutboundRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        log.Printf("リクエストデータバインドエラー: %v", err)
+        c.JSON(http.StatusBadRequest, gin.H{"error": "無効なリクエストデータです"})
+        return
+    }
+
+    if req.OutboundDate != "" {
+        if _, err := time.Parse("2006-01-02", req.OutboundDate); err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "無効な日付フォーマットです"})
+            return
+        }
+    }
+
+    // 読み取り専用トランザクションで検証
+    tx, err := db.BeginReadOnlyTx()
+    if err != nil {
+        log.Printf("トランザクション開始エラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "トランザクションの開始に失敗しました"})
+        return
+    }
+    defer tx.Rollback()
+
+    var targetIDs []string
+    var gaps []gin.H
+    if len(req.ProductIDs) > 0 {
+        targetIDs, err = selectOutboundByList(tx, category, req.ProductIDs)
+    } else {
+        targetIDs, gaps, err = selectOutboundByRange(tx, category, req.ProductIDStart, req.ProductIDEnd)
+    }
+    if err != nil {
+        respondRequestError(c, err, "出庫対象の取得に失敗しました")
+        return
+    }
+
+    // 対象製品の型番・ロット番号を取得
+    rows, err := tx.Query(`
+        SELECT p.product_id, p.lot_number, pt.id, pt.name
+        FROM products p
+        INNER JOIN product_types pt ON p.type_id = pt.id
+        WHERE p.product_id = ANY($1)
+        ORDER BY p.product_id
+    `, pq.Array(targetIDs))
+    if err != nil {
+        log.Printf("製品取得エラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "出庫対象の取得に失敗しました"})
+        return
+    }
+    defer rows.Close()
+
+    products := make([]gin.H, 0, len(targetIDs))
+    typeCounts := make(map[int]int)
+    typeNames := make(map[int]string)
+    var typeOrder []int
+    lotSeen := make(map[string]bool)
+    lotNumbers := []string{}
+    for rows.Next() {
+        var productID, typeName string
+        var lotNumber sql.NullString
+        var typeID int
+        if err := rows.Scan(&productID, &lotNumber, &typeID, &typeName); err != nil {
+            log.Printf("製品データ読み取りエラー: %v", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "製品データの読み取りに失敗しました"})
+            return
+        }
+
+        products = append(products, gin.H{
+            "productId": productID,
+            "lotNumber": lotNumber.String,
+            "type": gin.H{
+                "id":   typeID,
+                "name": typeName,
+            },
+        })
+        if _, ok := typeCounts[typeID]; !ok {
+            typeOrder = append(typeOrder, typeID)
+            typeNames[typeID] = typeName
+        }
+        typeCounts[typeID]++
+        if lotNumber.Valid && !lotSeen[lotNumber.String] {
+            lotSeen[lotNumber.String] = true
+            lotNumbers = append(lotNumbers, lotNumber.String)
+        }
+    }
+    if err := rows.Err(); err != nil {
+        log.Printf("製品データ読み取りエラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "製品データの読み取りに失敗しました"})
+        return
+    }
+
+    types := make([]gin.H, 0, len(typeOrder))
+    for _, id := range typeOrder {
+        types = append(types, gin.H{"id": id, "name": typeNames[id], "count": typeCounts[id]})
+    }
+
+    // 警告の作成
+    warnings := []string{}
+    canSubmit := true
+    if len(gaps) > 0 {
+        if req.AllowGaps {
+            warnings = append(warnings, fmt.Sprintf("指定された範囲の%d件の製品IDは出庫対象から除外されます", len(gaps)))
+        } else {
+            warnings = append(warnings, fmt.Sprintf("指定された範囲に出庫できない製品IDが%d件あります", len(gaps)))
+            canSubmit = false
+        }
+    }
+    if len(types) > 1 {
+        warnings = append(warnings, fmt.Sprintf("%d種類の型番が含まれています", len(types)))
+    }
+    if len(lotNumbers) > 1 {
+        warnings = append(warnings, fmt.Sprintf("%d種類のロット番号が含まれています", len(lotNumbers)))
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "canSubmit":  canSubmit,
+        "count":      len(products),
+        "products":   products,
+        "types":      types,
+        "lotNumbers": lotNumbers,
+        "gaps":       gaps,
+        "warnings":   warnings,
+    })
+}
+
+// 出庫取消ハンドラー
+// 出庫伝票に含まれる製品を在庫に戻し、出庫記録は取消済みとして残す
+func VoidOutbound(c *gin.Context) {
+    category := c.Param("category")
+    outboundNumber := c.Param("outboundNumber")
+    log.Printf("出庫取消開始: カテゴリ = %s, 出庫番号 = %s", category, outboundNumber)
+
+    var req model.VoidRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        log.Printf("リクエストデータバインドエラー: %v", err)
+        c.JSON(http.StatusBadRequest, gin.H{"error": "無効なリクエストデータです"})
+        return
+    }
+
+    // 担当者はログイン中のセッションから取得
+    req.StaffID = auth.CurrentStaffID(c)
+    if strings.TrimSpace(req.Reason) == "" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "取消理由を入力してください"})
+        return
+    }
+
+    // トランザクション開始
+    tx, err := db.BeginTx()
+    if err != nil {
+        log.Printf("トランザクション開始エラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "トランザクションの開始に失敗しました"})
+        return
+    }
+    defer tx.Rollback()
+
+    // 出庫記録の取得（取消処理が完了するまでロック）
+    rows, err := tx.Query(`
+        SELECT
+            obr.product_id, p.status, obr.voided_at IS NOT NULL AS voided,
+            EXISTS(SELECT 1 FROM return_records rr WHERE rr.outbound_record_id = obr.id) AS returned,
+            EXISTS(
+                SELECT 1 FROM outbound_records later
+                WHERE later.product_id = obr.product_id
+                AND later.id > obr.id
+                AND later.voided_at IS NULL
+            ) AS shipped_again
+        FROM outbound_records obr
+        INNER JOIN products p ON obr.product_id = p.product_id
+        INNER JOIN product_types pt ON p.type_id = pt.id
+        WHERE obr.outbound_number = $1
+        AND pt.category = $2
+        ORDER BY obr.product_id
+        FOR UPDATE OF obr
+    `, outboundNumber, category)
+    if err != nil {
+        log.Printf("出庫記録取得エラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "出庫記録の取得に失敗しました"})
+        return
+    }
+
+    var productIDs []string
+    var blocking []gin.H
+    voidedCount := 0
+    for rows.Next() {
+        var productID, status string
+        var voided, returned, shippedAgain bool
+        if err := rows.Scan(&productID, &status, &voided, &returned, &shippedAgain); err != nil {
+            rows.Close()
+            log.Printf("出庫記録読み取りエラー: %v", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "出庫記録の読み取りに失敗しました"})
+            return
+        }
+
+        switch {
+        case voided:
+            voidedCount++
+        case returned:
+            blocking = append(blocking, gin.H{"productId": productID, "reason": "返品済みです"})
+        case shippedAgain:
+            blocking = append(blocking, gin.H{"productId": productID, "reason": "後続の出庫記録があります"})
+        case status != "out_of_stock":
+            blocking = append(blocking, gin.H{"productId": productID, "reason": "既に在庫に戻っています"})
+        default:
+            productIDs = append(productIDs, productID)
+        }
+    }
+    rows.Close()
+    if err := rows.Err(); err != nil {
+        log.Printf("出庫記録読み取りエラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "出庫記録の読み取りに失敗しました"})
+        return
+    }
+
+    if len(productIDs) == 0 && len(blocking) == 0 {
+        if voidedCount > 0 {
+            c.JSON(http.StatusConflict, gin.H{"error": "この出庫伝票は既に取消済みです"})
+            return
+        }
+        c.JSON(http.StatusNotFound, gin.H{"error": "指定された出庫番号が見つかりません"})
+        return
+    }
+    if len(blocking) > 0 {
+        log.Printf("取消できない製品があります: %+v", blocking)
+        c.JSON(http.StatusConflict, gin.H{
+            "error":    "取消できない製品が含まれています",
+            "failures": blocking,
+        })
+        return
+    }
+
+    before, ok := auditSnapshot(c, tx, outboundAuditQuery, outboundNumber, category)
+    if !ok {
+        return
+    }
+
+    // 製品を在庫に戻す
+    result, err := tx.Exec(`
+        UPDATE products
+        SET status = 'in_stock'
+        WHERE product_id = ANY($1)
+        AND status = 'out_of_stock'
+    `, pq.Array(productIDs))
+    if err != nil {
+        log.Printf("製品更新エラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "製品の更新に失敗しました"})
+        return
+    }
+    if updated, err := result.RowsAffected(); err != nil || int(updated) != len(productIDs) {
+        log.Printf("製品の状態が変更されています: 更新件数 = %d, 対象件数 = %d", updated, len(productIDs))
+        c.JSON(http.StatusConflict, gin.H{"error": "処理中に製品の状態が変更されました。もう一度お試しください"})
+        return
+    }
+
+    // 出庫記録を取消済みに更新
+    _, err = tx.Exec(`
+        UPDATE outbound_records
+        SET voided_at = CURRENT_TIMESTAMP, voided_by = $2, void_reason = $3
+        WHERE outbound_number = $1
+        AND product_id = ANY($4)
+        AND voided_at IS NULL
+    `, outboundNumber, req.StaffID, strings.TrimSpace(req.Reason), pq.Array(productIDs))
+    if err != nil {
+        log.Printf("出庫記録更新エラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "出庫記録の更新に失敗しました"})
+        return
+    }
+
+    after, ok := auditSnapshot(c, tx, outboundAuditQuery, outboundNumber, category)
+    if !ok {
+        return
+    }
+    if !recordAudit(c, tx, audit.Entry{
+        Action:     audit.ActionUpdate,
+        EntityType: "outbound",
+        EntityID:   outboundNumber,
+        Before:     before,
+        After:      after,
+    }) {
+        return
+    }
+
+    // トランザクションのコミット
+    if err := tx.Commit(); err != nil {
+        log.Printf("トランザクションコミットエラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "トランザクションのコミットに失敗しました"})
+        return
+    }
+
+    log.Printf("出庫取消が完了しました。出庫番号: %s, 製品: %v", outboundNumber, productIDs)
+    c.JSON(http.StatusOK, gin.H{
+        "success":        true,
+        "outboundNumber": outboundNumber,
+        "voidedCount":    len(productIDs),
+        "products":       productIDs,
+    })
+}
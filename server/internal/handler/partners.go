@@ -0,0 +1,399 @@
+package handler
+
+import (
+    "database/sql"
+    "fmt"
+    "log"
+    "net/http"
+    "strconv"
+    "strings"
+    "github.com/gin-gonic/gin"
+    "inventory-tracker/server/internal/audit"
+    "inventory-tracker/server/internal/db"
+    "inventory-tracker/server/internal/model"
+)
+
+// 顧客・購入者マスタの定義
+type partnerMaster struct {
+    table          string // マスタのテーブル
+    numberColumn   string // マスタの番号列
+    outboundColumn string // outbound_records の参照列
+    label          string
+    auditEntity    string // 監査ログのエンティティ種別
+}
+
+var (
+    customerMaster  = partnerMaster{"customers", "customer_number", "customer_id", "顧客", "customer"}
+    purchaserMaster = partnerMaster{"purchasers", "purchaser_number", "purchaser_id", "購入者", "purchaser"}
+)
+
+// 顧客
+func GetCustomers(c *gin.Context)    { listPartners(c, customerMaster) }
+func LookupCustomers(c *gin.Context) { lookupPartners(c, customerMaster) }
+func GetCustomer(c *gin.Context)     { getPartner(c, customerMaster) }
+func CreateCustomer(c *gin.Context)  { createPartner(c, customerMaster) }
+func UpdateCustomer(c *gin.Context)  { updatePartner(c, customerMaster) }
+func DeleteCustomer(c *gin.Context)  { deletePartner(c, customerMaster) }
+
+// 購入者
+func GetPurchasers(c *gin.Context)    { listPartners(c, purchaserMaster) }
+func LookupPurchasers(c *gin.Context) { lookupPartners(c, purchaserMaster) }
+func GetPurchaser(c *gin.Context)     { getPartner(c, purchaserMaster) }
+func CreatePurchaser(c *gin.Context)  { createPartner(c, purchaserMaster) }
+func UpdatePurchaser(c *gin.Context)  { updatePartner(c, purchaserMaster) }
+func DeletePurchaser(c *gin.Context)  { deletePartner(c, purchaserMaster) }
+
+func (m partnerMaster) columns() string {
+    return "id, " + m.numberColumn + ", name, postal_code, address, phone, email, contact_name, notes, created_at, updated_at"
+}
+
+func scanPartner(row interface{ Scan(...interface{}) error }) (model.Partner, error) {
+    var p model.Partner
+    err := row.Scan(
+        &p.ID, &p.Number, &p.Name, &p.PostalCode, &p.Address,
+        &p.Phone, &p.Email, &p.ContactName, &p.Notes, &p.CreatedAt, &p.UpdatedAt,
+    )
+    return p, err
+}
+
+// 一覧取得（q を指定した場合は番号・名称の部分一致で絞り込み）
+func listPartners(c *gin.Context, m partnerMaster) {
+    q := strings.TrimSpace(c.Query("q"))
+    rows, err := db.DB.Query(`
+        SELECT `+m.columns()+`
+        FROM `+m.table+`
+        WHERE $1 = '' OR `+m.numberColumn+` ILIKE $2 OR name ILIKE $2
+        ORDER BY `+m.numberColumn+` NULLS LAST, name
+    `, q, likePattern(q))
+    if err != nil {
+        log.Printf("%s一覧取得エラー: %v", m.label, err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("%s一覧の取得に失敗しました", m.label)})
+        return
+    }
+    defer rows.Close()
+
+    partners := []model.Partner{}
+    for rows.Next() {
+        p, err := scanPartner(rows)
+        if err != nil {
+            log.Printf("%s読み取りエラー: %v", m.label, err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("%sの読み取りに失敗しました", m.label)})
+            return
+        }
+        partners = append(partners, p)
+    }
+
+    c.JSON(http.StatusOK, partners)
+}
+
+// 入力補完用の検索（前方一致を優先して上位のみ返す）
+func lookupPartners(c *gin.Context, m partnerMaster) {
+    q := strings.TrimSpace(c.Query("q"))
+    limit := 10
+    if v := c.Query("limit"); v != "" {
+        if n, err := strconv.Atoi(v); err == nil && n > 0 && n <= 50 {
+            limit = n
+        }
+    }
+
+    rows, err := db.DB.Query(`
+        SELECT id, `+m.numberColumn+`, name
+        FROM `+m.table+`
+        WHERE `+m.numberColumn+` ILIKE $2 OR name ILIKE $2
+        ORDER BY
+            CASE WHEN `+m.numberColumn+` ILIKE $1 OR name ILIKE $1 THEN 0 ELSE 1 END,
+            name
+        LIMIT $3
+    `, escapeLike(q)+"%", likePattern(q), limit)
+    if err != nil {
+        log.Printf("%s検索エラー: %v", m.label, err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("%sの検索に失敗しました", m.label)})
+        return
+    }
+    defer rows.Close()
+
+    results := []gin.H{}
+    for rows.Next() {
+        var id int
+        var number sql.NullString
+        var name string
+        if err := rows.Scan(&id, &number, &name); err != nil {
+            log.Printf("%s読み取りエラー: %v", m.label, err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("%sの読み取りに失敗しました", m.label)})
+            return
+        }
+        results = append(results, gin.H{
+            "id":     id,
+            "number": number.String,
+            "name":   name,
+        })
+    }
+
+    c.JSON(http.StatusOK, results)
+}
+
+// 詳細取得
+func getPartner(c *gin.Context, m partnerMaster) {
+    id, err := strconv.Atoi(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("無効な%sIDです", m.label)})
+        return
+    }
+
+    p, err := scanPartner(db.DB.QueryRow("SELECT "+m.columns()+" FROM "+m.table+" WHERE id = $1", id))
+    if err == sql.ErrNoRows {
+        c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("%sが見つかりません", m.label)})
+        return
+    }
+    if err != nil {
+        log.Printf("%s取得エラー: %v", m.label, err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("%sの取得に失敗しました", m.label)})
+        return
+    }
+
+    c.JSON(http.StatusOK, p)
+}
+
+// リクエストの読み取り（番号・名称の前後の空白は除去）
+func bindPartnerRequest(c *gin.Context) (*model.PartnerRequest, bool) {
+    var req model.PartnerRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        log.Printf("リクエストデータバインドエラー: %v", err)
+        c.JSON(http.StatusBadRequest, gin.H{"error": "番号と名称を入力してください"})
+        return nil, false
+    }
+    req.Number = strings.TrimSpace(req.Number)
+    req.Name = strings.TrimSpace(req.Name)
+    if req.Number == "" || req.Name == "" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "番号と名称を入力してください"})
+        return nil, false
+    }
+    return &req, true
+}
+
+// 登録
+func createPartner(c *gin.Context, m partnerMaster) {
+    req, ok := bindPartnerRequest(c)
+    if !ok {
+        return
+    }
+
+    tx, err := db.BeginTx()
+    if err != nil {
+        log.Printf("トランザクション開始エラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "トランザクションの開始に失敗しました"})
+        return
+    }
+    defer tx.Rollback()
+
+    p, err := scanPartner(tx.QueryRow(`
+        INSERT INTO `+m.table+` (`+m.numberColumn+`, name, postal_code, address, phone, email, contact_name, notes)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+        RETURNING `+m.columns(),
+        req.Number, req.Name, req.PostalCode, req.Address, req.Phone, req.Email, req.ContactName, req.Notes,
+    ))
+    if isUniqueViolation(err) {
+        c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("%s番号 %s は既に登録されています", m.label, req.Number)})
+        return
+    }
+    if err != nil {
+        log.Printf("%s登録エラー: %v", m.label, err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("%sの登録に失敗しました", m.label)})
+        return
+    }
+
+    if !recordAudit(c, tx, audit.Entry{
+        Action:     audit.ActionCreate,
+        EntityType: m.auditEntity,
+        EntityID:   strconv.Itoa(p.ID),
+        After:      p,
+    }) {
+        return
+    }
+    if err := tx.Commit(); err != nil {
+        log.Printf("トランザクションコミットエラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "トランザクションのコミットに失敗しました"})
+        return
+    }
+
+    log.Printf("%sを登録しました: ID=%d", m.label, p.ID)
+    c.JSON(http.StatusOK, p)
+}
+
+// 更新
+// 出庫記録の顧客番号・名称は出庫時点の内容のまま残す
+func updatePartner(c *gin.Context, m partnerMaster) {
+    id, err := strconv.Atoi(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("無効な%sIDです", m.label)})
+        return
+    }
+    req, ok := bindPartnerRequest(c)
+    if !ok {
+        return
+    }
+
+    tx, err := db.BeginTx()
+    if err != nil {
+        log.Printf("トランザクション開始エラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "トランザクションの開始に失敗しました"})
+        return
+    }
+    defer tx.Rollback()
+
+    before, err := scanPartner(tx.QueryRow("SELECT "+m.columns()+" FROM "+m.table+" WHERE id = $1 FOR UPDATE", id))
+    if err == sql.ErrNoRows {
+        c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("%sが見つかりません", m.label)})
+        return
+    }
+    if err != nil {
+        log.Printf("%s取得エラー: %v", m.label, err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("%sの更新に失敗しました", m.label)})
+        return
+    }
+
+    p, err := scanPartner(tx.QueryRow(`
+        UPDATE `+m.table+`
+        SET `+m.numberColumn+` = $2, name = $3, postal_code = $4, address = $5,
+            phone = $6, email = $7, contact_name = $8, notes = $9
+        WHERE id = $1
+        RETURNING `+m.columns(),
+        id, req.Number, req.Name, req.PostalCode, req.Address, req.Phone, req.Email, req.ContactName, req.Notes,
+    ))
+    if err == sql.ErrNoRows {
+        c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("%sが見つかりません", m.label)})
+        return
+    }
+    if isUniqueViolation(err) {
+        c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("%s番号 %s は既に登録されています", m.label, req.Number)})
+        return
+    }
+    if err != nil {
+        log.Printf("%s更新エラー: %v", m.label, err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("%sの更新に失敗しました", m.label)})
+        return
+    }
+
+    if !recordAudit(c, tx, audit.Entry{
+        Action:     audit.ActionUpdate,
+        EntityType: m.auditEntity,
+        EntityID:   strconv.Itoa(p.ID),
+        Before:     before,
+        After:      p,
+    }) {
+        return
+    }
+    if err := tx.Commit(); err != nil {
+        log.Printf("トランザクションコミットエラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "トランザクションのコミットに失敗しました"})
+        return
+    }
+
+    log.Printf("%sを更新しました: ID=%d", m.label, p.ID)
+    c.JSON(http.StatusOK, p)
+}
+
+// 削除（出庫記録から参照されている場合は削除不可）
+func deletePartner(c *gin.Context, m partnerMaster) {
+    id, err := strconv.Atoi(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("無効な%sIDです", m.label)})
+        return
+    }
+
+    tx, err := db.BeginTx()
+    if err != nil {
+        log.Printf("トランザクション開始エラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "トランザクションの開始に失敗しました"})
+        return
+    }
+    defer tx.Rollback()
+
+    before, err := scanPartner(tx.QueryRow("SELECT "+m.columns()+" FROM "+m.table+" WHERE id = $1 FOR UPDATE", id))
+    if err == sql.ErrNoRows {
+        c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("%sが見つかりません", m.label)})
+        return
+    }
+    if err != nil {
+        log.Printf("%s取得エラー: %v", m.label, err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("%sの削除に失敗しました", m.label)})
+        return
+    }
+
+    var referenced bool
+    err = tx.QueryRow(
+        "SELECT EXISTS(SELECT 1 FROM outbound_records WHERE "+m.outboundColumn+" = $1)", id,
+    ).Scan(&referenced)
+    if err != nil {
+        log.Printf("%s参照確認エラー: %v", m.label, err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("%sの削除に失敗しました", m.label)})
+        return
+    }
+    if referenced {
+        c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("出庫記録で使用されている%sは削除できません", m.label)})
+        return
+    }
+
+    if _, err := tx.Exec("DELETE FROM "+m.table+" WHERE id = $1", id); err != nil {
+        log.Printf("%s削除エラー: %v", m.label, err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("%sの削除に失敗しました", m.label)})
+        return
+    }
+
+    if !recordAudit(c, tx, audit.Entry{
+        Action:     audit.ActionDelete,
+        EntityType: m.auditEntity,
+        EntityID:   strconv.Itoa(id),
+        Before:     before,
+    }) {
+        return
+    }
+    if err := tx.Commit(); err != nil {
+        log.Printf("トランザクションコミットエラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "トランザクションのコミットに失敗しました"})
+        return
+    }
+
+    log.Printf("%sを削除しました: ID=%d", m.label, id)
+    c.JSON(http.StatusOK, gin.H{"message": fmt.Sprintf("%sを削除しました", m.label)})
+}
+
+// 出庫時の顧客・購入者の確定
+// ID を指定した場合はそのマスタを、番号・名称のみの場合は一致するマスタを使用し、出庫記録にはマスタの番号・名称を保存する
+// いずれも未指定の場合は参照なし
+func resolvePartner(tx *sql.Tx, m partnerMaster, id *int, number, name *string) (sql.NullInt64, sql.NullString, sql.NullString, error) {
+    var partnerID sql.NullInt64
+    var partnerNumber, partnerName sql.NullString
+
+    var err error
+    switch {
+    case id != nil:
+        err = tx.QueryRow(
+            "SELECT id, "+m.numberColumn+", name FROM "+m.table+" WHERE id = $1", *id,
+        ).Scan(&partnerID, &partnerNumber, &partnerName)
+        if err == sql.ErrNoRows {
+            return partnerID, partnerNumber, partnerName, &requestError{
+                Status:  http.StatusBadRequest,
+                Message: fmt.Sprintf("指定された%sが見つかりません", m.label),
+            }
+        }
+    case strings.TrimSpace(stringValue(number)) != "" || strings.TrimSpace(stringValue(name)) != "":
+        // 番号が一致するものを優先し、番号がない場合は名称（表記ゆれを正規化）で照合する
+        err = tx.QueryRow(`
+            SELECT id, `+m.numberColumn+`, name FROM `+m.table+`
+            WHERE CASE
+                WHEN normalize_master_text($1) IS NOT NULL THEN `+m.numberColumn+` = normalize_master_text($1)
+                ELSE LOWER(name) = LOWER(normalize_master_text($2))
+            END
+            ORDER BY id
+            LIMIT 1
+        `, stringValue(number), stringValue(name)).Scan(&partnerID, &partnerNumber, &partnerName)
+        if err == sql.ErrNoRows {
+            return partnerID, partnerNumber, partnerName, &requestError{
+                Status:  http.StatusBadRequest,
+                Message: fmt.Sprintf("%sマスタに登録されていません。先に%sを登録してください", m.label, m.label),
+            }
+        }
+    }
+    return partnerID, partnerNumber, partnerName, err
+}
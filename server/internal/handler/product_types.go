@@ -1,18 +1,27 @@
 package handler
 
 import (
+    "database/sql"
+    "fmt"
     "log"
     "net/http"
+    "strconv"
+    "strings"
     "github.com/gin-gonic/gin"
+    "github.com/lib/pq"
+    "inventory-tracker/server/internal/audit"
     "inventory-tracker/server/internal/db"
+    "inventory-tracker/server/internal/model"
 )
 
 // 製品タイプ一覧取得ハンドラー
+// 既定では有効な製品タイプのみ（入庫の選択肢）を返し、includeInactive=true の場合は無効化した製品タイプも含める
 func GetProductTypes(c *gin.Context) {
     category := c.Param("category")
+    includeInactive := c.Query("includeInactive") == "true"
     log.Printf("製品タイプ取得リクエスト: カテゴリー = %s", category)
 
-    types, err := db.GetProductTypes(category)
+    types, err := db.GetProductTypes(category, includeInactive)
     log.Printf("取得された製品タイプ: %+v", types)
     if err != nil {
         c.JSON(http.StatusInternalServerError, gin.H{
@@ -24,9 +33,442 @@ func GetProductTypes(c *gin.Context) {
     c.JSON(http.StatusOK, types)
 }
 
+// 監査ログ用の製品タイプの内容
+const productTypeAuditQuery = `
+    SELECT to_jsonb(pt)
+    FROM product_types pt
+    WHERE pt.id = $1 AND pt.category = $2
+`
+
+// 監査ログ用のカテゴリ内の製品タイプの表示順
+const productTypeOrderAuditQuery = `
+    SELECT COALESCE(jsonb_agg(id ORDER BY sort_order, id), '[]'::jsonb)
+    FROM product_types
+    WHERE category = $1
+`
+
+// 製品タイプ名の読み取り（前後の空白は除去）
+func bindProductTypeName(c *gin.Context) (string, bool) {
+    var req struct {
+        Name string `json:"name" binding:"required"`
+    }
+    if err := c.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.Name) == "" {
+        c.JSON(http.StatusBadRequest, gin.H{
+            "error": "製品タイプ名を入力してください",
+        })
+        return "", false
+    }
+    return strings.TrimSpace(req.Name), true
+}
+
+// 製品タイプ追加ハンドラー
+// 追加した製品タイプはカテゴリ内の最後に表示する
+func CreateProductType(c *gin.Context) {
+    category := c.Param("category")
+    name, ok := bindProductTypeName(c)
+    if !ok {
+        return
+    }
+
+    tx, err := db.BeginTx()
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{
+            "error": "トランザクションの開始に失敗しました",
+        })
+        return
+    }
+    defer tx.Rollback()
+
+    var t model.ProductType
+    err = tx.QueryRow(`
+        INSERT INTO product_types (category, name, sort_order)
+        SELECT $1, $2, COALESCE(MAX(sort_order), 0) + 1
+        FROM product_types
+        WHERE category = $1
+        RETURNING id, category, name, active, sort_order, created_at::timestamp
+    `, category, name).Scan(&t.ID, &t.Category, &t.Name, &t.Active, &t.SortOrder, &t.CreatedAt)
+    if isUniqueViolation(err) {
+        c.JSON(http.StatusConflict, gin.H{
+            "error": "製品タイプ「" + name + "」は既に登録されています",
+        })
+        return
+    }
+    if err != nil {
+        log.Printf("製品タイプ登録エラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{
+            "error": "製品タイプの登録に失敗しました",
+        })
+        return
+    }
+
+    after, ok := auditSnapshot(c, tx, productTypeAuditQuery, t.ID, category)
+    if !ok {
+        return
+    }
+    if !recordAudit(c, tx, audit.Entry{
+        Action:     audit.ActionCreate,
+        EntityType: "product_type",
+        EntityID:   strconv.Itoa(t.ID),
+        After:      after,
+    }) {
+        return
+    }
+
+    if err := tx.Commit(); err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{
+            "error": "トランザクションのコミットに失敗しました",
+        })
+        return
+    }
+
+    log.Printf("製品タイプを登録しました: ID=%d, カテゴリー=%s, 名前=%s", t.ID, category, name)
+    c.JSON(http.StatusOK, t)
+}
+
+// 製品タイプ名変更ハンドラー
+// 製品は製品タイプIDで参照しているため、登録済みの製品も新しい名前で表示される
+func UpdateProductType(c *gin.Context) {
+    name, ok := bindProductTypeName(c)
+    if !ok {
+        return
+    }
+
+    updateProductType(c, "製品タイプを更新しました", func(tx *sql.Tx, typeID int) bool {
+        _, err := tx.Exec("UPDATE product_types SET name = $2 WHERE id = $1", typeID, name)
+        if isUniqueViolation(err) {
+            c.JSON(http.StatusConflict, gin.H{
+                "error": "製品タイプ「" + name + "」は既に登録されています",
+            })
+            return false
+        }
+        if err != nil {
+            log.Printf("製品タイプ更新エラー: %v", err)
+            c.JSON(http.StatusInternalServerError, gin.H{
+                "error": "製品タイプの更新に失敗しました",
+            })
+            return false
+        }
+        return true
+    })
+}
+
+// 製品タイプ無効化ハンドラー
+// 無効化した製品タイプは入庫の選択肢に表示されず、入庫もできない（登録済みの製品はそのまま）
+func DeactivateProductType(c *gin.Context) {
+    updateProductType(c, "製品タイプを無効化しました", func(tx *sql.Tx, typeID int) bool {
+        if _, err := tx.Exec("UPDATE product_types SET active = FALSE WHERE id = $1 AND active", typeID); err != nil {
+            log.Printf("製品タイプ無効化エラー: %v", err)
+            c.JSON(http.StatusInternalServerError, gin.H{
+                "error": "製品タイプの無効化に失敗しました",
+            })
+            return false
+        }
+        return true
+    })
+}
+
+// 製品タイプ再有効化ハンドラー
+func ReactivateProductType(c *gin.Context) {
+    updateProductType(c, "製品タイプを再有効化しました", func(tx *sql.Tx, typeID int) bool {
+        if _, err := tx.Exec("UPDATE product_types SET active = TRUE WHERE id = $1 AND NOT active", typeID); err != nil {
+            log.Printf("製品タイプ再有効化エラー: %v", err)
+            c.JSON(http.StatusInternalServerError, gin.H{
+                "error": "製品タイプの再有効化に失敗しました",
+            })
+            return false
+        }
+        return true
+    })
+}
+
+// 製品タイプの更新処理（対象の行をロックして update を実行し、変更前後を監査ログに記録する）
+// update はエラー時にレスポンスを返して false を返す
+func updateProductType(c *gin.Context, message string, update func(tx *sql.Tx, typeID int) bool) {
+    category := c.Param("category")
+    typeID, err := strconv.Atoi(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{
+            "error": "無効な製品タイプIDです",
+        })
+        return
+    }
+
+    tx, err := db.BeginTx()
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{
+            "error": "トランザクションの開始に失敗しました",
+        })
+        return
+    }
+    defer tx.Rollback()
+
+    before, ok := auditSnapshot(c, tx, productTypeAuditQuery+" FOR UPDATE", typeID, category)
+    if !ok {
+        return
+    }
+    if before == nil {
+        c.JSON(http.StatusNotFound, gin.H{
+            "error": "製品タイプが見つかりません",
+        })
+        return
+    }
+
+    if !update(tx, typeID) {
+        return
+    }
+
+    after, ok := auditSnapshot(c, tx, productTypeAuditQuery, typeID, category)
+    if !ok {
+        return
+    }
+    if !recordAudit(c, tx, audit.Entry{
+        Action:     audit.ActionUpdate,
+        EntityType: "product_type",
+        EntityID:   strconv.Itoa(typeID),
+        Before:     before,
+        After:      after,
+    }) {
+        return
+    }
+
+    if err := tx.Commit(); err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{
+            "error": "トランザクションのコミットに失敗しました",
+        })
+        return
+    }
+
+    log.Printf("%s: ID=%d", message, typeID)
+    c.JSON(http.StatusOK, gin.H{
+        "message": message,
+    })
+}
+
+// 製品タイプ並び替えハンドラー
+// カテゴリ内のすべての製品タイプ（無効化したものを含む）のIDを表示順に指定する
+func ReorderProductTypes(c *gin.Context) {
+    category := c.Param("category")
+    var req struct {
+        IDs []int `json:"ids" binding:"required"`
+    }
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{
+            "error": "製品タイプIDを表示順に指定してください",
+        })
+        return
+    }
+
+    tx, err := db.BeginTx()
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{
+            "error": "トランザクションの開始に失敗しました",
+        })
+        return
+    }
+    defer tx.Rollback()
+
+    // 並び替え中に製品タイプが追加・変更されないよう、カテゴリ内の行をロックする
+    rows, err := tx.Query("SELECT id FROM product_types WHERE category = $1 FOR UPDATE", category)
+    if err != nil {
+        log.Printf("製品タイプ取得エラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{
+            "error": "製品タイプの並び替えに失敗しました",
+        })
+        return
+    }
+    current := make(map[int]bool)
+    for rows.Next() {
+        var id int
+        if err := rows.Scan(&id); err != nil {
+            rows.Close()
+            log.Printf("製品タイプ読み取りエラー: %v", err)
+            c.JSON(http.StatusInternalServerError, gin.H{
+                "error": "製品タイプの並び替えに失敗しました",
+            })
+            return
+        }
+        current[id] = true
+    }
+    rows.Close()
+    if err := rows.Err(); err != nil {
+        log.Printf("製品タイプ読み取りエラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{
+            "error": "製品タイプの並び替えに失敗しました",
+        })
+        return
+    }
+    // 指定されたIDがカテゴリ内の製品タイプと過不足なく一致すること
+    seen := make(map[int]bool)
+    for _, id := range req.IDs {
+        if !current[id] || seen[id] {
+            c.JSON(http.StatusBadRequest, gin.H{
+                "error": fmt.Sprintf("製品タイプID %d はこのカテゴリの並び替えに指定できません", id),
+            })
+            return
+        }
+        seen[id] = true
+    }
+    if len(seen) != len(current) {
+        c.JSON(http.StatusBadRequest, gin.H{
+            "error": "カテゴリ内のすべての製品タイプを指定してください",
+        })
+        return
+    }
+
+    before, ok := auditSnapshot(c, tx, productTypeOrderAuditQuery, category)
+    if !ok {
+        return
+    }
+
+    _, err = tx.Exec(`
+        UPDATE product_types pt SET sort_order = o.ord
+        FROM unnest($1::integer[]) WITH ORDINALITY AS o(id, ord)
+        WHERE pt.id = o.id AND pt.sort_order <> o.ord
+    `, pq.Array(req.IDs))
+    if err != nil {
+        log.Printf("製品タイプ並び替えエラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{
+            "error": "製品タイプの並び替えに失敗しました",
+        })
+        return
+    }
+
+    after, ok := auditSnapshot(c, tx, productTypeOrderAuditQuery, category)
+    if !ok {
+        return
+    }
+    if !recordAudit(c, tx, audit.Entry{
+        Action:     audit.ActionUpdate,
+        EntityType: "product_type_order",
+        EntityID:   category,
+        Before:     before,
+        After:      after,
+    }) {
+        return
+    }
+
+    if err := tx.Commit(); err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{
+            "error": "トランザクションのコミットに失敗しました",
+        })
+        return
+    }
+
+    log.Printf("製品タイプを並び替えました: カテゴリー=%s", category)
+    c.JSON(http.StatusOK, gin.H{
+        "message": "製品タイプを並び替えました",
+    })
+}
+
+// 製品タイプ削除ハンドラー
+// 製品・製品タイプ用の追加項目から参照されている製品タイプは削除できない（無効化を使用する）
+func DeleteProductType(c *gin.Context) {
+    category := c.Param("category")
+    typeID, err := strconv.Atoi(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{
+            "error": "無効な製品タイプIDです",
+        })
+        return
+    }
+
+    tx, err := db.BeginTx()
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{
+            "error": "トランザクションの開始に失敗しました",
+        })
+        return
+    }
+    defer tx.Rollback()
+
+    // 行をロックするため、確認中に入庫で参照されることはない
+    before, ok := auditSnapshot(c, tx, productTypeAuditQuery+" FOR UPDATE", typeID, category)
+    if !ok {
+        return
+    }
+    if before == nil {
+        c.JSON(http.StatusNotFound, gin.H{
+            "error": "製品タイプが見つかりません",
+        })
+        return
+    }
+
+    var inUse bool
+    if err := tx.QueryRow(
+        "SELECT EXISTS(SELECT 1 FROM products WHERE type_id = $1)", typeID,
+    ).Scan(&inUse); err != nil {
+        log.Printf("製品タイプ使用状況確認エラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{
+            "error": "製品タイプ使用状況の確認に失敗しました",
+        })
+        return
+    }
+    if inUse {
+        c.JSON(http.StatusConflict, gin.H{
+            "error": "この製品タイプは製品で使用されているため削除できません。無効化してください",
+        })
+        return
+    }
+
+    // 製品タイプ用の追加項目がある場合は削除できない（追加項目の削除を監査ログに記録するため、先に追加項目を削除する）
+    var hasAttributes bool
+    if err := tx.QueryRow(
+        "SELECT EXISTS(SELECT 1 FROM attribute_definitions WHERE product_type_id = $1)", typeID,
+    ).Scan(&hasAttributes); err != nil {
+        log.Printf("製品タイプ使用状況確認エラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{
+            "error": "製品タイプ使用状況の確認に失敗しました",
+        })
+        return
+    }
+    if hasAttributes {
+        c.JSON(http.StatusConflict, gin.H{
+            "error": "この製品タイプ用の追加項目が登録されているため削除できません。追加項目を削除するか、製品タイプを無効化してください",
+        })
+        return
+    }
+
+    _, err = tx.Exec("DELETE FROM product_types WHERE id = $1", typeID)
+    if isForeignKeyViolation(err) {
+        c.JSON(http.StatusConflict, gin.H{
+            "error": "この製品タイプは製品または追加項目で使用されているため削除できません。無効化してください",
+        })
+        return
+    }
+    if err != nil {
+        log.Printf("製品タイプ削除エラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{
+            "error": "製品タイプの削除に失敗しました",
+        })
+        return
+    }
+
+    if !recordAudit(c, tx, audit.Entry{
+        Action:     audit.ActionDelete,
+        EntityType: "product_type",
+        EntityID:   strconv.Itoa(typeID),
+        Before:     before,
+    }) {
+        return
+    }
+
+    if err := tx.Commit(); err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{
+            "error": "トランザクションのコミットに失敗しました",
+        })
+        return
+    }
+
+    log.Printf("製品タイプを削除しました: ID=%d", typeID)
+    c.JSON(http.StatusOK, gin.H{
+        "success": true,
+    })
+}
+
 // PC型番一覧取得ハンドラー
+// 既定では有効な型番のみ（入庫の選択肢）を返し、includeInactive=true の場合は無効化した型番も含める
 func GetPCModelNumbers(c *gin.Context) {
-    models, err := db.GetPCModelNumbers()
+    models, err := db.GetPCModelNumbers(c.Query("includeInactive") == "true")
     if err != nil {
         c.JSON(http.StatusInternalServerError, gin.H{
             "error": "PC型番の取得に失敗しました",
@@ -37,56 +479,304 @@ func GetPCModelNumbers(c *gin.Context) {
     c.JSON(http.StatusOK, models)
 }
 
+// 監査ログ用のPC型番の内容
+const pcModelNumberAuditQuery = `
+    SELECT to_jsonb(m)
+    FROM pc_model_numbers m
+    WHERE m.model_number = $1
+`
+
+// PC型番の列（scanPCModelNumber で読み取る順）
+const pcModelNumberColumns = `
+    id, model_number, manufacturer, display_name, default_warranty_period,
+    cpu, memory, storage, os, active, created_at, updated_at
+`
+
+// PC型番の登録・更新内容（前後の空白は除去）
+type pcModelNumberRequest struct {
+    ModelNumber           string `json:"modelNumber" binding:"required"`
+    Manufacturer          string `json:"manufacturer"`
+    DisplayName           string `json:"displayName"`
+    DefaultWarrantyPeriod *int   `json:"defaultWarrantyPeriod"`
+    CPU                   string `json:"cpu"`
+    Memory                string `json:"memory"`
+    Storage               string `json:"storage"`
+    OS                    string `json:"os"`
+}
+
+// PC型番の登録・更新内容の読み取り
+func bindPCModelNumberRequest(c *gin.Context) (pcModelNumberRequest, bool) {
+    var req pcModelNumberRequest
+    if err := c.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.ModelNumber) == "" {
+        c.JSON(http.StatusBadRequest, gin.H{
+            "error": "型番を入力してください",
+        })
+        return req, false
+    }
+    if req.DefaultWarrantyPeriod != nil && *req.DefaultWarrantyPeriod < 0 {
+        c.JSON(http.StatusBadRequest, gin.H{
+            "error": "既定の保証期間は0以上で入力してください",
+        })
+        return req, false
+    }
+
+    req.ModelNumber = strings.TrimSpace(req.ModelNumber)
+    req.Manufacturer = strings.TrimSpace(req.Manufacturer)
+    req.DisplayName = strings.TrimSpace(req.DisplayName)
+    req.CPU = strings.TrimSpace(req.CPU)
+    req.Memory = strings.TrimSpace(req.Memory)
+    req.Storage = strings.TrimSpace(req.Storage)
+    req.OS = strings.TrimSpace(req.OS)
+    return req, true
+}
+
+// PC型番の読み取り（pcModelNumberColumns の順）
+func scanPCModelNumber(row *sql.Row) (model.PCModelNumber, error) {
+    var m model.PCModelNumber
+    var warrantyPeriod sql.NullInt32
+    err := row.Scan(
+        &m.ID, &m.ModelNumber, &m.Manufacturer, &m.DisplayName, &warrantyPeriod,
+        &m.CPU, &m.Memory, &m.Storage, &m.OS, &m.Active, &m.CreatedAt, &m.UpdatedAt,
+    )
+    if warrantyPeriod.Valid {
+        period := int(warrantyPeriod.Int32)
+        m.DefaultWarrantyPeriod = &period
+    }
+    return m, err
+}
+
 // PC型番追加ハンドラー
 func AddPCModelNumber(c *gin.Context) {
-    var req struct {
-        ModelNumber string `json:"modelNumber" binding:"required"`
+    req, ok := bindPCModelNumberRequest(c)
+    if !ok {
+        return
     }
 
-    if err := c.ShouldBindJSON(&req); err != nil {
-        c.JSON(http.StatusBadRequest, gin.H{
-            "error": "無効なリクエストデータです",
+    tx, err := db.BeginTx()
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{
+            "error": "トランザクションの開始に失敗しました",
         })
         return
     }
+    defer tx.Rollback()
 
-    // 既存の型番チェック
-    var exists bool
-    err := db.DB.QueryRow(
-        "SELECT EXISTS(SELECT 1 FROM pc_model_numbers WHERE model_number = $1)",
-        req.ModelNumber,
-    ).Scan(&exists)
+    // 新規型番の登録
+    m, err := scanPCModelNumber(tx.QueryRow(`
+        INSERT INTO pc_model_numbers (
+            model_number, manufacturer, display_name, default_warranty_period,
+            cpu, memory, storage, os
+        ) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+        RETURNING `+pcModelNumberColumns,
+        req.ModelNumber, req.Manufacturer, req.DisplayName, req.DefaultWarrantyPeriod,
+        req.CPU, req.Memory, req.Storage, req.OS,
+    ))
 
+    // 登録済みの型番（同時に登録された場合を含む）
+    if isUniqueViolation(err) {
+        c.JSON(http.StatusConflict, gin.H{
+            "error": "型番「" + req.ModelNumber + "」は既に登録されています",
+        })
+        return
+    }
     if err != nil {
+        log.Printf("型番登録エラー: %v", err)
         c.JSON(http.StatusInternalServerError, gin.H{
-            "error": "型番チェックに失敗しました",
+            "error": "型番の登録に失敗しました",
         })
         return
     }
 
-    if exists {
-        c.JSON(http.StatusBadRequest, gin.H{
-            "error": "この型番は既に登録されています",
+    after, ok := auditSnapshot(c, tx, pcModelNumberAuditQuery, m.ModelNumber)
+    if !ok {
+        return
+    }
+    if !recordAudit(c, tx, audit.Entry{
+        Action:     audit.ActionCreate,
+        EntityType: "pc_model_number",
+        EntityID:   m.ModelNumber,
+        After:      after,
+    }) {
+        return
+    }
+
+    if err := tx.Commit(); err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{
+            "error": "トランザクションのコミットに失敗しました",
         })
         return
     }
 
-    // 新規型番の登録
-    _, err = db.DB.Exec(
-        "INSERT INTO pc_model_numbers (model_number) VALUES ($1)",
-        req.ModelNumber,
-    )
+    c.JSON(http.StatusOK, m)
+}
 
+// PC型番更新ハンドラー
+// 型番を変更した場合は、登録済みのPC詳細情報の型番も外部キー（ON UPDATE CASCADE）により変更される
+func UpdatePCModelNumber(c *gin.Context) {
+    modelNumber := c.Param("modelNumber")
+    req, ok := bindPCModelNumberRequest(c)
+    if !ok {
+        return
+    }
+
+    tx, err := db.BeginTx()
     if err != nil {
         c.JSON(http.StatusInternalServerError, gin.H{
-            "error": "型番の登録に失敗しました",
+            "error": "トランザクションの開始に失敗しました",
         })
         return
     }
+    defer tx.Rollback()
 
+    before, ok := auditSnapshot(c, tx, pcModelNumberAuditQuery+" FOR UPDATE", modelNumber)
+    if !ok {
+        return
+    }
+    if before == nil {
+        c.JSON(http.StatusNotFound, gin.H{
+            "error": "指定された型番が見つかりません",
+        })
+        return
+    }
+
+    // 型番の変更により更新されるPCの件数
+    var affectedCount int
+    if req.ModelNumber != modelNumber {
+        if err := tx.QueryRow(
+            "SELECT COUNT(*) FROM pc_details WHERE model_number = $1",
+            modelNumber,
+        ).Scan(&affectedCount); err != nil {
+            log.Printf("型番使用状況の確認エラー: %v", err)
+            c.JSON(http.StatusInternalServerError, gin.H{
+                "error": "型番使用状況の確認に失敗しました",
+            })
+            return
+        }
+    }
+
+    m, err := scanPCModelNumber(tx.QueryRow(`
+        UPDATE pc_model_numbers SET
+            model_number = $2, manufacturer = $3, display_name = $4, default_warranty_period = $5,
+            cpu = $6, memory = $7, storage = $8, os = $9
+        WHERE model_number = $1
+        RETURNING `+pcModelNumberColumns,
+        modelNumber, req.ModelNumber, req.Manufacturer, req.DisplayName, req.DefaultWarrantyPeriod,
+        req.CPU, req.Memory, req.Storage, req.OS,
+    ))
+    if isUniqueViolation(err) {
+        c.JSON(http.StatusConflict, gin.H{
+            "error": "型番「" + req.ModelNumber + "」は既に登録されています",
+        })
+        return
+    }
+    if err != nil {
+        log.Printf("型番更新エラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{
+            "error": "型番の更新に失敗しました",
+        })
+        return
+    }
+
+    after, ok := auditSnapshot(c, tx, pcModelNumberAuditQuery, m.ModelNumber)
+    if !ok {
+        return
+    }
+    if !recordAudit(c, tx, audit.Entry{
+        Action:     audit.ActionUpdate,
+        EntityType: "pc_model_number",
+        EntityID:   modelNumber,
+        Before:     before,
+        After:      after,
+    }) {
+        return
+    }
+
+    if err := tx.Commit(); err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{
+            "error": "トランザクションのコミットに失敗しました",
+        })
+        return
+    }
+
+    if m.ModelNumber != modelNumber {
+        log.Printf("型番を変更しました: %s → %s（PC %d 件）", modelNumber, m.ModelNumber, affectedCount)
+    }
     c.JSON(http.StatusOK, gin.H{
-        "success": true,
-        "modelNumber": req.ModelNumber,
+        "pcModelNumber": m,
+        "affectedCount": affectedCount,
+    })
+}
+
+// PC型番無効化ハンドラー
+// 無効化した型番は入庫の選択肢に表示されず、入庫もできない（登録済みのPCはそのまま）
+func DeactivatePCModelNumber(c *gin.Context) {
+    setPCModelNumberActive(c, false, "型番を無効化しました")
+}
+
+// PC型番再有効化ハンドラー
+func ReactivatePCModelNumber(c *gin.Context) {
+    setPCModelNumberActive(c, true, "型番を再有効化しました")
+}
+
+// PC型番の有効・無効の切り替え（変更前後を監査ログに記録する）
+func setPCModelNumberActive(c *gin.Context, active bool, message string) {
+    modelNumber := c.Param("modelNumber")
+
+    tx, err := db.BeginTx()
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{
+            "error": "トランザクションの開始に失敗しました",
+        })
+        return
+    }
+    defer tx.Rollback()
+
+    before, ok := auditSnapshot(c, tx, pcModelNumberAuditQuery+" FOR UPDATE", modelNumber)
+    if !ok {
+        return
+    }
+    if before == nil {
+        c.JSON(http.StatusNotFound, gin.H{
+            "error": "指定された型番が見つかりません",
+        })
+        return
+    }
+
+    if _, err := tx.Exec(
+        "UPDATE pc_model_numbers SET active = $2 WHERE model_number = $1 AND active <> $2",
+        modelNumber, active,
+    ); err != nil {
+        log.Printf("型番更新エラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{
+            "error": "型番の更新に失敗しました",
+        })
+        return
+    }
+
+    after, ok := auditSnapshot(c, tx, pcModelNumberAuditQuery, modelNumber)
+    if !ok {
+        return
+    }
+    if !recordAudit(c, tx, audit.Entry{
+        Action:     audit.ActionUpdate,
+        EntityType: "pc_model_number",
+        EntityID:   modelNumber,
+        Before:     before,
+        After:      after,
+    }) {
+        return
+    }
+
+    if err := tx.Commit(); err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{
+            "error": "トランザクションのコミットに失敗しました",
+        })
+        return
+    }
+
+    log.Printf("%s: %s", message, modelNumber)
+    c.JSON(http.StatusOK, gin.H{
+        "message": message,
     })
 }
 
@@ -94,51 +784,65 @@ func AddPCModelNumber(c *gin.Context) {
 func DeletePCModelNumber(c *gin.Context) {
     modelNumber := c.Param("modelNumber")
 
+    tx, err := db.BeginTx()
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{
+            "error": "トランザクションの開始に失敗しました",
+        })
+        return
+    }
+    defer tx.Rollback()
+
+    // 行をロックするため、確認中に入庫で参照されることはない
+    before, ok := auditSnapshot(c, tx, pcModelNumberAuditQuery+" FOR UPDATE", modelNumber)
+    if !ok {
+        return
+    }
+    if before == nil {
+        c.JSON(http.StatusNotFound, gin.H{
+            "error": "指定された型番が見つかりません",
+        })
+        return
+    }
+
     // 使用中の型番チェック
     var inUse bool
-    err := db.DB.QueryRow(
+    if err := tx.QueryRow(
         "SELECT EXISTS(SELECT 1 FROM pc_details WHERE model_number = $1)",
         modelNumber,
-    ).Scan(&inUse)
-
-    if err != nil {
+    ).Scan(&inUse); err != nil {
         c.JSON(http.StatusInternalServerError, gin.H{
             "error": "型番使用状況の確認に失敗しました",
         })
         return
     }
-
     if inUse {
-        c.JSON(http.StatusBadRequest, gin.H{
-            "error": "この型番は使用中のため削除できません",
+        c.JSON(http.StatusConflict, gin.H{
+            "error": "この型番は使用中のため削除できません（入庫の選択肢から外す場合は無効化してください）",
         })
         return
     }
 
     // 型番の削除
-    result, err := db.DB.Exec(
-        "DELETE FROM pc_model_numbers WHERE model_number = $1",
-        modelNumber,
-    )
-
-    if err != nil {
+    if _, err := tx.Exec("DELETE FROM pc_model_numbers WHERE model_number = $1", modelNumber); err != nil {
         c.JSON(http.StatusInternalServerError, gin.H{
             "error": "型番の削除に失敗しました",
         })
         return
     }
 
-    rowsAffected, err := result.RowsAffected()
-    if err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{
-            "error": "削除結果の確認に失敗しました",
-        })
+    if !recordAudit(c, tx, audit.Entry{
+        Action:     audit.ActionDelete,
+        EntityType: "pc_model_number",
+        EntityID:   modelNumber,
+        Before:     before,
+    }) {
         return
     }
 
-    if rowsAffected == 0 {
-        c.JSON(http.StatusNotFound, gin.H{
-            "error": "指定された型番が見つかりません",
+    if err := tx.Commit(); err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{
+            "error": "トランザクションのコミットに失敗しました",
         })
         return
     }
@@ -0,0 +1,393 @@
+package handler
+
+import (
+    "database/sql"
+    "encoding/json"
+    "log"
+    "net/http"
+    "sort"
+    "strings"
+    "time"
+    "github.com/gin-gonic/gin"
+    "github.com/lib/pq"
+    "inventory-tracker/server/internal/auth"
+    "inventory-tracker/server/internal/db"
+    "inventory-tracker/server/internal/model"
+)
+
+// 製品履歴のイベント種別と並び順（同日で登録日時・記録IDでも順序が決まらない場合に使用する）
+var timelineEventOrder = map[string]int{
+    "inbound":       0,
+    "outbound":      1,
+    "outbound_void": 2,
+    "return":        3,
+    "inbound_void":  4,
+}
+
+// 製品履歴の同日判定に使用するタイムゾーン（日時は UTC で保存されている）
+var timelineLocation = time.FixedZone("JST", 9*60*60)
+
+// 製品履歴のイベント
+// date は入出庫日・返品日（取消は取消日時）、recordedAt は記録の登録日時、
+// table と id は同日・同時刻の記録を登録順に並べるための記録のテーブルとID
+type timelineEvent struct {
+    kind       string
+    date       time.Time
+    recordedAt time.Time
+    table      string
+    id         int
+    event      gin.H
+}
+
+// 製品詳細取得ハンドラー
+// 製品の基本情報・PC/ベスト詳細と、入庫・出庫・出庫取消・返品・入庫取消を日付順に並べた履歴を返す
+func GetProductDetail(c *gin.Context) {
+    productID := strings.TrimSpace(c.Param("productId"))
+
+    var p struct {
+        ID             int
+        ProductID      string
+        LotNumber      sql.NullString
+        InboundNumber  string
+        Status         string
+        CreatedAt      time.Time
+        UpdatedAt      time.Time
+        TypeID         int
+        TypeName       string
+        Category       string
+        ModelNumber    sql.NullString
+        SerialNumber   sql.NullString
+        PurchaseDate   sql.NullTime
+        WarrantyPeriod sql.NullInt32
+        WarrantyEnd    sql.NullTime
+        VestType       sql.NullString
+        VestSize       sql.NullString
+        VestHasLogo    sql.NullBool
+        Attributes     []byte
+    }
+    err := db.DB.QueryRow(`
+        SELECT
+            p.id, p.product_id, p.lot_number, p.inbound_number, p.status, p.created_at, p.updated_at,
+            pt.id, pt.name, pt.category,
+            pc.model_number, pc.serial_number, pc.purchase_date, pc.warranty_period,
+            ` + pcWarrantyEndColumn + `,
+            vd.type, vd.size, vd.has_logo, p.attributes
+        FROM products p
+        INNER JOIN product_types pt ON p.type_id = pt.id
+        LEFT JOIN pc_details pc ON p.product_id = pc.product_id
+        LEFT JOIN vest_details vd ON p.product_id = vd.product_id
+        WHERE p.product_id = $1
+    `, productID).Scan(
+        &p.ID, &p.ProductID, &p.LotNumber, &p.InboundNumber, &p.Status, &p.CreatedAt, &p.UpdatedAt,
+        &p.TypeID, &p.TypeName, &p.Category,
+        &p.ModelNumber, &p.SerialNumber, &p.PurchaseDate, &p.WarrantyPeriod, &p.WarrantyEnd,
+        &p.VestType, &p.VestSize, &p.VestHasLogo, &p.Attributes,
+    )
+    found := err == nil
+    if err != nil && err != sql.ErrNoRows {
+        log.Printf("製品取得エラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "製品の取得に失敗しました"})
+        return
+    }
+
+    staff, _ := auth.CurrentStaff(c)
+    if found && !auth.CanAccessCategory(staff, p.Category) {
+        c.JSON(http.StatusForbidden, gin.H{"error": "この操作を行う権限がありません: カテゴリ " + p.Category + " の担当ではありません"})
+        return
+    }
+    if !found {
+        // 担当カテゴリ以外で入庫取消された製品は取消履歴も返さない
+        accessible, err := voidedProductAccessible(staff, productID)
+        if err != nil {
+            log.Printf("製品取得エラー: %v", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "製品の取得に失敗しました"})
+            return
+        }
+        if !accessible {
+            c.JSON(http.StatusNotFound, gin.H{"error": "製品が見つかりません"})
+            return
+        }
+    }
+
+    timeline, err := productTimeline(productID)
+    if err != nil {
+        log.Printf("製品履歴取得エラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "製品履歴の取得に失敗しました"})
+        return
+    }
+
+    // 入庫取消により削除された製品は取消履歴のみ返す
+    if !found {
+        response := gin.H{"error": "製品が見つかりません"}
+        if len(timeline) > 0 {
+            response["timeline"] = timeline
+        }
+        c.JSON(http.StatusNotFound, response)
+        return
+    }
+
+    product := gin.H{
+        "id":            p.ID,
+        "productId":     p.ProductID,
+        "lotNumber":     p.LotNumber.String,
+        "inboundNumber": p.InboundNumber,
+        "status":        p.Status,
+        "createdAt":     p.CreatedAt,
+        "updatedAt":     p.UpdatedAt,
+        "type": gin.H{
+            "id":       p.TypeID,
+            "name":     p.TypeName,
+            "category": p.Category,
+        },
+        "attributes": attributesJSON(p.Attributes),
+    }
+    if p.VestType.Valid {
+        product["vestDetails"] = vestDetailsJSON(p.VestType, p.VestSize, p.VestHasLogo)
+    }
+    if p.ModelNumber.Valid {
+        product["pcDetails"] = gin.H{
+            "modelNumber":     p.ModelNumber.String,
+            "serialNumber":    p.SerialNumber.String,
+            "purchaseDate":    p.PurchaseDate.Time,
+            "warrantyPeriod":  p.WarrantyPeriod.Int32,
+            "warrantyEndDate": nullableTime(p.WarrantyEnd),
+        }
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "product":  product,
+        "timeline": timeline,
+    })
+}
+
+// 製品の履歴を日付順に取得
+func productTimeline(productID string) ([]gin.H, error) {
+    var events []timelineEvent
+
+    // 入庫
+    rows, err := db.DB.Query(`
+        SELECT ir.id, ir.inbound_number, ir.inbound_date, COALESCE(ir.created_at, ir.inbound_date), s.id, s.name
+        FROM inbound_records ir
+        LEFT JOIN staff s ON ir.staff_id = s.id
+        WHERE ir.product_id = $1
+        ORDER BY ir.id
+    `, productID)
+    if err != nil {
+        return nil, err
+    }
+    for rows.Next() {
+        var id int
+        var number string
+        var date, recordedAt time.Time
+        var staffID sql.NullInt32
+        var staffName sql.NullString
+        if err := rows.Scan(&id, &number, &date, &recordedAt, &staffID, &staffName); err != nil {
+            rows.Close()
+            return nil, err
+        }
+        events = append(events, timelineEvent{"inbound", date, recordedAt, "inbound_records", id, gin.H{
+            "date":          date,
+            "inboundNumber": number,
+            "staff":         staffJSON(staffID, staffName),
+        }})
+    }
+    rows.Close()
+    if err := rows.Err(); err != nil {
+        return nil, err
+    }
+
+    // 出庫と出庫取消
+    rows, err = db.DB.Query(`
+        SELECT
+            obr.id, obr.outbound_number, obr.outbound_date, COALESCE(obr.created_at, obr.outbound_date), s.id, s.name,
+            obr.customer_number, obr.customer_name, obr.purchaser_number, obr.purchaser_name, obr.notes,
+            obr.voided_at, vs.id, vs.name, obr.void_reason
+        FROM outbound_records obr
+        LEFT JOIN staff s ON obr.staff_id = s.id
+        LEFT JOIN staff vs ON obr.voided_by = vs.id
+        WHERE obr.product_id = $1
+        ORDER BY obr.id
+    `, productID)
+    if err != nil {
+        return nil, err
+    }
+    for rows.Next() {
+        var o struct {
+            ID              int
+            Number          string
+            Date            time.Time
+            RecordedAt      time.Time
+            StaffID         sql.NullInt32
+            StaffName       sql.NullString
+            CustomerNumber  sql.NullString
+            CustomerName    sql.NullString
+            PurchaserNumber sql.NullString
+            PurchaserName   sql.NullString
+            Notes           sql.NullString
+            VoidedAt        sql.NullTime
+            VoidedByID      sql.NullInt32
+            VoidedByName    sql.NullString
+            VoidReason      sql.NullString
+        }
+        err := rows.Scan(
+            &o.ID, &o.Number, &o.Date, &o.RecordedAt, &o.StaffID, &o.StaffName,
+            &o.CustomerNumber, &o.CustomerName, &o.PurchaserNumber, &o.PurchaserName, &o.Notes,
+            &o.VoidedAt, &o.VoidedByID, &o.VoidedByName, &o.VoidReason,
+        )
+        if err != nil {
+            rows.Close()
+            return nil, err
+        }
+        events = append(events, timelineEvent{"outbound", o.Date, o.RecordedAt, "outbound_records", o.ID, gin.H{
+            "date":            o.Date,
+            "outboundNumber":  o.Number,
+            "staff":           staffJSON(o.StaffID, o.StaffName),
+            "customerNumber":  o.CustomerNumber.String,
+            "customerName":    o.CustomerName.String,
+            "purchaserNumber": o.PurchaserNumber.String,
+            "purchaserName":   o.PurchaserName.String,
+            "notes":           o.Notes.String,
+            "voided":          o.VoidedAt.Valid,
+        }})
+        if o.VoidedAt.Valid {
+            events = append(events, timelineEvent{"outbound_void", o.VoidedAt.Time, o.VoidedAt.Time, "outbound_records", o.ID, gin.H{
+                "date":           o.VoidedAt.Time,
+                "outboundNumber": o.Number,
+                "staff":          staffJSON(o.VoidedByID, o.VoidedByName),
+                "reason":         o.VoidReason.String,
+            }})
+        }
+    }
+    rows.Close()
+    if err := rows.Err(); err != nil {
+        return nil, err
+    }
+
+    // 返品
+    rows, err = db.DB.Query(`
+        SELECT rr.id, rr.return_number, rr.return_date, COALESCE(rr.created_at, rr.return_date),
+            s.id, s.name, rr.reason, rr.notes, obr.outbound_number
+        FROM return_records rr
+        INNER JOIN outbound_records obr ON rr.outbound_record_id = obr.id
+        LEFT JOIN staff s ON rr.staff_id = s.id
+        WHERE rr.product_id = $1
+        ORDER BY rr.id
+    `, productID)
+    if err != nil {
+        return nil, err
+    }
+    for rows.Next() {
+        var id int
+        var number, reason, outboundNumber string
+        var date, recordedAt time.Time
+        var staffID sql.NullInt32
+        var staffName, notes sql.NullString
+        if err := rows.Scan(&id, &number, &date, &recordedAt, &staffID, &staffName, &reason, &notes, &outboundNumber); err != nil {
+            rows.Close()
+            return nil, err
+        }
+        events = append(events, timelineEvent{"return", date, recordedAt, "return_records", id, gin.H{
+            "date":           date,
+            "returnNumber":   number,
+            "outboundNumber": outboundNumber,
+            "staff":          staffJSON(staffID, staffName),
+            "reason":         reason,
+            "notes":          notes.String,
+        }})
+    }
+    rows.Close()
+    if err := rows.Err(); err != nil {
+        return nil, err
+    }
+
+    // 入庫取消（取消時の内容に製品が含まれるもの）
+    filter, _ := json.Marshal([]gin.H{{"product_id": productID}})
+    rows, err = db.DB.Query(`
+        SELECT iv.id, iv.inbound_number, iv.voided_at, s.id, s.name, iv.reason
+        FROM inbound_voids iv
+        LEFT JOIN staff s ON iv.staff_id = s.id
+        WHERE iv.snapshot->'products' @> $1::jsonb
+        ORDER BY iv.id
+    `, string(filter))
+    if err != nil {
+        return nil, err
+    }
+    for rows.Next() {
+        var id int
+        var number, reason string
+        var voidedAt time.Time
+        var staffID sql.NullInt32
+        var staffName sql.NullString
+        if err := rows.Scan(&id, &number, &voidedAt, &staffID, &staffName, &reason); err != nil {
+            rows.Close()
+            return nil, err
+        }
+        events = append(events, timelineEvent{"inbound_void", voidedAt, voidedAt, "inbound_voids", id, gin.H{
+            "date":          voidedAt,
+            "inboundNumber": number,
+            "staff":         staffJSON(staffID, staffName),
+            "reason":        reason,
+        }})
+    }
+    rows.Close()
+    if err := rows.Err(); err != nil {
+        return nil, err
+    }
+
+    // 日付順（日本時間で同日の場合は登録日時 → 同じテーブルの記録ID → 種別の順）
+    sort.SliceStable(events, func(i, j int) bool {
+        ei, ej := events[i], events[j]
+        di := ei.date.In(timelineLocation).Format("2006-01-02")
+        dj := ej.date.In(timelineLocation).Format("2006-01-02")
+        if di != dj {
+            return di < dj
+        }
+        if !ei.recordedAt.Equal(ej.recordedAt) {
+            return ei.recordedAt.Before(ej.recordedAt)
+        }
+        if ei.table == ej.table && ei.id != ej.id {
+            return ei.id < ej.id
+        }
+        return timelineEventOrder[ei.kind] < timelineEventOrder[ej.kind]
+    })
+
+    timeline := make([]gin.H, 0, len(events))
+    for _, e := range events {
+        e.event["type"] = e.kind
+        timeline = append(timeline, e.event)
+    }
+    return timeline, nil
+}
+
+// 入庫取消により削除された製品の取消履歴がすべて担当カテゴリのものか
+func voidedProductAccessible(staff *model.Staff, productID string) (bool, error) {
+    if staff == nil {
+        return false, nil
+    }
+    if staff.Categories == nil {
+        return true, nil
+    }
+    filter, _ := json.Marshal([]gin.H{{"product_id": productID}})
+    var other bool
+    err := db.DB.QueryRow(`
+        SELECT EXISTS(
+            SELECT 1 FROM inbound_voids iv
+            WHERE iv.snapshot->'products' @> $1::jsonb AND NOT iv.category = ANY($2)
+        )
+    `, string(filter), pq.Array(staff.Categories)).Scan(&other)
+    if err != nil {
+        return false, err
+    }
+    return !other, nil
+}
+
+// 担当者の JSON（削除済みの場合は nil）
+func staffJSON(id sql.NullInt32, name sql.NullString) interface{} {
+    if !id.Valid {
+        return nil
+    }
+    return gin.H{
+        "id":   id.Int32,
+        "name": name.String,
+    }
+}
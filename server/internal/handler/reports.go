@@ -0,0 +1,227 @@
+package handler
+
+import (
+    "database/sql"
+    "fmt"
+    "log"
+    "net/http"
+    "strconv"
+    "time"
+    "github.com/gin-gonic/gin"
+    "inventory-tracker/server/internal/auth"
+    "inventory-tracker/server/internal/db"
+)
+
+// PCの保証終了日（購入日に保証期間の月数を加えた日付、保証期間が未設定・0の場合は NULL）
+const pcWarrantyEndColumn = `CASE WHEN pc.warranty_period > 0
+    THEN (pc.purchase_date + make_interval(months => pc.warranty_period))::date END`
+
+// 保証期限レポートの期間（日数）
+const (
+    defaultWarrantyReportDays = 30
+    maxWarrantyReportDays     = 3650
+)
+
+// 保証期限レポートのクエリ定義
+// 出庫先は取消・返品されていない最新の出庫から取得する
+var pcWarrantyReportSpec = listQuerySpec{
+    selectColumns: `
+        p.id, p.product_id, p.status, pt.id, pt.name,
+        pc.model_number, pc.serial_number, pc.purchase_date, pc.warranty_period,
+        ` + pcWarrantyEndColumn + `,
+        ob.outbound_number, ob.outbound_date, ob.customer_name`,
+    from: `products p
+        INNER JOIN product_types pt ON p.type_id = pt.id
+        INNER JOIN pc_details pc ON p.product_id = pc.product_id
+        LEFT JOIN LATERAL (
+            SELECT obr.outbound_number, obr.outbound_date, obr.customer_name
+            FROM outbound_records obr
+            WHERE obr.product_id = p.product_id
+              AND obr.voided_at IS NULL
+              AND NOT EXISTS (SELECT 1 FROM return_records rr WHERE rr.outbound_record_id = obr.id)
+            ORDER BY obr.id DESC
+            LIMIT 1
+        ) ob ON true`,
+    idColumn: "p.id",
+    // 保証期間のある製品のみを対象とするため、保証終了日は NULL にならない（型番は未設定の場合がある）
+    sorts: map[string]sortColumn{
+        "warrantyEndDate": {"(" + pcWarrantyEndColumn + ")", "date"},
+        "productId":       {"p.product_id", "text"},
+        "modelNumber":     {"COALESCE(pc.model_number, '')", "text"},
+        "purchaseDate":    {"pc.purchase_date", "timestamp"},
+    },
+    defaultSort: "warrantyEndDate",
+    filters: listFilterColumns{
+        Status:      "p.status",
+        TypeID:      "pt.id",
+        LotNumber:   "p.lot_number",
+        ModelNumber: "pc.model_number",
+    },
+}
+
+// PC保証期限レポート取得ハンドラー
+// 保証が切れたPCと days 日以内（既定30日）に保証が切れるPCを保証終了日順に返す
+// warrantyStatus に expired / expiring を指定するとどちらか一方のみに絞り込む
+func GetPCWarrantyReport(c *gin.Context) {
+    staff, _ := auth.CurrentStaff(c)
+    if !auth.CanAccessCategory(staff, "pc") {
+        c.JSON(http.StatusForbidden, gin.H{"error": "この操作を行う権限がありません: カテゴリ pc の担当ではありません"})
+        return
+    }
+    format, ok := exportFormat(c)
+    if !ok {
+        return
+    }
+
+    days := defaultWarrantyReportDays
+    if v := c.Query("days"); v != "" {
+        n, err := strconv.Atoi(v)
+        if err != nil || n < 0 || n > maxWarrantyReportDays {
+            c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("days には0から%dまでの数値を指定してください", maxWarrantyReportDays)})
+            return
+        }
+        days = n
+    }
+
+    q, err := newListQuery(c, pcWarrantyReportSpec)
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+
+    // 基準日はアプリケーションの日付とし、残り日数・期限切れの判定に使用する
+    asOf := time.Now().Format("2006-01-02")
+    today := q.arg(asOf) + "::date"
+    q.where("pt.category = 'pc'")
+    q.where("pc.warranty_period > 0")
+    switch c.Query("warrantyStatus") {
+    case "":
+        q.where(fmt.Sprintf("%s <= %s + %s", pcWarrantyEndColumn, today, q.arg(days)))
+    case "expired":
+        q.where(fmt.Sprintf("%s < %s", pcWarrantyEndColumn, today))
+    case "expiring":
+        q.where(fmt.Sprintf("%s BETWEEN %s AND %s + %s", pcWarrantyEndColumn, today, today, q.arg(days)))
+    default:
+        c.JSON(http.StatusBadRequest, gin.H{"error": "warrantyStatus には expired または expiring を指定してください"})
+        return
+    }
+
+    var totalCount int
+    if format == "" {
+        query, args := q.countSQL()
+        if err := db.DB.QueryRow(query, args...).Scan(&totalCount); err != nil {
+            log.Printf("保証期限レポート取得エラー: %v", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "保証期限レポートの取得に失敗しました"})
+            return
+        }
+    }
+
+    query, args := q.selectSQL(format == "")
+    rows, err := db.DB.Query(query, args...)
+    if err != nil {
+        log.Printf("保証期限レポート取得エラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "保証期限レポートの取得に失敗しました"})
+        return
+    }
+    defer rows.Close()
+
+    var export exportWriter
+    if format != "" {
+        headers := []string{
+            "製品ID", "タイプ", "ステータス", "型番", "シリアル番号", "購入日", "保証期間（月）",
+            "保証終了日", "残り日数", "出庫番号", "出庫日", "出庫先",
+        }
+        if export, err = newExportWriter(c, format, "pc_warranty", headers); err != nil {
+            log.Printf("エクスポート開始エラー: %v", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "エクスポートの開始に失敗しました"})
+            return
+        }
+    }
+
+    base, _ := time.Parse("2006-01-02", asOf)
+    var items []gin.H
+    var cursors []listCursor
+    for rows.Next() {
+        var r struct {
+            ID             int
+            ProductID      string
+            Status         string
+            TypeID         int
+            TypeName       string
+            ModelNumber    sql.NullString
+            SerialNumber   string
+            PurchaseDate   time.Time
+            WarrantyPeriod int
+            WarrantyEnd    time.Time
+            OutboundNumber sql.NullString
+            OutboundDate   sql.NullTime
+            CustomerName   sql.NullString
+            SortValue      string
+        }
+        if err := rows.Scan(
+            &r.ID, &r.ProductID, &r.Status, &r.TypeID, &r.TypeName,
+            &r.ModelNumber, &r.SerialNumber, &r.PurchaseDate, &r.WarrantyPeriod,
+            &r.WarrantyEnd,
+            &r.OutboundNumber, &r.OutboundDate, &r.CustomerName,
+            &r.SortValue,
+        ); err != nil {
+            log.Printf("保証期限レポート読み取りエラー: %v", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "データの読み取りに失敗しました"})
+            return
+        }
+
+        daysRemaining := int(r.WarrantyEnd.Sub(base).Hours() / 24)
+        warrantyStatus := "expiring"
+        if daysRemaining < 0 {
+            warrantyStatus = "expired"
+        }
+
+        if export != nil {
+            values := []interface{}{
+                r.ProductID, r.TypeName, statusLabel(r.Status), r.ModelNumber.String, r.SerialNumber,
+                r.PurchaseDate, r.WarrantyPeriod, r.WarrantyEnd, daysRemaining,
+                r.OutboundNumber.String, nullableTime(r.OutboundDate), r.CustomerName.String,
+            }
+            if err := export.WriteRow(values); err != nil {
+                log.Printf("エクスポート書き込みエラー: %v", err)
+                return
+            }
+            continue
+        }
+
+        item := gin.H{
+            "id":              r.ID,
+            "productId":       r.ProductID,
+            "status":          r.Status,
+            "type":            gin.H{"id": r.TypeID, "name": r.TypeName},
+            "modelNumber":     r.ModelNumber.String,
+            "serialNumber":    r.SerialNumber,
+            "purchaseDate":    r.PurchaseDate,
+            "warrantyPeriod":  r.WarrantyPeriod,
+            "warrantyEndDate": r.WarrantyEnd,
+            "daysRemaining":   daysRemaining,
+            "warrantyStatus":  warrantyStatus,
+        }
+        if r.OutboundNumber.Valid {
+            item["lastOutbound"] = gin.H{
+                "outboundNumber": r.OutboundNumber.String,
+                "outboundDate":   r.OutboundDate.Time,
+                "customerName":   r.CustomerName.String,
+            }
+        }
+        items = append(items, item)
+        cursors = append(cursors, listCursor{Value: r.SortValue, ID: r.ID})
+    }
+
+    if export != nil {
+        if err := export.Close(); err != nil {
+            log.Printf("エクスポート書き込みエラー: %v", err)
+        }
+        return
+    }
+
+    response := q.response(items, cursors, totalCount)
+    response["asOf"] = asOf
+    response["days"] = days
+    c.JSON(http.StatusOK, response)
+}
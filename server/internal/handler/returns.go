@@ -0,0 +1,367 @@
+package handler
+
+import (
+    "database/sql"
+    "fmt"
+    "log"
+    "net/http"
+    "strings"
+    "time"
+    "github.com/gin-gonic/gin"
+    "github.com/lib/pq"
+    "inventory-tracker/server/internal/audit"
+    "inventory-tracker/server/internal/auth"
+    "inventory-tracker/server/internal/db"
+    "inventory-tracker/server/internal/model"
+    "inventory-tracker/server/internal/numbering"
+)
+
+// 返品処理ハンドラー
+func HandleReturn(c *gin.Context) {
+    category := c.Param("category")
+    log.Printf("返品処理開始: カテゴリ = %s", category)
+
+    var req model.ReturnRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        log.Printf("リクエストデータバインドエラー: %v", err)
+        c.JSON(http.StatusBadRequest, gin.H{"error": "無効なリクエストデータです"})
+        return
+    }
+
+    // 担当者はログイン中のセッションから取得
+    req.StaffID = auth.CurrentStaffID(c)
+
+    log.Printf("リクエストデータ: %+v", req)
+
+    var outboundNumber string
+    if req.OutboundNumber != nil {
+        outboundNumber = strings.TrimSpace(*req.OutboundNumber)
+    }
+    var productIDs []string
+    for _, id := range req.ProductIDs {
+        if id = strings.TrimSpace(id); id != "" {
+            productIDs = append(productIDs, id)
+        }
+    }
+
+    if len(productIDs) == 0 && outboundNumber == "" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "返品する製品IDまたは出庫番号を指定してください"})
+        return
+    }
+    if strings.TrimSpace(req.Reason) == "" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "返品理由を入力してください"})
+        return
+    }
+
+    // 日付文字列をtime.Time型に変換
+    returnDate, err := time.Parse("2006-01-02", req.ReturnDate)
+    if err != nil {
+        log.Printf("日付パースエラー: %v", err)
+        c.JSON(http.StatusBadRequest, gin.H{"error": "無効な日付フォーマットです"})
+        return
+    }
+
+    // トランザクション開始
+    tx, err := db.BeginTx()
+    if err != nil {
+        log.Printf("トランザクション開始エラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "トランザクションの開始に失敗しました"})
+        return
+    }
+    defer tx.Rollback()
+
+    // 対象製品の最新の出庫記録を取得
+    // 出庫番号のみ指定された場合はその出庫伝票に含まれる製品すべてが対象
+    targetCondition := "obr.product_id = ANY($2)"
+    targetArg := interface{}(pq.Array(productIDs))
+    if len(productIDs) == 0 {
+        targetCondition = "obr.product_id IN (SELECT product_id FROM outbound_records WHERE outbound_number = $2 AND voided_at IS NULL)"
+        targetArg = outboundNumber
+    }
+
+    rows, err := tx.Query(`
+        SELECT DISTINCT ON (obr.product_id)
+            obr.id, obr.product_id, obr.outbound_number, p.status,
+            EXISTS(SELECT 1 FROM return_records rr WHERE rr.outbound_record_id = obr.id) AS returned
+        FROM outbound_records obr
+        INNER JOIN products p ON obr.product_id = p.product_id
+        INNER JOIN product_types pt ON p.type_id = pt.id
+        WHERE pt.category = $1
+        AND obr.voided_at IS NULL
+        AND `+targetCondition+`
+        ORDER BY obr.product_id, obr.id DESC
+    `, category, targetArg)
+    if err != nil {
+        log.Printf("出庫記録取得エラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "出庫記録の取得に失敗しました"})
+        return
+    }
+
+    type returnTarget struct {
+        OutboundRecordID int
+        ProductID        string
+        OutboundNumber   string
+        Status           string
+        Returned         bool
+    }
+    found := make(map[string]returnTarget)
+    var targets []returnTarget
+    for rows.Next() {
+        var t returnTarget
+        if err := rows.Scan(&t.OutboundRecordID, &t.ProductID, &t.OutboundNumber, &t.Status, &t.Returned); err != nil {
+            rows.Close()
+            log.Printf("出庫記録読み取りエラー: %v", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "出庫記録の読み取りに失敗しました"})
+            return
+        }
+        found[t.ProductID] = t
+        targets = append(targets, t)
+    }
+    rows.Close()
+    if err := rows.Err(); err != nil {
+        log.Printf("出庫記録読み取りエラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "出庫記録の読み取りに失敗しました"})
+        return
+    }
+
+    // 製品ごとに返品可否を確認
+    var failures []gin.H
+    if len(productIDs) > 0 {
+        for _, id := range productIDs {
+            if _, ok := found[id]; !ok {
+                failures = append(failures, gin.H{"productId": id, "reason": "出庫記録が見つかりません"})
+            }
+        }
+    }
+    for _, t := range targets {
+        switch {
+        case outboundNumber != "" && t.OutboundNumber != outboundNumber:
+            failures = append(failures, gin.H{
+                "productId": t.ProductID,
+                "reason":    fmt.Sprintf("最新の出庫記録が出庫番号 %s ではありません", outboundNumber),
+            })
+        case t.Status != "out_of_stock" || t.Returned:
+            failures = append(failures, gin.H{"productId": t.ProductID, "reason": "既に在庫に戻っています"})
+        }
+    }
+
+    if len(targets) == 0 && len(failures) == 0 {
+        log.Printf("返品対象の製品が見つかりません: 出庫番号 = %s", outboundNumber)
+        c.JSON(http.StatusNotFound, gin.H{"error": "指定された出庫番号の製品が見つかりません"})
+        return
+    }
+    if len(failures) > 0 {
+        log.Printf("返品できない製品があります: %+v", failures)
+        c.JSON(http.StatusBadRequest, gin.H{
+            "error":    "返品できない製品があります",
+            "failures": failures,
+        })
+        return
+    }
+
+    // 返品番号の生成
+    returnNumber, err := numbering.Next(tx, numbering.Return, category)
+    if err != nil {
+        log.Printf("返品番号生成エラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "返品番号生成エラー"})
+        return
+    }
+    log.Printf("生成された返品番号: %s", returnNumber)
+
+    // 製品を在庫に戻す
+    var processedProducts []string
+    for _, t := range targets {
+        processedProducts = append(processedProducts, t.ProductID)
+    }
+
+    result, err := tx.Exec(`
+        UPDATE products
+        SET status = 'in_stock'
+        WHERE product_id = ANY($1)
+        AND status = 'out_of_stock'
+    `, pq.Array(processedProducts))
+    if err != nil {
+        log.Printf("製品更新エラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "製品の更新に失敗しました"})
+        return
+    }
+    if updated, err := result.RowsAffected(); err != nil || int(updated) != len(processedProducts) {
+        log.Printf("製品の状態が変更されています: 更新件数 = %d, 対象件数 = %d", updated, len(processedProducts))
+        c.JSON(http.StatusConflict, gin.H{"error": "処理中に製品の状態が変更されました。もう一度お試しください"})
+        return
+    }
+
+    // 返品記録の作成
+    stmt, err := tx.Prepare(`
+        INSERT INTO return_records (
+            product_id, outbound_record_id, staff_id, return_number, reason, notes, return_date
+        ) VALUES ($1, $2, $3, $4, $5, $6, $7)
+    `)
+    if err != nil {
+        log.Printf("ステートメント準備エラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "返品記録の準備に失敗しました"})
+        return
+    }
+    defer stmt.Close()
+
+    for _, t := range targets {
+        _, err = stmt.Exec(
+            t.ProductID, t.OutboundRecordID, req.StaffID, returnNumber,
+            strings.TrimSpace(req.Reason), req.Notes, returnDate,
+        )
+        if err != nil {
+            log.Printf("返品記録作成エラー: %v", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("返品記録の作成に失敗しました: %v", err)})
+            return
+        }
+    }
+
+    after, ok := auditSnapshot(c, tx, `
+        SELECT json_build_object('category', $2::text, 'records', json_agg(row_to_json(rr) ORDER BY rr.product_id))
+        FROM return_records rr
+        WHERE rr.return_number = $1
+    `, returnNumber, category)
+    if !ok {
+        return
+    }
+    if !recordAudit(c, tx, audit.Entry{
+        Action:     audit.ActionCreate,
+        EntityType: "return",
+        EntityID:   returnNumber,
+        After:      after,
+    }) {
+        return
+    }
+
+    // トランザクションのコミット
+    if err := tx.Commit(); err != nil {
+        log.Printf("トランザクションコミットエラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "トランザクションのコミットに失敗しました"})
+        return
+    }
+
+    log.Printf("返品処理が完了しました。処理された製品: %v", processedProducts)
+    c.JSON(http.StatusOK, gin.H{
+        "success":        true,
+        "returnNumber":   returnNumber,
+        "processedCount": len(processedProducts),
+        "products":       processedProducts,
+    })
+}
+
+// 返品履歴取得ハンドラー
+func GetReturnHistory(c *gin.Context) {
+    category := c.Param("category")
+
+    query := `
+        SELECT
+            rr.id, rr.return_number, rr.return_date, rr.reason, rr.notes,
+            p.product_id, p.lot_number,
+            pt.id as type_id, pt.name as type_name,
+            s.id as staff_id, s.name as staff_name,
+            obr.outbound_number, obr.outbound_date,
+            obr.customer_number, obr.customer_name,
+            pc.model_number, pc.serial_number,
+            vd.type as vest_type, vd.size as vest_size, vd.has_logo as vest_has_logo,
+            p.attributes
+        FROM return_records rr
+        INNER JOIN outbound_records obr ON rr.outbound_record_id = obr.id
+        INNER JOIN products p ON rr.product_id = p.product_id
+        INNER JOIN product_types pt ON p.type_id = pt.id
+        INNER JOIN staff s ON rr.staff_id = s.id
+        LEFT JOIN pc_details pc ON p.product_id = pc.product_id
+        LEFT JOIN vest_details vd ON p.product_id = vd.product_id
+        WHERE pt.category = $1
+        ORDER BY rr.return_date DESC, rr.id DESC
+    `
+
+    rows, err := db.DB.Query(query, category)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("返品履歴の取得に失敗しました: %v", err)})
+        return
+    }
+    defer rows.Close()
+
+    var history []gin.H
+    for rows.Next() {
+        var h struct {
+            ID             int
+            ReturnNumber   string
+            ReturnDate     time.Time
+            Reason         string
+            Notes          sql.NullString
+            ProductID      string
+            LotNumber      sql.NullString
+            TypeID         int
+            TypeName       string
+            StaffID        int
+            StaffName      string
+            OutboundNumber string
+            OutboundDate   time.Time
+            CustomerNumber sql.NullString
+            CustomerName   sql.NullString
+            ModelNumber    sql.NullString
+            SerialNumber   sql.NullString
+            VestType       sql.NullString
+            VestSize       sql.NullString
+            VestHasLogo    sql.NullBool
+            Attributes     []byte
+        }
+
+        err := rows.Scan(
+            &h.ID, &h.ReturnNumber, &h.ReturnDate, &h.Reason, &h.Notes,
+            &h.ProductID, &h.LotNumber,
+            &h.TypeID, &h.TypeName,
+            &h.StaffID, &h.StaffName,
+            &h.OutboundNumber, &h.OutboundDate,
+            &h.CustomerNumber, &h.CustomerName,
+            &h.ModelNumber, &h.SerialNumber,
+            &h.VestType, &h.VestSize, &h.VestHasLogo,
+            &h.Attributes,
+        )
+        if err != nil {
+            c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("データの読み取りに失敗しました: %v", err)})
+            return
+        }
+
+        record := gin.H{
+            "id":           h.ID,
+            "returnNumber": h.ReturnNumber,
+            "returnDate":   h.ReturnDate,
+            "reason":       h.Reason,
+            "notes":        h.Notes.String,
+            "productId":    h.ProductID,
+            "lotNumber":    h.LotNumber.String,
+            "type": gin.H{
+                "id":   h.TypeID,
+                "name": h.TypeName,
+            },
+            "staff": gin.H{
+                "id":   h.StaffID,
+                "name": h.StaffName,
+            },
+            "outbound": gin.H{
+                "outboundNumber": h.OutboundNumber,
+                "outboundDate":   h.OutboundDate,
+                "customerNumber": h.CustomerNumber.String,
+                "customerName":   h.CustomerName.String,
+            },
+            "attributes": attributesJSON(h.Attributes),
+        }
+
+        if category == "vest" && h.VestType.Valid {
+            record["vestDetails"] = vestDetailsJSON(h.VestType, h.VestSize, h.VestHasLogo)
+        }
+
+        if category == "pc" && h.ModelNumber.Valid {
+            record["pcDetails"] = gin.H{
+                "modelNumber":  h.ModelNumber.String,
+                "serialNumber": h.SerialNumber.String,
+            }
+        }
+
+        history = append(history, record)
+    }
+
+    c.JSON(http.StatusOK, history)
+}
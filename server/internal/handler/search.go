@@ -0,0 +1,253 @@
+package handler
+
+import (
+    "encoding/json"
+    "log"
+    "net/http"
+    "sort"
+    "strconv"
+    "strings"
+    "github.com/gin-gonic/gin"
+    "github.com/lib/pq"
+    "inventory-tracker/server/internal/auth"
+    "inventory-tracker/server/internal/db"
+)
+
+// 検索結果の件数
+const (
+    defaultSearchLimit = 20
+    maxSearchLimit     = 100
+)
+
+// 検索対象
+// 各クエリは $1: 完全一致（小文字）, $2: 前方一致パターン, $3: 部分一致パターン, $4: 件数,
+// $5: 担当カテゴリ（NULL の場合はすべてのカテゴリ）を受け取り、
+// value（一致した値）, rank（0: 完全一致, 1: 前方一致, 2: 部分一致）, category, details（JSON）を返す
+type searchSource struct {
+    kind  string
+    query string
+}
+
+// 一致の種類による順位
+func searchRank(column string) string {
+    return `CASE WHEN LOWER(` + column + `) = $1 THEN 0 WHEN ` + column + ` ILIKE $2 THEN 1 ELSE 2 END`
+}
+
+// 担当カテゴリによる絞り込み
+const searchCategoryFilter = `($5::text[] IS NULL OR pt.category = ANY($5))`
+
+var searchSources = []searchSource{
+    {"product", `
+        SELECT p.product_id, ` + searchRank("p.product_id") + `, pt.category,
+            json_build_object(
+                'productId', p.product_id, 'typeName', pt.name, 'status', p.status, 'lotNumber', p.lot_number,
+                'attributes', p.attributes)
+        FROM products p
+        INNER JOIN product_types pt ON p.type_id = pt.id
+        WHERE p.product_id ILIKE $3 AND ` + searchCategoryFilter + `
+        ORDER BY 2, 1
+        LIMIT $4`},
+    {"serial", `
+        SELECT pc.serial_number, ` + searchRank("pc.serial_number") + `, pt.category,
+            json_build_object('productId', p.product_id, 'modelNumber', pc.model_number, 'status', p.status)
+        FROM pc_details pc
+        INNER JOIN products p ON pc.product_id = p.product_id
+        INNER JOIN product_types pt ON p.type_id = pt.id
+        WHERE pc.serial_number ILIKE $3 AND ` + searchCategoryFilter + `
+        ORDER BY 2, 1
+        LIMIT $4`},
+    {"attribute", `
+        SELECT a.value, ` + searchRank("a.value") + `, pt.category,
+            json_build_object(
+                'productId', p.product_id, 'typeName', pt.name, 'status', p.status,
+                'attributeKey', a.key, 'attributeLabel', ad.label)
+        FROM products p
+        INNER JOIN product_types pt ON p.type_id = pt.id
+        CROSS JOIN LATERAL jsonb_each_text(p.attributes) a
+        LEFT JOIN attribute_definitions ad ON ad.category = pt.category AND ad.key = a.key
+        WHERE a.value ILIKE $3 AND ` + searchCategoryFilter + `
+        ORDER BY 2, 1
+        LIMIT $4`},
+    {"lot", `
+        SELECT p.lot_number, ` + searchRank("p.lot_number") + `, pt.category,
+            json_build_object('lotNumber', p.lot_number, 'productCount', COUNT(*))
+        FROM products p
+        INNER JOIN product_types pt ON p.type_id = pt.id
+        WHERE p.lot_number ILIKE $3 AND ` + searchCategoryFilter + `
+        GROUP BY p.lot_number, pt.category
+        ORDER BY 2, 1
+        LIMIT $4`},
+    {"inbound", `
+        SELECT ir.inbound_number, ` + searchRank("ir.inbound_number") + `, pt.category,
+            json_build_object('inboundNumber', ir.inbound_number, 'inboundDate', MIN(ir.inbound_date), 'productCount', COUNT(*))
+        FROM inbound_records ir
+        INNER JOIN products p ON ir.product_id = p.product_id
+        INNER JOIN product_types pt ON p.type_id = pt.id
+        WHERE ir.inbound_number ILIKE $3 AND ` + searchCategoryFilter + `
+        GROUP BY ir.inbound_number, pt.category
+        ORDER BY 2, 1
+        LIMIT $4`},
+    {"outbound", `
+        SELECT obr.outbound_number, ` + searchRank("obr.outbound_number") + `, pt.category,
+            json_build_object(
+                'outboundNumber', obr.outbound_number, 'outboundDate', MIN(obr.outbound_date),
+                'customerName', MAX(obr.customer_name), 'productCount', COUNT(*),
+                'voided', BOOL_AND(obr.voided_at IS NOT NULL))
+        FROM outbound_records obr
+        INNER JOIN products p ON obr.product_id = p.product_id
+        INNER JOIN product_types pt ON p.type_id = pt.id
+        WHERE obr.outbound_number ILIKE $3 AND ` + searchCategoryFilter + `
+        GROUP BY obr.outbound_number, pt.category
+        ORDER BY 2, 1
+        LIMIT $4`},
+    {"customer", `
+        SELECT COALESCE(obr.customer_name, obr.customer_number),
+            LEAST(` + searchRank("COALESCE(obr.customer_name, '')") + `, ` + searchRank("COALESCE(obr.customer_number, '')") + `),
+            NULL::text,
+            json_build_object(
+                'customerNumber', obr.customer_number, 'customerName', obr.customer_name,
+                'outboundCount', COUNT(DISTINCT obr.outbound_number), 'lastOutboundDate', MAX(obr.outbound_date))
+        FROM outbound_records obr
+        INNER JOIN products p ON obr.product_id = p.product_id
+        INNER JOIN product_types pt ON p.type_id = pt.id
+        WHERE (obr.customer_name ILIKE $3 OR obr.customer_number ILIKE $3) AND ` + searchCategoryFilter + `
+        GROUP BY obr.customer_number, obr.customer_name
+        ORDER BY 2, 1
+        LIMIT $4`},
+    {"purchaser", `
+        SELECT COALESCE(obr.purchaser_name, obr.purchaser_number),
+            LEAST(` + searchRank("COALESCE(obr.purchaser_name, '')") + `, ` + searchRank("COALESCE(obr.purchaser_number, '')") + `),
+            NULL::text,
+            json_build_object(
+                'purchaserNumber', obr.purchaser_number, 'purchaserName', obr.purchaser_name,
+                'outboundCount', COUNT(DISTINCT obr.outbound_number), 'lastOutboundDate', MAX(obr.outbound_date))
+        FROM outbound_records obr
+        INNER JOIN products p ON obr.product_id = p.product_id
+        INNER JOIN product_types pt ON p.type_id = pt.id
+        WHERE (obr.purchaser_name ILIKE $3 OR obr.purchaser_number ILIKE $3) AND ` + searchCategoryFilter + `
+        GROUP BY obr.purchaser_number, obr.purchaser_name
+        ORDER BY 2, 1
+        LIMIT $4`},
+}
+
+// 検索対象の種別の表示順（同順位の場合）
+var searchKindOrder = map[string]int{
+    "product":   0,
+    "serial":    1,
+    "attribute": 2,
+    "lot":       3,
+    "inbound":   4,
+    "outbound":  5,
+    "customer":  6,
+    "purchaser": 7,
+}
+
+// 検索結果
+type searchResult struct {
+    Type     string          `json:"type"`
+    Value    string          `json:"value"`
+    Match    string          `json:"match"`
+    Category *string         `json:"category"`
+    Details  json.RawMessage `json:"details"`
+    rank     int
+}
+
+// 一致の種類
+var searchMatchLabels = []string{"exact", "prefix", "partial"}
+
+// 横断検索ハンドラー
+// 製品ID・シリアル番号・追加項目の値・ロット番号・入出庫番号・顧客・購入者を担当カテゴリから部分一致で検索し、
+// 完全一致 → 前方一致 → 部分一致の順に種別付きで返す
+func Search(c *gin.Context) {
+    q := strings.TrimSpace(c.Query("q"))
+    if q == "" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "検索キーワードを入力してください"})
+        return
+    }
+
+    limit := defaultSearchLimit
+    if v := c.Query("limit"); v != "" {
+        n, err := strconv.Atoi(v)
+        if err != nil || n < 1 {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "limit には1以上の数値を指定してください"})
+            return
+        }
+        if n > maxSearchLimit {
+            n = maxSearchLimit
+        }
+        limit = n
+    }
+
+    // 種別の絞り込み（カンマ区切り）
+    kinds := make(map[string]bool)
+    for _, kind := range strings.Split(c.Query("types"), ",") {
+        if kind = strings.TrimSpace(kind); kind == "" {
+            continue
+        }
+        if _, ok := searchKindOrder[kind]; !ok {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "検索対象の種別が正しくありません: " + kind})
+            return
+        }
+        kinds[kind] = true
+    }
+
+    // 担当カテゴリ以外の製品・入出庫は返さない（顧客・購入者は担当カテゴリの出庫から集計する）
+    staff, _ := auth.CurrentStaff(c)
+    categories := pq.Array([]string{})
+    if staff != nil {
+        categories = pq.Array(staff.Categories)
+    }
+
+    escaped := escapeLike(q)
+    results := []searchResult{}
+    for _, source := range searchSources {
+        if len(kinds) > 0 && !kinds[source.kind] {
+            continue
+        }
+
+        rows, err := db.DB.Query(source.query, strings.ToLower(q), escaped+"%", "%"+escaped+"%", limit, categories)
+        if err != nil {
+            log.Printf("検索エラー (%s): %v", source.kind, err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "検索に失敗しました"})
+            return
+        }
+        for rows.Next() {
+            r := searchResult{Type: source.kind}
+            var details []byte
+            if err := rows.Scan(&r.Value, &r.rank, &r.Category, &details); err != nil {
+                rows.Close()
+                log.Printf("検索結果読み取りエラー (%s): %v", source.kind, err)
+                c.JSON(http.StatusInternalServerError, gin.H{"error": "検索結果の読み取りに失敗しました"})
+                return
+            }
+            r.Match = searchMatchLabels[r.rank]
+            r.Details = details
+            results = append(results, r)
+        }
+        rows.Close()
+        if err := rows.Err(); err != nil {
+            log.Printf("検索結果読み取りエラー (%s): %v", source.kind, err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "検索結果の読み取りに失敗しました"})
+            return
+        }
+    }
+
+    // 一致の種類 → 種別 → 値の順に並べ替え
+    sort.SliceStable(results, func(i, j int) bool {
+        if results[i].rank != results[j].rank {
+            return results[i].rank < results[j].rank
+        }
+        if results[i].Type != results[j].Type {
+            return searchKindOrder[results[i].Type] < searchKindOrder[results[j].Type]
+        }
+        return results[i].Value < results[j].Value
+    })
+    if len(results) > limit {
+        results = results[:limit]
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "query":   q,
+        "results": results,
+    })
+}
@@ -1,18 +1,31 @@
 package handler
 
 import (
+    "database/sql"
     "log"
     "net/http"
     "strconv"
+    "strings"
     "time"
     "github.com/gin-gonic/gin"
+    "inventory-tracker/server/internal/audit"
+    "inventory-tracker/server/internal/auth"
     "inventory-tracker/server/internal/db"
 )
 
+// 監査ログ用の担当者の内容（パスワード・PINのハッシュは含めない）
+const staffAuditQuery = `
+    SELECT to_jsonb(s) - 'password_hash' - 'pin_hash'
+    FROM staff s
+    WHERE s.id = $1
+`
+
 // スタッフ一覧取得ハンドラー
+// 既定では有効な担当者のみを返し、includeInactive=true の場合は無効化した担当者も含める
 func GetStaffList(c *gin.Context) {
     log.Printf("[GetStaffList] リクエスト受信")
-    staffList, err := db.GetStaffList()
+    includeInactive := c.Query("includeInactive") == "true"
+    staffList, err := db.GetStaffList(includeInactive)
     if err != nil {
         log.Printf("[GetStaffList] エラー: %v", err)
         c.JSON(http.StatusInternalServerError, gin.H{
@@ -32,7 +45,7 @@ func CreateStaff(c *gin.Context) {
         Name string `json:"name" binding:"required"`
     }
 
-    if err := c.ShouldBindJSON(&req); err != nil {
+    if err := c.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.Name) == "" {
         log.Printf("[CreateStaff] バリデーションエラー: %v", err)
         c.JSON(http.StatusBadRequest, gin.H{
             "error": "無効なリクエストデータです",
@@ -44,13 +57,24 @@ func CreateStaff(c *gin.Context) {
     var staff struct {
         ID        int       `json:"id"`
         Name      string    `json:"name"`
+        Active    bool      `json:"active"`
         CreatedAt time.Time `json:"createdAt"`
     }
 
-    err := db.DB.QueryRow(
-        "INSERT INTO staff (name) VALUES ($1) RETURNING id, name, created_at::timestamp",
-        req.Name,
-    ).Scan(&staff.ID, &staff.Name, &staff.CreatedAt)
+    tx, err := db.BeginTx()
+    if err != nil {
+        log.Printf("[CreateStaff] トランザクション開始エラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{
+            "error": "トランザクションの開始に失敗しました",
+        })
+        return
+    }
+    defer tx.Rollback()
+
+    err = tx.QueryRow(
+        "INSERT INTO staff (name) VALUES ($1) RETURNING id, name, active, created_at::timestamp",
+        strings.TrimSpace(req.Name),
+    ).Scan(&staff.ID, &staff.Name, &staff.Active, &staff.CreatedAt)
 
     if err != nil {
         log.Printf("[CreateStaff] データベースエラー: %v", err)
@@ -60,11 +84,179 @@ func CreateStaff(c *gin.Context) {
         return
     }
 
+    after, ok := auditSnapshot(c, tx, staffAuditQuery, staff.ID)
+    if !ok {
+        return
+    }
+    if !recordAudit(c, tx, audit.Entry{
+        Action:     audit.ActionCreate,
+        EntityType: "staff",
+        EntityID:   strconv.Itoa(staff.ID),
+        After:      after,
+    }) {
+        return
+    }
+    if err := tx.Commit(); err != nil {
+        log.Printf("[CreateStaff] コミットエラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{
+            "error": "トランザクションのコミットに失敗しました",
+        })
+        return
+    }
+
     log.Printf("[CreateStaff] 成功: ID=%d", staff.ID)
     c.JSON(http.StatusOK, staff)
 }
 
+// スタッフ名変更ハンドラー
+// 履歴は担当者IDで参照しているため、過去の記録も新しい名前で表示される
+func UpdateStaff(c *gin.Context) {
+    var req struct {
+        Name string `json:"name" binding:"required"`
+    }
+    if err := c.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.Name) == "" {
+        c.JSON(http.StatusBadRequest, gin.H{
+            "error": "担当者名を入力してください",
+        })
+        return
+    }
+
+    updateStaff(c, "[UpdateStaff]", "スタッフを更新しました", func(tx *sql.Tx, staffID int) bool {
+        if _, err := tx.Exec("UPDATE staff SET name = $2 WHERE id = $1", staffID, strings.TrimSpace(req.Name)); err != nil {
+            log.Printf("[UpdateStaff] データベースエラー: %v", err)
+            c.JSON(http.StatusInternalServerError, gin.H{
+                "error": "スタッフの更新に失敗しました",
+            })
+            return false
+        }
+        return true
+    })
+}
+
+// スタッフ無効化ハンドラー
+// 無効化した担当者はログインできず、担当者の選択肢にも表示されない（既存のセッションも無効化する）
+func DeactivateStaff(c *gin.Context) {
+    updateStaff(c, "[DeactivateStaff]", "スタッフを無効化しました", func(tx *sql.Tx, staffID int) bool {
+        // 管理者を1人以上残す
+        lastAdmin, err := isLastActiveAdmin(tx, staffID)
+        if err != nil {
+            log.Printf("[DeactivateStaff] 管理者数確認エラー: %v", err)
+            c.JSON(http.StatusInternalServerError, gin.H{
+                "error": "スタッフの無効化に失敗しました",
+            })
+            return false
+        }
+        if lastAdmin {
+            c.JSON(http.StatusConflict, gin.H{
+                "error": "有効な管理者が1人もいなくなるため無効化できません",
+            })
+            return false
+        }
+
+        _, err = tx.Exec(`
+            UPDATE staff SET active = FALSE, deactivated_at = CURRENT_TIMESTAMP
+            WHERE id = $1 AND active
+        `, staffID)
+        if err == nil {
+            _, err = tx.Exec(`
+                UPDATE staff_sessions SET revoked_at = CURRENT_TIMESTAMP
+                WHERE staff_id = $1 AND revoked_at IS NULL
+            `, staffID)
+        }
+        if err != nil {
+            log.Printf("[DeactivateStaff] データベースエラー: %v", err)
+            c.JSON(http.StatusInternalServerError, gin.H{
+                "error": "スタッフの無効化に失敗しました",
+            })
+            return false
+        }
+        return true
+    })
+}
+
+// スタッフ再有効化ハンドラー
+func ReactivateStaff(c *gin.Context) {
+    updateStaff(c, "[ReactivateStaff]", "スタッフを再有効化しました", func(tx *sql.Tx, staffID int) bool {
+        _, err := tx.Exec(`
+            UPDATE staff SET active = TRUE, deactivated_at = NULL
+            WHERE id = $1 AND NOT active
+        `, staffID)
+        if err != nil {
+            log.Printf("[ReactivateStaff] データベースエラー: %v", err)
+            c.JSON(http.StatusInternalServerError, gin.H{
+                "error": "スタッフの再有効化に失敗しました",
+            })
+            return false
+        }
+        return true
+    })
+}
+
+// 担当者の更新処理（対象の行をロックして update を実行し、変更前後を監査ログに記録する）
+// update はエラー時にレスポンスを返して false を返す
+func updateStaff(c *gin.Context, logPrefix string, message string, update func(tx *sql.Tx, staffID int) bool) {
+    staffID, err := strconv.Atoi(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{
+            "error": "無効なスタッフIDです",
+        })
+        return
+    }
+
+    tx, err := db.BeginTx()
+    if err != nil {
+        log.Printf("%s トランザクション開始エラー: %v", logPrefix, err)
+        c.JSON(http.StatusInternalServerError, gin.H{
+            "error": "トランザクションの開始に失敗しました",
+        })
+        return
+    }
+    defer tx.Rollback()
+
+    before, ok := auditSnapshot(c, tx, staffAuditQuery+" FOR UPDATE", staffID)
+    if !ok {
+        return
+    }
+    if before == nil {
+        c.JSON(http.StatusNotFound, gin.H{
+            "error": "スタッフが見つかりません",
+        })
+        return
+    }
+
+    if !update(tx, staffID) {
+        return
+    }
+
+    after, ok := auditSnapshot(c, tx, staffAuditQuery, staffID)
+    if !ok {
+        return
+    }
+    if !recordAudit(c, tx, audit.Entry{
+        Action:     audit.ActionUpdate,
+        EntityType: "staff",
+        EntityID:   strconv.Itoa(staffID),
+        Before:     before,
+        After:      after,
+    }) {
+        return
+    }
+    if err := tx.Commit(); err != nil {
+        log.Printf("%s コミットエラー: %v", logPrefix, err)
+        c.JSON(http.StatusInternalServerError, gin.H{
+            "error": "トランザクションのコミットに失敗しました",
+        })
+        return
+    }
+
+    log.Printf("%s 成功: ID=%d", logPrefix, staffID)
+    c.JSON(http.StatusOK, gin.H{
+        "message": message,
+    })
+}
+
 // スタッフ削除ハンドラー
+// 入出庫・返品・取消の記録で参照されている担当者は削除できない（無効化を使用する）
 func DeleteStaff(c *gin.Context) {
     log.Printf("[DeleteStaff] リクエスト受信")
     staffID, err := strconv.Atoi(c.Param("id"))
@@ -77,7 +269,77 @@ func DeleteStaff(c *gin.Context) {
     }
 
     log.Printf("[DeleteStaff] スタッフID: %d", staffID)
-    _, err = db.DB.Exec("DELETE FROM staff WHERE id = $1", staffID)
+    tx, err := db.BeginTx()
+    if err != nil {
+        log.Printf("[DeleteStaff] トランザクション開始エラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{
+            "error": "トランザクションの開始に失敗しました",
+        })
+        return
+    }
+    defer tx.Rollback()
+
+    before, ok := auditSnapshot(c, tx, staffAuditQuery+" FOR UPDATE", staffID)
+    if !ok {
+        return
+    }
+    if before == nil {
+        c.JSON(http.StatusNotFound, gin.H{
+            "error": "スタッフが見つかりません",
+        })
+        return
+    }
+
+    // 記録からの参照の確認
+    var referenced bool
+    err = tx.QueryRow(`
+        SELECT
+            EXISTS(SELECT 1 FROM inbound_records WHERE staff_id = $1)
+            OR EXISTS(SELECT 1 FROM outbound_records WHERE staff_id = $1 OR voided_by = $1)
+            OR EXISTS(SELECT 1 FROM return_records WHERE staff_id = $1)
+            OR EXISTS(SELECT 1 FROM inbound_voids WHERE staff_id = $1)
+    `, staffID).Scan(&referenced)
+    if err != nil {
+        log.Printf("[DeleteStaff] 参照確認エラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{
+            "error": "スタッフの削除に失敗しました",
+        })
+        return
+    }
+    if referenced {
+        c.JSON(http.StatusConflict, gin.H{
+            "error": "入出庫などの記録で使用されている担当者は削除できません。無効化してください",
+        })
+        return
+    }
+
+    // 管理者を1人以上残す
+    lastAdmin, err := isLastActiveAdmin(tx, staffID)
+    if err != nil {
+        log.Printf("[DeleteStaff] 管理者数確認エラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{
+            "error": "スタッフの削除に失敗しました",
+        })
+        return
+    }
+    if lastAdmin {
+        c.JSON(http.StatusConflict, gin.H{
+            "error": "有効な管理者が1人もいなくなるため削除できません",
+        })
+        return
+    }
+
+    // セッションは履歴ではないため担当者と合わせて削除する
+    _, err = tx.Exec("DELETE FROM staff_sessions WHERE staff_id = $1", staffID)
+    if err == nil {
+        _, err = tx.Exec("DELETE FROM staff WHERE id = $1", staffID)
+    }
+    if isForeignKeyViolation(err) {
+        c.JSON(http.StatusConflict, gin.H{
+            "error": "入出庫などの記録で使用されている担当者は削除できません。無効化してください",
+        })
+        return
+    }
     if err != nil {
         log.Printf("[DeleteStaff] データベースエラー: %v", err)
         c.JSON(http.StatusInternalServerError, gin.H{
@@ -86,8 +348,38 @@ func DeleteStaff(c *gin.Context) {
         return
     }
 
+    if !recordAudit(c, tx, audit.Entry{
+        Action:     audit.ActionDelete,
+        EntityType: "staff",
+        EntityID:   strconv.Itoa(staffID),
+        Before:     before,
+    }) {
+        return
+    }
+    if err := tx.Commit(); err != nil {
+        log.Printf("[DeleteStaff] コミットエラー: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{
+            "error": "トランザクションのコミットに失敗しました",
+        })
+        return
+    }
+
     log.Printf("[DeleteStaff] 成功")
     c.JSON(http.StatusOK, gin.H{
         "message": "スタッフを削除しました",
     })
-}
\ No newline at end of file
+}
+
+// 指定した担当者が唯一の有効な管理者か
+// 同時に管理者を外す処理が行われないよう、管理者の行をロックして確認する
+func isLastActiveAdmin(tx *sql.Tx, staffID int) (bool, error) {
+    var self, total int
+    err := tx.QueryRow(`
+        SELECT COUNT(*) FILTER (WHERE id = $2), COUNT(*) FROM (
+            SELECT id FROM staff
+            WHERE role = $1 AND active
+            FOR UPDATE
+        ) admins
+    `, auth.RoleAdmin, staffID).Scan(&self, &total)
+    return self == 1 && total == 1, err
+}
@@ -0,0 +1,58 @@
+package middleware
+
+import (
+    "fmt"
+    "log"
+    "net/http"
+    "github.com/gin-gonic/gin"
+    "inventory-tracker/server/internal/auth"
+    "inventory-tracker/server/internal/model"
+)
+
+// セッション必須ミドルウェア
+// 有効なセッションがないリクエストは 401 を返し、ある場合はログイン中の担当者をコンテキストに設定する
+func RequireSession() gin.HandlerFunc {
+    return func(c *gin.Context) {
+        token := auth.TokenFromRequest(c)
+        staff, err := auth.LookupSession(token)
+        if err != nil {
+            log.Printf("セッション確認エラー: %v", err)
+            c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "セッションの確認に失敗しました"})
+            return
+        }
+        if staff == nil {
+            c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "ログインしてください"})
+            return
+        }
+
+        auth.SetSession(c, staff, token)
+        c.Next()
+    }
+}
+
+// 権限確認ミドルウェア
+// ルートごとに必要な権限を指定し、:category パラメータがあるルートでは担当カテゴリも確認する
+// 権限がない場合は 403 を返し、ログに記録する
+func RequirePermission(perm auth.Permission) gin.HandlerFunc {
+    return func(c *gin.Context) {
+        staff, _ := auth.CurrentStaff(c)
+        if !auth.HasPermission(staff, perm) {
+            denyPermission(c, staff, fmt.Sprintf("権限 %s がありません", perm))
+            return
+        }
+        if category := c.Param("category"); category != "" && !auth.CanAccessCategory(staff, category) {
+            denyPermission(c, staff, fmt.Sprintf("カテゴリ %s の担当ではありません", category))
+            return
+        }
+        c.Next()
+    }
+}
+
+func denyPermission(c *gin.Context, staff *model.Staff, reason string) {
+    staffID, role := 0, ""
+    if staff != nil {
+        staffID, role = staff.ID, staff.Role
+    }
+    log.Printf("権限エラー: 担当者ID = %d, ロール = %s, %s %s, %s", staffID, role, c.Request.Method, c.Request.URL.Path, reason)
+    c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "この操作を行う権限がありません: " + reason})
+}
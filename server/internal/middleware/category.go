@@ -0,0 +1,32 @@
+package middleware
+
+import (
+    "log"
+    "net/http"
+    "github.com/gin-gonic/gin"
+    "inventory-tracker/server/internal/db"
+)
+
+// カテゴリ確認ミドルウェア
+// :category パラメータがあるルートで、カテゴリマスタに登録されていないカテゴリは 404 を返す
+func RequireKnownCategory() gin.HandlerFunc {
+    return func(c *gin.Context) {
+        category := c.Param("category")
+        if category == "" {
+            c.Next()
+            return
+        }
+
+        exists, err := db.CategoryExists(category)
+        if err != nil {
+            log.Printf("カテゴリ確認エラー: %v", err)
+            c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "カテゴリの確認に失敗しました"})
+            return
+        }
+        if !exists {
+            c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "不明なカテゴリです: " + category})
+            return
+        }
+        c.Next()
+    }
+}
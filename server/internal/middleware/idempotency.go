@@ -0,0 +1,145 @@
+package middleware
+
+import (
+    "bytes"
+    "crypto/sha256"
+    "database/sql"
+    "encoding/hex"
+    "io"
+    "log"
+    "net/http"
+    "strings"
+    "github.com/gin-gonic/gin"
+    "inventory-tracker/server/internal/auth"
+    "inventory-tracker/server/internal/db"
+)
+
+const (
+    IdempotencyKeyHeader     = "Idempotency-Key"
+    IdempotentReplayedHeader = "Idempotent-Replayed"
+    maxIdempotencyKeyLength  = 255
+)
+
+// レスポンス本文を記録するためのResponseWriter
+type responseRecorder struct {
+    gin.ResponseWriter
+    body *bytes.Buffer
+}
+
+func (w *responseRecorder) Write(b []byte) (int, error) {
+    w.body.Write(b)
+    return w.ResponseWriter.Write(b)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+    w.body.WriteString(s)
+    return w.ResponseWriter.WriteString(s)
+}
+
+// 冪等性キーミドルウェア
+// Idempotency-Key ヘッダー付きのリクエストは成功時のレスポンスを保存し、
+// 同じ担当者が同じキーで再送した場合は処理を行わずに保存したレスポンスを返す
+//
+// レスポンスはハンドラーのトランザクションのコミット後に保存する。コミットから保存までの間に
+// サーバーが停止した場合、キーは処理中のまま残り、5分経過後の再送は再度処理される（二重登録になりうる）。
+// 5分以内の再送は処理中として 409 を返すため、クライアントは再送前に結果を確認すること
+func Idempotency() gin.HandlerFunc {
+    return func(c *gin.Context) {
+        key := strings.TrimSpace(c.GetHeader(IdempotencyKeyHeader))
+        if key == "" {
+            c.Next()
+            return
+        }
+        if len(key) > maxIdempotencyKeyLength {
+            c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Idempotency-Keyが長すぎます"})
+            return
+        }
+
+        // リクエスト本文のハッシュを計算（本文はハンドラーで再度読めるように戻す）
+        body, err := io.ReadAll(c.Request.Body)
+        if err != nil {
+            c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "無効なリクエストデータです"})
+            return
+        }
+        c.Request.Body = io.NopCloser(bytes.NewReader(body))
+        sum := sha256.Sum256(body)
+        requestHash := hex.EncodeToString(sum[:])
+        endpoint := c.Request.Method + " " + c.Request.URL.Path
+        staffID := auth.CurrentStaffID(c)
+
+        // キーの確保（処理中のまま一定時間経過したキーは再利用する）
+        result, err := db.DB.Exec(`
+            INSERT INTO idempotency_keys (staff_id, idempotency_key, endpoint, request_hash)
+            VALUES ($1, $2, $3, $4)
+            ON CONFLICT (staff_id, idempotency_key, endpoint) DO UPDATE
+            SET request_hash = EXCLUDED.request_hash, created_at = CURRENT_TIMESTAMP
+            WHERE idempotency_keys.status_code IS NULL
+            AND idempotency_keys.created_at < CURRENT_TIMESTAMP - INTERVAL '5 minutes'
+        `, staffID, key, endpoint, requestHash)
+        if err != nil {
+            log.Printf("[Idempotency] キー登録エラー: %v", err)
+            c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "リクエストの受付に失敗しました"})
+            return
+        }
+
+        if claimed, _ := result.RowsAffected(); claimed == 0 {
+            replayIdempotentResponse(c, staffID, key, endpoint, requestHash)
+            return
+        }
+
+        recorder := &responseRecorder{ResponseWriter: c.Writer, body: &bytes.Buffer{}}
+        c.Writer = recorder
+        c.Next()
+
+        // 成功時のみレスポンスを保存し、失敗時は同じキーで再試行できるようにキーを削除
+        status := recorder.Status()
+        if status >= 200 && status < 300 {
+            _, err = db.DB.Exec(`
+                UPDATE idempotency_keys
+                SET status_code = $4, response_body = $5, completed_at = CURRENT_TIMESTAMP
+                WHERE staff_id = $1 AND idempotency_key = $2 AND endpoint = $3
+            `, staffID, key, endpoint, status, recorder.body.String())
+        } else {
+            _, err = db.DB.Exec(
+                "DELETE FROM idempotency_keys WHERE staff_id = $1 AND idempotency_key = $2 AND endpoint = $3",
+                staffID, key, endpoint,
+            )
+        }
+        if err != nil {
+            log.Printf("[Idempotency] キー更新エラー: key=%s, endpoint=%s, error=%v", key, endpoint, err)
+        }
+    }
+}
+
+// 保存済みのレスポンスを返す
+func replayIdempotentResponse(c *gin.Context, staffID int, key string, endpoint string, requestHash string) {
+    var storedHash string
+    var statusCode sql.NullInt32
+    var responseBody sql.NullString
+    err := db.DB.QueryRow(`
+        SELECT request_hash, status_code, response_body
+        FROM idempotency_keys
+        WHERE staff_id = $1 AND idempotency_key = $2 AND endpoint = $3
+    `, staffID, key, endpoint).Scan(&storedHash, &statusCode, &responseBody)
+    if err != nil {
+        log.Printf("[Idempotency] キー取得エラー: %v", err)
+        c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "リクエストの受付に失敗しました"})
+        return
+    }
+
+    if storedHash != requestHash {
+        c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{
+            "error": "同じIdempotency-Keyで異なる内容のリクエストが送信されました",
+        })
+        return
+    }
+    if !statusCode.Valid {
+        c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "同じリクエストを処理中です。しばらくしてから再度お試しください"})
+        return
+    }
+
+    log.Printf("[Idempotency] 保存済みのレスポンスを返します: key=%s, endpoint=%s", key, endpoint)
+    c.Header(IdempotentReplayedHeader, "true")
+    c.Data(int(statusCode.Int32), "application/json; charset=utf-8", []byte(responseBody.String))
+    c.Abort()
+}
@@ -0,0 +1,37 @@
+package middleware
+
+import (
+    "crypto/rand"
+    "encoding/hex"
+    "regexp"
+    "github.com/gin-gonic/gin"
+)
+
+const (
+    RequestIDHeader     = "X-Request-ID"
+    contextRequestIDKey = "requestID"
+)
+
+// クライアントから受け付けるリクエストIDの形式
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,64}$`)
+
+// リクエストIDミドルウェア
+// X-Request-ID ヘッダーがあればその値を、なければ新しいIDを発行してレスポンスヘッダーにも返す
+func RequestID() gin.HandlerFunc {
+    return func(c *gin.Context) {
+        id := c.GetHeader(RequestIDHeader)
+        if !requestIDPattern.MatchString(id) {
+            buf := make([]byte, 16)
+            rand.Read(buf)
+            id = hex.EncodeToString(buf)
+        }
+        c.Set(contextRequestIDKey, id)
+        c.Header(RequestIDHeader, id)
+        c.Next()
+    }
+}
+
+// 現在のリクエストのリクエストID
+func CurrentRequestID(c *gin.Context) string {
+    return c.GetString(contextRequestIDKey)
+}
@@ -2,25 +2,92 @@ package model
 
 import "time"
 
+// PC型番（DefaultWarrantyPeriod は入庫時に保証期間を省略した場合の既定値、単位は月）
 type PCModelNumber struct {
-    ID          int       `json:"id"`
-    ModelNumber string    `json:"modelNumber"`
-    CreatedAt   time.Time `json:"createdAt"`
+    ID                    int       `json:"id"`
+    ModelNumber           string    `json:"modelNumber"`
+    Manufacturer          string    `json:"manufacturer"`
+    DisplayName           string    `json:"displayName"`
+    DefaultWarrantyPeriod *int      `json:"defaultWarrantyPeriod"`
+    CPU                   string    `json:"cpu"`
+    Memory                string    `json:"memory"`
+    Storage               string    `json:"storage"`
+    OS                    string    `json:"os"`
+    Active                bool      `json:"active"`
+    CreatedAt             time.Time `json:"createdAt"`
+    UpdatedAt             time.Time `json:"updatedAt"`
 }
 
 type Staff struct {
-    ID        int       `json:"id"`
+    ID         int       `json:"id"`
+    Name       string    `json:"name"`
+    Role       string    `json:"role"`
+    Categories []string  `json:"categories"` // nil の場合はすべてのカテゴリ
+    Active     bool      `json:"active"`
+    CreatedAt  time.Time `json:"createdAt"`
+}
+
+// 製品カテゴリ
+type Category struct {
+    Code      string    `json:"code"`
     Name      string    `json:"name"`
+    NameEn    string    `json:"nameEn"`
+    SortOrder int       `json:"sortOrder"`
     CreatedAt time.Time `json:"createdAt"`
+    UpdatedAt time.Time `json:"updatedAt"`
 }
 
 type ProductType struct {
     ID        int       `json:"id"`
     Category  string    `json:"category"`
     Name      string    `json:"name"`
+    Active    bool      `json:"active"`
+    SortOrder int       `json:"sortOrder"`
     CreatedAt time.Time `json:"createdAt"`
 }
 
+// 追加項目の定義（ProductTypeID が nil の場合はカテゴリ内のすべての製品タイプに適用）
+type AttributeDefinition struct {
+    ID            int       `json:"id"`
+    Category      string    `json:"category"`
+    ProductTypeID *int      `json:"productTypeId"`
+    Key           string    `json:"key"`
+    Label         string    `json:"label"`
+    DataType      string    `json:"dataType"`
+    Options       []string  `json:"options"`
+    Required      bool      `json:"required"`
+    Active        bool      `json:"active"`
+    SortOrder     int       `json:"sortOrder"`
+    CreatedAt     time.Time `json:"createdAt"`
+    UpdatedAt     time.Time `json:"updatedAt"`
+}
+
+// 顧客・購入者マスタ（customers / purchasers 共通）
+type Partner struct {
+    ID          int       `json:"id"`
+    Number      *string   `json:"number"`
+    Name        string    `json:"name"`
+    PostalCode  *string   `json:"postalCode"`
+    Address     *string   `json:"address"`
+    Phone       *string   `json:"phone"`
+    Email       *string   `json:"email"`
+    ContactName *string   `json:"contactName"`
+    Notes       *string   `json:"notes"`
+    CreatedAt   time.Time `json:"createdAt"`
+    UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+type PartnerRequest struct {
+    Number      string  `json:"number" binding:"required"`
+    Name        string  `json:"name" binding:"required"`
+    PostalCode  *string `json:"postalCode"`
+    Address     *string `json:"address"`
+    Phone       *string `json:"phone"`
+    Email       *string `json:"email"`
+    ContactName *string `json:"contactName"`
+    Notes       *string `json:"notes"`
+}
+
 type Product struct {
     ID            int       `json:"id"`
     ProductID     string    `json:"productId"`
@@ -63,10 +130,26 @@ type OutboundRecord struct {
     PurchaserName   *string   `json:"purchaserName,omitempty"`
     Notes           *string   `json:"notes,omitempty"`
     OutboundDate    time.Time `json:"outboundDate"`
+    VoidedAt        *time.Time `json:"voidedAt,omitempty"`
+    VoidedBy        *int      `json:"voidedBy,omitempty"`
+    VoidReason      *string   `json:"voidReason,omitempty"`
     Product         *Product  `json:"product,omitempty"`
     Staff           *Staff    `json:"staff,omitempty"`
 }
 
+type ReturnRecord struct {
+    ID               int       `json:"id"`
+    ProductID        string    `json:"productId"`
+    OutboundRecordID int       `json:"outboundRecordId"`
+    StaffID          int       `json:"staffId"`
+    ReturnNumber     string    `json:"returnNumber"`
+    Reason           string    `json:"reason"`
+    Notes            *string   `json:"notes,omitempty"`
+    ReturnDate       time.Time `json:"returnDate"`
+    Product          *Product  `json:"product,omitempty"`
+    Staff            *Staff    `json:"staff,omitempty"`
+}
+
 type InboundRecord struct {
     ID            int       `json:"id"`
     ProductID     string    `json:"productId"`
@@ -88,26 +171,51 @@ type CheckProductIDResponse struct {
 }
 
 type InboundRequest struct {
-    Products    []struct {
-        ProductID  string     `json:"productId"`
-        TypeID    int        `json:"typeId"`
-        LotNumber  *string    `json:"lotNumber,omitempty"`
-        PCDetails *struct {
-            ModelNumber    string    `json:"modelNumber"`
-            SerialNumber   string    `json:"serialNumber"`
-            PurchaseDate   time.Time `json:"purchaseDate"`
-            WarrantyPeriod *int      `json:"warrantyPeriod,omitempty"`
-        } `json:"pcDetails,omitempty"`
-    } `json:"products"`
+    Products    []InboundProduct `json:"products"`
     StaffID     int       `json:"staffId"`
     InboundDate time.Time `json:"inboundDate"`
 }
 
+type InboundProduct struct {
+    ProductID  string     `json:"productId"`
+    TypeID    int        `json:"typeId"`
+    LotNumber  *string    `json:"lotNumber,omitempty"`
+    PCDetails *InboundPCDetails `json:"pcDetails,omitempty"`
+    VestDetails *InboundVestDetails `json:"vestDetails,omitempty"`
+    Attributes map[string]interface{} `json:"attributes,omitempty"`
+}
+
+type InboundPCDetails struct {
+    ModelNumber    string    `json:"modelNumber"`
+    SerialNumber   string    `json:"serialNumber"`
+    PurchaseDate   time.Time `json:"purchaseDate"`
+    WarrantyPeriod *int      `json:"warrantyPeriod,omitempty"`
+}
+
+type InboundVestDetails struct {
+    Type    string `json:"type"`
+    Size    string `json:"size"`
+    HasLogo *bool  `json:"hasLogo"`
+}
+
+// 入庫データの検証エラー（row は products の1始まりの行番号、リクエスト全体の項目は0）
+type InboundValidationError struct {
+    Row       int    `json:"row"`
+    ProductID string `json:"productId,omitempty"`
+    Field     string `json:"field"`
+    Code      string `json:"code"`
+    Message   string `json:"message"`
+}
+
 type OutboundRequest struct {
     ProductIDStart   string    `json:"productIdStart"`
     ProductIDEnd     string    `json:"productIdEnd"`
+    ProductIDs      []string  `json:"productIds,omitempty"`
+    AllowGaps       bool      `json:"allowGaps,omitempty"`
     StaffID         int       `json:"staffId"`
-    OutboundDate    time.Time `json:"outboundDate"`
+    OutboundDate    string    `json:"outboundDate"`
+    CustomerID      *int      `json:"customerId,omitempty"`
+    PurchaserID     *int      `json:"purchaserId,omitempty"`
     CustomerNumber  *string   `json:"customerNumber,omitempty"`
     CustomerName    *string   `json:"customerName,omitempty"`
     PurchaserNumber *string   `json:"purchaserNumber,omitempty"`
@@ -115,6 +223,20 @@ type OutboundRequest struct {
     Notes           *string   `json:"notes,omitempty"`
 }
 
+type ReturnRequest struct {
+    ProductIDs     []string `json:"productIds"`
+    OutboundNumber *string  `json:"outboundNumber,omitempty"`
+    StaffID        int      `json:"staffId"`
+    ReturnDate     string   `json:"returnDate"`
+    Reason         string   `json:"reason"`
+    Notes          *string  `json:"notes,omitempty"`
+}
+
+type VoidRequest struct {
+    StaffID int    `json:"staffId"`
+    Reason  string `json:"reason"`
+}
+
 type DashboardStats struct {
     TotalProducts    int            `json:"totalProducts"`
     ByCategory      map[string]int `json:"byCategory"`
@@ -0,0 +1,166 @@
+package numbering
+
+import (
+    "database/sql"
+    "fmt"
+    "regexp"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// 伝票種別
+const (
+    Inbound  = "inbound"
+    Outbound = "outbound"
+    Return   = "return"
+)
+
+// 採番対象の伝票種別一覧
+var DocTypes = []string{Inbound, Outbound, Return}
+
+// 書式が登録されていない場合の書式（YYYYMMDD-NNNN）
+const DefaultFormat = "{date}-{seq:4}"
+
+var tokenPattern = regexp.MustCompile(`\{(date|yyyy|mm|dd|category|seq(?::(\d+))?)\}`)
+
+// 採番設定
+type Setting struct {
+    Format      string
+    PerCategory bool
+}
+
+// 伝票番号の採番
+// 採番カウンターの行は呼び出し元のトランザクションがコミットまたはロールバックされるまでロックされるため、
+// 同時に処理されたリクエストに同じ番号が払い出されることはなく、ロールバック時は番号も消費されない
+// カテゴリ別の連番は同じ期間の共通の連番の続きから始めるため、期間の途中でカテゴリ別採番に切り替えても
+// 共通の連番で払い出した番号を再度払い出すことはない
+func Next(tx *sql.Tx, docType string, category string) (string, error) {
+    setting, err := LoadSetting(tx, docType, category)
+    if err != nil {
+        return "", err
+    }
+
+    now := time.Now()
+    scope := ""
+    if setting.PerCategory {
+        scope = category
+    }
+
+    var seq int
+    err = tx.QueryRow(`
+        INSERT INTO document_counters (doc_type, scope, period, last_value)
+        SELECT $1, $2, $3, COALESCE(MAX(last_value), 0) + 1
+        FROM document_counters
+        WHERE doc_type = $1 AND scope = '' AND period = $3 AND $2 <> ''
+        ON CONFLICT (doc_type, scope, period) DO UPDATE
+        SET last_value = document_counters.last_value + 1
+        RETURNING last_value
+    `, docType, scope, Period(setting.Format, now)).Scan(&seq)
+    if err != nil {
+        return "", fmt.Errorf("採番エラー: %v", err)
+    }
+
+    return Format(setting.Format, category, now, seq), nil
+}
+
+// 採番設定の取得
+// カテゴリ専用の設定があればそれを、なければ共通の設定を使用する
+func LoadSetting(tx *sql.Tx, docType string, category string) (Setting, error) {
+    var s Setting
+    err := tx.QueryRow(`
+        SELECT format, per_category
+        FROM document_number_formats
+        WHERE doc_type = $1
+        AND (category = $2 OR category IS NULL)
+        ORDER BY category NULLS LAST
+        LIMIT 1
+    `, docType, category).Scan(&s.Format, &s.PerCategory)
+    if err == sql.ErrNoRows {
+        return Setting{Format: DefaultFormat}, nil
+    }
+    if err != nil {
+        return Setting{}, fmt.Errorf("採番設定の取得エラー: %v", err)
+    }
+    return s, nil
+}
+
+// 伝票種別の存在チェック
+func IsDocType(docType string) bool {
+    for _, t := range DocTypes {
+        if t == docType {
+            return true
+        }
+    }
+    return false
+}
+
+// 書式の検証
+// カテゴリ別採番はカテゴリごとに同じ連番を払い出すため、書式に {category} を含める必要がある
+func Validate(format string, perCategory bool) error {
+    if strings.TrimSpace(format) == "" {
+        return fmt.Errorf("書式を入力してください")
+    }
+    if perCategory && !strings.Contains(format, "{category}") {
+        return fmt.Errorf("カテゴリ別に採番する場合は書式にカテゴリ（{category}）を含めてください")
+    }
+    hasSeq := false
+    for _, m := range tokenPattern.FindAllStringSubmatch(format, -1) {
+        if strings.HasPrefix(m[1], "seq") {
+            hasSeq = true
+            if m[2] != "" {
+                if width, _ := strconv.Atoi(m[2]); width < 1 || width > 10 {
+                    return fmt.Errorf("連番の桁数は1〜10で指定してください")
+                }
+            }
+        }
+    }
+    if !hasSeq {
+        return fmt.Errorf("書式には連番（{seq}）を含めてください")
+    }
+    rest := tokenPattern.ReplaceAllString(format, "")
+    if strings.ContainsAny(rest, "{}") {
+        return fmt.Errorf("書式に使用できない項目が含まれています")
+    }
+    return nil
+}
+
+// 採番単位の算出
+// 書式に含まれる日付項目に応じて日・月・年ごとに連番をリセットする
+func Period(format string, now time.Time) string {
+    switch {
+    case strings.Contains(format, "{date}") || strings.Contains(format, "{dd}"):
+        return now.Format("20060102")
+    case strings.Contains(format, "{mm}"):
+        return now.Format("200601")
+    case strings.Contains(format, "{yyyy}"):
+        return now.Format("2006")
+    default:
+        return ""
+    }
+}
+
+// 書式に従って伝票番号を組み立てる
+func Format(format string, category string, now time.Time, seq int) string {
+    return tokenPattern.ReplaceAllStringFunc(format, func(token string) string {
+        m := tokenPattern.FindStringSubmatch(token)
+        switch {
+        case m[1] == "date":
+            return now.Format("20060102")
+        case m[1] == "yyyy":
+            return now.Format("2006")
+        case m[1] == "mm":
+            return now.Format("01")
+        case m[1] == "dd":
+            return now.Format("02")
+        case m[1] == "category":
+            return strings.ToUpper(category)
+        default:
+            width := 4
+            if m[2] != "" {
+                width, _ = strconv.Atoi(m[2])
+            }
+            return fmt.Sprintf("%0*d", width, seq)
+        }
+    })
+}
@@ -0,0 +1,79 @@
+package productid
+
+import (
+    "fmt"
+    "strconv"
+    "strings"
+)
+
+// 1回の範囲指定で展開できる製品IDの上限
+const MaxRangeSize = 10000
+
+// 製品IDを接頭辞と末尾の数字部分に分割
+// 例: "WR-009" → ("WR-", "009")
+func Split(productID string) (prefix string, digits string) {
+    i := len(productID)
+    for i > 0 && productID[i-1] >= '0' && productID[i-1] <= '9' {
+        i--
+    }
+    return productID[:i], productID[i:]
+}
+
+// 開始IDから終了IDまでの製品IDを数値順に展開
+// 接頭辞が同じで末尾が数字の製品IDのみ対応する。
+// 開始IDの数字部分が0埋めされている場合は同じ桁数で0埋めし（A001〜A010）、
+// それ以外は0埋めなしで展開する（WR-9〜WR-12）。
+func ExpandRange(start string, end string) ([]string, error) {
+    start = strings.TrimSpace(start)
+    end = strings.TrimSpace(end)
+    if start == "" || end == "" {
+        return nil, fmt.Errorf("開始IDと終了IDを指定してください")
+    }
+    if start == end {
+        return []string{start}, nil
+    }
+
+    startPrefix, startDigits := Split(start)
+    endPrefix, endDigits := Split(end)
+    if startDigits == "" || endDigits == "" {
+        return nil, fmt.Errorf("製品IDの末尾が数字ではないため範囲指定できません（開始ID: %s, 終了ID: %s）", start, end)
+    }
+    if startPrefix != endPrefix {
+        return nil, fmt.Errorf("開始IDと終了IDの接頭辞が一致しません（開始ID: %s, 終了ID: %s）", start, end)
+    }
+    if len(startDigits) > 18 || len(endDigits) > 18 {
+        return nil, fmt.Errorf("製品IDの数字部分が長すぎます")
+    }
+
+    from, err := strconv.ParseInt(startDigits, 10, 64)
+    if err != nil {
+        return nil, fmt.Errorf("開始IDの数字部分が不正です: %s", start)
+    }
+    to, err := strconv.ParseInt(endDigits, 10, 64)
+    if err != nil {
+        return nil, fmt.Errorf("終了IDの数字部分が不正です: %s", end)
+    }
+    if from > to {
+        return nil, fmt.Errorf("開始IDが終了IDより大きくなっています（開始ID: %s, 終了ID: %s）", start, end)
+    }
+    if to-from+1 > MaxRangeSize {
+        return nil, fmt.Errorf("範囲指定できる製品IDは%d件までです", MaxRangeSize)
+    }
+
+    // 0埋めの判定
+    width := 0
+    if len(startDigits) > 1 && startDigits[0] == '0' {
+        width = len(startDigits)
+        if len(endDigits) != width {
+            return nil, fmt.Errorf("開始IDと終了IDの桁数が一致しません（開始ID: %s, 終了ID: %s）", start, end)
+        }
+    } else if len(endDigits) > 1 && endDigits[0] == '0' {
+        return nil, fmt.Errorf("開始IDと終了IDの桁数が一致しません（開始ID: %s, 終了ID: %s）", start, end)
+    }
+
+    ids := make([]string, 0, to-from+1)
+    for n := from; n <= to; n++ {
+        ids = append(ids, fmt.Sprintf("%s%0*d", startPrefix, width, n))
+    }
+    return ids, nil
+}
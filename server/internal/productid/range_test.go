@@ -0,0 +1,80 @@
+package productid
+
+import (
+    "fmt"
+    "reflect"
+    "testing"
+)
+
+func TestSplit(t *testing.T) {
+    tests := []struct {
+        in     string
+        prefix string
+        digits string
+    }{
+        {"WR-009", "WR-", "009"},
+        {"A1", "A", "1"},
+        {"123", "", "123"},
+        {"ABC", "ABC", ""},
+        {"", "", ""},
+        {"X1-2", "X1-", "2"},
+    }
+    for _, tt := range tests {
+        prefix, digits := Split(tt.in)
+        if prefix != tt.prefix || digits != tt.digits {
+            t.Errorf("Split(%q) = (%q, %q), want (%q, %q)", tt.in, prefix, digits, tt.prefix, tt.digits)
+        }
+    }
+}
+
+func TestExpandRange(t *testing.T) {
+    tests := []struct {
+        name    string
+        start   string
+        end     string
+        want    []string
+        wantErr bool
+    }{
+        {name: "0埋めなし", start: "WR-9", end: "WR-12", want: []string{"WR-9", "WR-10", "WR-11", "WR-12"}},
+        {name: "0埋めあり", start: "A008", end: "A011", want: []string{"A008", "A009", "A010", "A011"}},
+        {name: "0埋めで桁上がり", start: "A098", end: "A100", want: []string{"A098", "A099", "A100"}},
+        {name: "開始と終了が同じ", start: "WR-9", end: "WR-9", want: []string{"WR-9"}},
+        {name: "末尾が数字でない同一ID", start: "ABC", end: "ABC", want: []string{"ABC"}},
+        {name: "前後の空白を除去", start: " WR-9", end: "WR-10 ", want: []string{"WR-9", "WR-10"}},
+        {name: "接頭辞なし", start: "1", end: "3", want: []string{"1", "2", "3"}},
+        {name: "0のみの開始ID", start: "A0", end: "A2", want: []string{"A0", "A1", "A2"}},
+        {name: "開始IDが空", start: "", end: "A1", wantErr: true},
+        {name: "終了IDが空白のみ", start: "A1", end: "  ", wantErr: true},
+        {name: "末尾が数字でない", start: "A1", end: "AB", wantErr: true},
+        {name: "接頭辞が不一致", start: "A1", end: "B3", wantErr: true},
+        {name: "開始IDが終了IDより大きい", start: "A10", end: "A9", wantErr: true},
+        {name: "0埋めの桁数が不一致", start: "A001", end: "A10", wantErr: true},
+        {name: "終了IDのみ0埋め", start: "A1", end: "A010", wantErr: true},
+        {name: "数字部分が長すぎる", start: "A1", end: "A1234567890123456789", wantErr: true},
+        {name: "上限件数ちょうど", start: "A1", end: fmt.Sprintf("A%d", MaxRangeSize)},
+        {name: "上限件数を超過", start: "A1", end: fmt.Sprintf("A%d", MaxRangeSize+1), wantErr: true},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            got, err := ExpandRange(tt.start, tt.end)
+            if tt.wantErr {
+                if err == nil {
+                    t.Fatalf("ExpandRange(%q, %q) = %v, want error", tt.start, tt.end, got)
+                }
+                return
+            }
+            if err != nil {
+                t.Fatalf("ExpandRange(%q, %q) error: %v", tt.start, tt.end, err)
+            }
+            if tt.want == nil {
+                if len(got) != MaxRangeSize {
+                    t.Fatalf("ExpandRange(%q, %q) returned %d IDs, want %d", tt.start, tt.end, len(got), MaxRangeSize)
+                }
+                return
+            }
+            if !reflect.DeepEqual(got, tt.want) {
+                t.Errorf("ExpandRange(%q, %q) = %v, want %v", tt.start, tt.end, got, tt.want)
+            }
+        })
+    }
+}
@@ -3,7 +3,9 @@ package routes
 import (
     "github.com/gin-gonic/gin"
     "github.com/gin-contrib/cors"
+    "inventory-tracker/server/internal/auth"
     "inventory-tracker/server/internal/handler"
+    "inventory-tracker/server/internal/middleware"
 )
 
 func SetupRouter() *gin.Engine {
@@ -12,50 +14,126 @@ func SetupRouter() *gin.Engine {
     // ミドルウェアの順序を変更
     r.Use(gin.Logger())
     r.Use(gin.Recovery())
+    r.Use(middleware.RequestID())
     r.Use(cors.New(cors.Config{
         AllowOrigins:     []string{"http://localhost:5173"},
         AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-        AllowHeaders:     []string{"Origin", "Content-Type", "Accept"},
-        ExposeHeaders:    []string{"Content-Length"},
+        AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", middleware.IdempotencyKeyHeader, middleware.RequestIDHeader},
+        ExposeHeaders:    []string{"Content-Length", "Content-Disposition", middleware.IdempotentReplayedHeader, middleware.RequestIDHeader},
         AllowCredentials: true,
         MaxAge:           300,
     }))
 
-    // APIルートグループ
-    api := r.Group("/api")
+    // ログイン前に利用するAPI
+    public := r.Group("/api")
     {
+        public.POST("/auth/login", handler.Login)
+        public.GET("/auth/staff", handler.GetPINLoginStaff)
+    }
+
+    // APIルートグループ（ログイン必須、各ルートで必要な権限を指定する）
+    // :category パラメータがあるルートでは、未登録のカテゴリを 404 とする
+    api := r.Group("/api", middleware.RequireSession(), middleware.RequireKnownCategory())
+    {
+        // ログイン中の担当者
+        api.GET("/auth/me", handler.GetCurrentStaff)
+        api.POST("/auth/logout", handler.Logout)
+
         // 製品ID存在チェック
-        api.GET("/inventory/:category/check-product-id/:productId", handler.CheckProductID)
+        api.GET("/inventory/:category/check-product-id/:productId", middleware.RequirePermission(auth.PermView), handler.CheckProductID)
 
         // 入出庫処理
-        api.POST("/inbound/:category", handler.HandleInbound)
-        api.POST("/outbound/:category", handler.HandleOutbound)
+        api.POST("/inbound/:category", middleware.RequirePermission(auth.PermOperate), middleware.Idempotency(), handler.HandleInbound)
+        api.POST("/inbound/:category/void/:inboundNumber", middleware.RequirePermission(auth.PermVoid), handler.VoidInbound)
+        api.POST("/inbound/:category/import", middleware.RequirePermission(auth.PermOperate), handler.ImportInbound)
+        api.POST("/outbound/:category", middleware.RequirePermission(auth.PermOperate), middleware.Idempotency(), handler.HandleOutbound)
+        api.POST("/outbound/:category/preview", middleware.RequirePermission(auth.PermOperate), handler.PreviewOutbound)
+        api.POST("/outbound/:category/void/:outboundNumber", middleware.RequirePermission(auth.PermVoid), handler.VoidOutbound)
+        api.POST("/returns/:category", middleware.RequirePermission(auth.PermOperate), handler.HandleReturn)
 
         // 在庫一覧
-        api.GET("/inventory/:category", handler.GetInventory)
+        api.GET("/inventory/:category", middleware.RequirePermission(auth.PermView), handler.GetInventory)
+        api.GET("/products/:productId", middleware.RequirePermission(auth.PermView), handler.GetProductDetail)
+        api.GET("/search", middleware.RequirePermission(auth.PermView), handler.Search)
 
         // スタッフ管理
-        api.GET("/staff", handler.GetStaffList)
-        api.POST("/staff", handler.CreateStaff)
-        api.DELETE("/staff/:id", handler.DeleteStaff)
+        api.GET("/staff", middleware.RequirePermission(auth.PermView), handler.GetStaffList)
+        api.POST("/staff", middleware.RequirePermission(auth.PermManageStaff), handler.CreateStaff)
+        api.PUT("/staff/:id", middleware.RequirePermission(auth.PermManageStaff), handler.UpdateStaff)
+        api.POST("/staff/:id/deactivate", middleware.RequirePermission(auth.PermManageStaff), handler.DeactivateStaff)
+        api.POST("/staff/:id/reactivate", middleware.RequirePermission(auth.PermManageStaff), handler.ReactivateStaff)
+        api.DELETE("/staff/:id", middleware.RequirePermission(auth.PermManageStaff), handler.DeleteStaff)
+        api.PUT("/staff/:id/credentials", middleware.RequirePermission(auth.PermManageStaff), handler.UpdateStaffCredentials)
+        api.PUT("/staff/:id/permissions", middleware.RequirePermission(auth.PermManageStaff), handler.UpdateStaffPermissions)
+
+        // カテゴリ
+        api.GET("/categories", middleware.RequirePermission(auth.PermView), handler.GetCategories)
+        api.POST("/categories", middleware.RequirePermission(auth.PermManageMasters), handler.CreateCategory)
+        api.PUT("/categories/order", middleware.RequirePermission(auth.PermManageMasters), handler.ReorderCategories)
+        api.PUT("/categories/:code", middleware.RequirePermission(auth.PermManageMasters), handler.UpdateCategory)
+        api.DELETE("/categories/:code", middleware.RequirePermission(auth.PermManageMasters), handler.DeleteCategory)
 
         // 製品タイプ
-        api.GET("/product-types/:category", handler.GetProductTypes)
+        api.GET("/product-types/:category", middleware.RequirePermission(auth.PermView), handler.GetProductTypes)
+        api.POST("/product-types/:category", middleware.RequirePermission(auth.PermManageMasters), handler.CreateProductType)
+        api.PUT("/product-types/:category/order", middleware.RequirePermission(auth.PermManageMasters), handler.ReorderProductTypes)
+        api.PUT("/product-types/:category/:id", middleware.RequirePermission(auth.PermManageMasters), handler.UpdateProductType)
+        api.POST("/product-types/:category/:id/deactivate", middleware.RequirePermission(auth.PermManageMasters), handler.DeactivateProductType)
+        api.POST("/product-types/:category/:id/reactivate", middleware.RequirePermission(auth.PermManageMasters), handler.ReactivateProductType)
+        api.DELETE("/product-types/:category/:id", middleware.RequirePermission(auth.PermManageMasters), handler.DeleteProductType)
+
+        // 追加項目（カテゴリ・製品タイプごとの入力項目）
+        api.GET("/attribute-definitions/:category", middleware.RequirePermission(auth.PermView), handler.GetAttributeDefinitions)
+        api.POST("/attribute-definitions/:category", middleware.RequirePermission(auth.PermManageMasters), handler.CreateAttributeDefinition)
+        api.PUT("/attribute-definitions/:category/:id", middleware.RequirePermission(auth.PermManageMasters), handler.UpdateAttributeDefinition)
+        api.POST("/attribute-definitions/:category/:id/deactivate", middleware.RequirePermission(auth.PermManageMasters), handler.DeactivateAttributeDefinition)
+        api.POST("/attribute-definitions/:category/:id/reactivate", middleware.RequirePermission(auth.PermManageMasters), handler.ReactivateAttributeDefinition)
+        api.DELETE("/attribute-definitions/:category/:id", middleware.RequirePermission(auth.PermManageMasters), handler.DeleteAttributeDefinition)
 
         // PC型番管理
-        api.GET("/pc-model-numbers", handler.GetPCModelNumbers)
-        api.POST("/pc-model-numbers", handler.AddPCModelNumber)
-        api.DELETE("/pc-model-numbers/:modelNumber", handler.DeletePCModelNumber)
+        api.GET("/pc-model-numbers", middleware.RequirePermission(auth.PermView), handler.GetPCModelNumbers)
+        api.POST("/pc-model-numbers", middleware.RequirePermission(auth.PermManageMasters), handler.AddPCModelNumber)
+        api.PUT("/pc-model-numbers/:modelNumber", middleware.RequirePermission(auth.PermManageMasters), handler.UpdatePCModelNumber)
+        api.POST("/pc-model-numbers/:modelNumber/deactivate", middleware.RequirePermission(auth.PermManageMasters), handler.DeactivatePCModelNumber)
+        api.POST("/pc-model-numbers/:modelNumber/reactivate", middleware.RequirePermission(auth.PermManageMasters), handler.ReactivatePCModelNumber)
+        api.DELETE("/pc-model-numbers/:modelNumber", middleware.RequirePermission(auth.PermManageMasters), handler.DeletePCModelNumber)
+
+        // 顧客・購入者マスタ
+        api.GET("/customers", middleware.RequirePermission(auth.PermView), handler.GetCustomers)
+        api.GET("/customers/lookup", middleware.RequirePermission(auth.PermView), handler.LookupCustomers)
+        api.GET("/customers/:id", middleware.RequirePermission(auth.PermView), handler.GetCustomer)
+        api.POST("/customers", middleware.RequirePermission(auth.PermManageMasters), handler.CreateCustomer)
+        api.PUT("/customers/:id", middleware.RequirePermission(auth.PermManageMasters), handler.UpdateCustomer)
+        api.DELETE("/customers/:id", middleware.RequirePermission(auth.PermManageMasters), handler.DeleteCustomer)
+        api.GET("/purchasers", middleware.RequirePermission(auth.PermView), handler.GetPurchasers)
+        api.GET("/purchasers/lookup", middleware.RequirePermission(auth.PermView), handler.LookupPurchasers)
+        api.GET("/purchasers/:id", middleware.RequirePermission(auth.PermView), handler.GetPurchaser)
+        api.POST("/purchasers", middleware.RequirePermission(auth.PermManageMasters), handler.CreatePurchaser)
+        api.PUT("/purchasers/:id", middleware.RequirePermission(auth.PermManageMasters), handler.UpdatePurchaser)
+        api.DELETE("/purchasers/:id", middleware.RequirePermission(auth.PermManageMasters), handler.DeletePurchaser)
+
+        // 伝票番号書式
+        api.GET("/document-number-formats", middleware.RequirePermission(auth.PermView), handler.GetDocumentNumberFormats)
+        api.PUT("/document-number-formats/:docType", middleware.RequirePermission(auth.PermManageMasters), handler.UpdateDocumentNumberFormat)
 
         // 最新ロット番号
-        api.GET("/latest-lot-number/:category", handler.GetLatestLotNumber)
+        api.GET("/latest-lot-number/:category", middleware.RequirePermission(auth.PermView), handler.GetLatestLotNumber)
 
         // ダッシュボード
-        api.GET("/dashboard/stats", handler.GetDashboardStats)
+        api.GET("/dashboard/stats", middleware.RequirePermission(auth.PermView), handler.GetDashboardStats)
+
+        // レポート
+        api.GET("/reports/pc-warranty", middleware.RequirePermission(auth.PermView), handler.GetPCWarrantyReport)
+
+        // 監査ログ
+        api.GET("/audit-log", middleware.RequirePermission(auth.PermAudit), handler.GetAuditLog)
+        api.GET("/audit-log/verify", middleware.RequirePermission(auth.PermAudit), handler.VerifyAuditLog)
 
         // 履歴
-        api.GET("/inbound/:category/history", handler.GetInboundHistory)
-        api.GET("/outbound/:category/history", handler.GetOutboundHistory)
+        api.GET("/inbound/:category/history", middleware.RequirePermission(auth.PermView), handler.GetInboundHistory)
+        api.GET("/inbound/:category/voids", middleware.RequirePermission(auth.PermView), handler.GetInboundVoids)
+        api.GET("/outbound/:category/history", middleware.RequirePermission(auth.PermView), handler.GetOutboundHistory)
+        api.GET("/returns/:category/history", middleware.RequirePermission(auth.PermView), handler.GetReturnHistory)
     }
 
     return r